@@ -2,75 +2,325 @@ package main
 
 import (
 	"Backend/internal/api"
-	"Backend/internal/repository/postgres"
+	"Backend/internal/auth"
+	"Backend/internal/codehost"
+	"Backend/internal/config"
+	"Backend/internal/domain"
+	"Backend/internal/logging"
+	"Backend/internal/metrics"
+	"Backend/internal/outbox"
 	"Backend/internal/service"
+	"Backend/internal/stream"
+	"Backend/internal/tracing"
+	"Backend/internal/webhook"
 	"context"
-	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
-
-	_ "github.com/lib/pq"
 )
 
 func main() {
-	ctx := context.Background()
-
-	log.Println("Initializing PostgreSQL Repository...")
-
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := os.Getenv("DB_PORT")
-	dbUser := os.Getenv("DB_USER")
-	dbPass := os.Getenv("DB_PASSWORD")
-	dbName := os.Getenv("DB_NAME")
+	if err := run(); err != nil {
+		slog.Error("fatal error", "error", err)
+		os.Exit(1)
+	}
+}
 
-	if dbHost == "" || dbName == "" {
-		log.Fatal("FATAL: Required environment variables (DB_HOST, DB_NAME, etc.) for PostgreSQL are not set.")
+// run serves until a shutdown signal arrives. Its deferred cleanup, flushing
+// traces and closing the storage among others, runs on every return, which
+// os.Exit would skip.
+func run() error {
+	cfg, err := config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
 	}
 
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		dbHost, dbPort, dbUser, dbPass, dbName)
+	logger, err := logging.New(os.Stdout, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
 
-	db, err := sql.Open("postgres", connStr)
+	shutdownTracing, err := tracing.Setup(tracing.Options{
+		Exporter:    cfg.Tracing.Exporter,
+		File:        cfg.Tracing.File,
+		ServiceName: cfg.Tracing.ServiceName,
+	})
 	if err != nil {
-		log.Fatalf("FATAL: Failed to open DB connection: %v", err)
+		return fmt.Errorf("failed to set up tracing: %w", err)
 	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("failed to flush traces", "error", err)
+		}
+	}()
 
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	if err = db.PingContext(ctx); err != nil {
-		log.Fatalf("FATAL: Failed to ping database: %v. Check docker-compose and environment variables.", err)
+	repoImpl, db, err := openStorage(cfg.Storage)
+	if err != nil {
+		return fmt.Errorf("failed to open %s storage: %w", cfg.Storage.Driver, err)
 	}
+	defer func() {
+		if err := repoImpl.Close(); err != nil {
+			slog.Error("failed to close storage", "error", err)
+		}
+	}()
 
-	pgRepo := postgres.NewPostgresRepository(db)
-	if err = pgRepo.Init(context.Background()); err != nil {
-		log.Fatalf("FATAL: Failed to initialize PostgreSQL schema: %v", err)
+	m := metrics.New()
+	if db != nil {
+		m.RegisterDB(db, cfg.Storage.Driver)
 	}
+	m.RegisterReviewStats(repoImpl, cfg.Metrics.StatsTimeout)
 
-	log.Println("PostgreSQL connection established and schema initialized.")
-
-	repoImpl := pgRepo
-
-	prService := service.NewPRService(repoImpl, repoImpl)
+	webhookService := service.NewWebhookService(repoImpl)
+	expertiseOptions := service.ExpertiseOptions{
+		Window:   cfg.Reviewers.ExpertiseWindow,
+		HalfLife: cfg.Reviewers.ExpertiseHalfLife,
+		Refresh:  cfg.Reviewers.ExpertiseRefresh,
+	}
+	prService := service.NewPRServiceWithOptions(repoImpl, repoImpl, service.PRServiceOptions{
+		ReviewerCount: cfg.Reviewers.Count,
+		Strategy:      cfg.Reviewers.Strategy,
+		CodeOwners:    repoImpl,
+		History:       repoImpl,
+		Stats:         repoImpl,
+		Expertise:     expertiseOptions,
+	})
 	teamService := service.NewTeamService(repoImpl)
 	userService := service.NewUserService(repoImpl, repoImpl)
+	prService = service.NewAuditedPRService(prService)
+	teamService = service.NewAuditedTeamService(teamService)
+	userService = service.NewAuditedUserService(userService)
+	codeOwnersService := service.NewAuditedCodeOwnersService(service.NewCodeOwnersService(repoImpl, repoImpl))
+
+	authService := service.NewAuthService(repoImpl, repoImpl)
+	if cfg.Auth.BootstrapToken != "" {
+		if err := authService.EnsureToken(ctx, "bootstrap", cfg.Auth.BootstrapToken, []domain.Role{domain.RoleAdmin}); err != nil {
+			return fmt.Errorf("failed to register bootstrap token: %w", err)
+		}
+	}
+	var authenticator api.Authenticator
+	if cfg.Auth.Enabled {
+		chain := auth.Chain{APITokens: authService}
+		if cfg.Auth.JWT.Enabled() {
+			verifier, err := newJWTVerifier(ctx, cfg.Auth.JWT)
+			if err != nil {
+				return fmt.Errorf("failed to set up JWT authentication: %w", err)
+			}
+			chain.JWT = verifier
+		}
+		authenticator = chain
+	} else {
+		slog.Warn("authentication is disabled, every API route is open")
+	}
 
 	prHandler := api.NewPRHandler(prService)
 	teamHandler := api.NewTeamHandler(teamService)
 	userHandler := api.NewUserHandler(userService)
 
-	r := api.NewRouter(prHandler, teamHandler, userHandler)
+	idempotency := api.NewIdempotencyMiddleware(repoImpl, cfg.Idempotency.TTL)
+	go idempotency.RunCleanup(ctx, cfg.Idempotency.CleanupInterval)
+
+	var limiter *api.RateLimiter
+	if cfg.RateLimit.Enabled {
+		limiter = api.NewRateLimiter(rateLimiterOptions(cfg.RateLimit, m))
+		go limiter.RunCleanup(ctx, cfg.RateLimit.IdleTimeout)
+	}
+
+	sinks, closeSinks, err := outboxSinks(cfg.Outbox, webhookService)
+	if err != nil {
+		return fmt.Errorf("failed to set up outbox sinks: %w", err)
+	}
+	defer closeSinks()
+
+	// The webhook worker and the outbox relay record their outcomes even when
+	// ctx is done, so they have to stop before the sinks and the storage are closed.
+	var workers sync.WaitGroup
+	defer func() {
+		stop()
+		workers.Wait()
+	}()
+
+	if cfg.Webhooks.Enabled {
+		worker := webhook.NewWorker(repoImpl, webhookWorkerOptions(cfg.Webhooks, m))
+		workers.Go(func() { worker.Run(ctx) })
+	} else {
+		slog.Warn("webhook delivery is disabled, deliveries are queued but not sent")
+	}
+
+	broker := stream.NewBroker(cfg.Stream.BufferSize)
+	sinks["stream"] = broker
+	if gh := cfg.Integrations.GitHub; gh.Token != "" {
+		sinks[codehost.SinkName] = codehost.NewSink(repoImpl, map[string]codehost.Client{
+			domain.ProviderGitHub: codehost.NewGitHubClient(gh.APIURL, gh.Token, gh.APITimeout),
+		})
+	}
+	relay := outbox.NewRelay(repoImpl, sinks, outboxRelayOptions(cfg.Outbox, m))
+	workers.Go(func() { relay.Run(ctx) })
+
+	integrations := api.NewIntegrationHandler(service.NewIntegrationService(prService, repoImpl, repoImpl), api.IntegrationOptions{
+		GitHubSecret: cfg.Integrations.GitHub.WebhookSecret,
+		GitLabToken:  cfg.Integrations.GitLab.WebhookToken,
+	})
+
+	health := api.NewHealthHandler(repoImpl, cfg.Server.ReadinessTimeout)
+
+	r := api.NewRouter(prHandler, teamHandler, userHandler, api.RouterOptions{
+		Health:         health,
+		Idempotency:    idempotency,
+		RequestTimeout: cfg.Server.RequestTimeout,
+		Metrics:        m,
+		Auth:           authenticator,
+		Tokens:         api.NewTokenHandler(authService),
+		Audit:          api.NewAuditHandler(service.NewAuditService(repoImpl)),
+		RateLimiter:    limiter,
+		Stream:         api.NewStreamHandler(broker, teamService, cfg.Stream.KeepAlive),
+		Integrations:   integrations,
+		CodeOwners:     api.NewCodeOwnersHandler(codeOwnersService),
+		Expertise:      api.NewExpertiseHandler(service.NewExpertiseService(repoImpl, repoImpl, expertiseOptions)),
+		Webhooks:       api.NewWebhookHandler(webhookService),
+	})
 
 	server := &http.Server{
-		Addr:    ":8080",
-		Handler: r,
+		Addr:              cfg.Server.ListenAddr,
+		Handler:           r,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+	// Event streams never finish on their own; closing them lets Shutdown drain.
+	server.RegisterOnShutdown(broker.Close)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("server starting", "addr", cfg.Server.ListenAddr, "storage", cfg.Storage.Driver)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		if err != nil && err != http.ErrServerClosed {
+			return fmt.Errorf("could not listen on %s: %w", cfg.Server.ListenAddr, err)
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	health.SetShuttingDown()
+	if cfg.Server.ShutdownDelay > 0 {
+		slog.Info("shutdown signal received, failing readiness before draining", "delay", cfg.Server.ShutdownDelay.String())
+		time.Sleep(cfg.Server.ShutdownDelay)
+	}
+
+	slog.Info("shutdown signal received, draining connections", "timeout", cfg.Server.ShutdownTimeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("graceful shutdown did not complete", "error", err)
+	}
+	slog.Info("server stopped")
+	return nil
+}
+
+func newJWTVerifier(ctx context.Context, cfg config.JWTConfig) (*auth.JWTVerifier, error) {
+	var (
+		keys *auth.KeySet
+		err  error
+	)
+	if cfg.JWKSURL != "" {
+		keys, err = auth.NewKeySetFromURL(ctx, cfg.JWKSURL, &http.Client{Timeout: 10 * time.Second}, cfg.RefreshInterval)
+	} else {
+		keys, err = auth.NewKeySetFromFile(ctx, cfg.JWKSFile, cfg.RefreshInterval)
 	}
+	if err != nil {
+		return nil, err
+	}
+	return auth.NewJWTVerifier(keys, auth.JWTOptions{
+		Issuer:     cfg.Issuer,
+		Audience:   cfg.Audience,
+		UserClaim:  cfg.UserClaim,
+		RolesClaim: cfg.RolesClaim,
+		Leeway:     cfg.Leeway,
+	}), nil
+}
+
+func rateLimiterOptions(cfg config.RateLimitConfig, m *metrics.Metrics) api.RateLimiterOptions {
+	routes := make(map[string]api.RateLimit, len(cfg.Routes))
+	for route, rule := range cfg.Routes {
+		routes[route] = api.RateLimit{RPS: rule.RPS, Burst: rule.Burst}
+	}
+	return api.RateLimiterOptions{
+		PerClient:      api.RateLimit{RPS: cfg.PerClient.RPS, Burst: cfg.PerClient.Burst},
+		Default:        api.RateLimit{RPS: cfg.Default.RPS, Burst: cfg.Default.Burst},
+		Routes:         routes,
+		ClientIPHeader: cfg.ClientIPHeader,
+		IdleTimeout:    cfg.IdleTimeout,
+		Metrics:        m,
+	}
+}
+
+func webhookWorkerOptions(cfg config.WebhooksConfig, m *metrics.Metrics) webhook.Options {
+	return webhook.Options{
+		MaxAttempts:    cfg.MaxAttempts,
+		InitialBackoff: cfg.InitialBackoff,
+		MaxBackoff:     cfg.MaxBackoff,
+		PollInterval:   cfg.PollInterval,
+		Timeout:        cfg.Timeout,
+		BatchSize:      cfg.BatchSize,
+		Retention:      cfg.Retention,
+		Metrics:        m,
+	}
+}
+
+// outboxSinks builds the configured sinks; the returned function closes them.
+func outboxSinks(cfg config.OutboxConfig, webhooks service.WebhookService) (map[string]outbox.Sink, func(), error) {
+	sinks := make(map[string]outbox.Sink, len(cfg.Sinks))
+	closeSinks := func() {}
+	for _, name := range cfg.Sinks {
+		switch name {
+		case outbox.SinkWebhook:
+			sinks[name] = webhooks
+		case outbox.SinkLog:
+			sinks[name] = outbox.LogSink{}
+		case outbox.SinkFile:
+			file, err := outbox.NewFileSink(cfg.File)
+			if err != nil {
+				return nil, nil, err
+			}
+			sinks[name] = file
+			closeSinks = func() {
+				if err := file.Close(); err != nil {
+					slog.Error("failed to close event file", "error", err)
+				}
+			}
+		}
+	}
+	return sinks, closeSinks, nil
+}
 
-	log.Printf("Server starting on port 8080. Repository: PostgreSQL")
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("Could not listen on :8080: %v\n", err)
+func outboxRelayOptions(cfg config.OutboxConfig, m *metrics.Metrics) outbox.Options {
+	return outbox.Options{
+		PollInterval:   cfg.PollInterval,
+		BatchSize:      cfg.BatchSize,
+		InitialBackoff: cfg.InitialBackoff,
+		MaxBackoff:     cfg.MaxBackoff,
+		Retention:      cfg.Retention,
+		Metrics:        m,
 	}
 }
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"Backend/internal/config"
+	"Backend/internal/repository"
+	"Backend/internal/repository/memory"
+	"Backend/internal/repository/postgres"
+	"Backend/internal/repository/sqlite"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log/slog"
+
+	_ "github.com/lib/pq"
+)
+
+type storage interface {
+	repository.TeamRepository
+	repository.PullRequestRepository
+	repository.IdempotencyRepository
+	repository.StatsRepository
+	repository.HealthRepository
+	repository.TokenRepository
+	repository.AuditRepository
+	repository.WebhookRepository
+	repository.OutboxRepository
+	repository.UserMappingRepository
+	repository.CodeOwnersRepository
+	repository.ReviewHistoryRepository
+	io.Closer
+}
+
+// openStorage returns the configured repository and, for SQL drivers, its
+// connection pool so that pool statistics can be exported.
+func openStorage(cfg config.StorageConfig) (storage, *sql.DB, error) {
+	switch cfg.Driver {
+	case "postgres":
+		return openPostgres(cfg.Postgres)
+	case "sqlite":
+		return openSQLite(cfg.SQLite)
+	case "memory":
+		slog.Warn("using in-memory repository, data will be lost on restart")
+		return memory.NewMemoryRepository(), nil, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+}
+
+func openPostgres(cfg config.PostgresConfig) (*postgres.PostgresRepository, *sql.DB, error) {
+	slog.Info("initializing PostgreSQL repository", "host", cfg.Host, "database", cfg.Name)
+
+	db, err := sql.Open("postgres", cfg.ConnString())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open DB connection: %w", err)
+	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnectTimeout)
+	defer cancel()
+
+	if err = db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("failed to ping database: %w. Check docker-compose and environment variables", err)
+	}
+
+	pgRepo := postgres.NewPostgresRepository(db)
+	if err = pgRepo.Init(context.Background()); err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("failed to initialize PostgreSQL schema: %w", err)
+	}
+
+	slog.Info("PostgreSQL connection established and schema initialized")
+	return pgRepo, db, nil
+}
+
+func openSQLite(cfg config.SQLiteConfig) (*sqlite.SQLiteRepository, *sql.DB, error) {
+	slog.Info("initializing SQLite repository", "path", cfg.Path)
+
+	db, err := sqlite.Open(cfg.Path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+
+	sqliteRepo := sqlite.NewSQLiteRepository(db)
+	if err = sqliteRepo.Init(context.Background()); err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("failed to migrate SQLite schema: %w", err)
+	}
+
+	slog.Info("SQLite database opened and migrations applied")
+	return sqliteRepo, db, nil
+}
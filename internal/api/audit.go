@@ -0,0 +1,77 @@
+package api
+
+import (
+	"Backend/internal/domain"
+	"Backend/internal/service"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const maxAuditFilterLen = 256
+
+type AuditHandler struct{ auditService service.AuditService }
+
+func NewAuditHandler(auditService service.AuditService) *AuditHandler {
+	return &AuditHandler{auditService: auditService}
+}
+
+// ListAudit serves GET /audit?actor=&target=&from=&to=&limit=, newest entries first.
+func (h *AuditHandler) ListAudit(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	entries, err := h.auditService.ListAuditEntries(r.Context(), filter)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, map[string]interface{}{"entries": entries})
+}
+
+func parseAuditFilter(r *http.Request) (domain.AuditFilter, error) {
+	query := r.URL.Query()
+	filter := domain.AuditFilter{Actor: query.Get("actor"), Target: query.Get("target")}
+	var details []domain.FieldError
+
+	for _, p := range []struct{ name, value string }{{"actor", filter.Actor}, {"target", filter.Target}} {
+		if len(p.value) > maxAuditFilterLen {
+			details = append(details, domain.FieldError{Field: p.name, Message: "must be at most " + strconv.Itoa(maxAuditFilterLen) + " characters"})
+		}
+	}
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		value := query.Get(p.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			details = append(details, domain.FieldError{Field: p.name, Message: "must be an RFC 3339 timestamp"})
+			continue
+		}
+		t = t.UTC()
+		*p.dst = &t
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		details = append(details, domain.FieldError{Field: "to", Message: "must be after from"})
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > service.MaxAuditLimit {
+			details = append(details, domain.FieldError{Field: "limit", Message: "must be an integer between 1 and " + strconv.Itoa(service.MaxAuditLimit)})
+		}
+		filter.Limit = limit
+	}
+
+	if len(details) > 0 {
+		return domain.AuditFilter{}, domain.NewValidationError(details...)
+	}
+	return filter, nil
+}
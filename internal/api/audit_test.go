@@ -0,0 +1,59 @@
+package api
+
+import (
+	"Backend/internal/domain"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestAudit_RecordsCallerAndRequestID(t *testing.T) {
+	f := newAuthFixture(t)
+
+	rec := f.do("POST", "/users/setIsActive", f.secrets[domain.RoleTeamLead], `{"user_id":"u3","is_active":false}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	requestID := rec.Header().Get(requestIDHeader)
+
+	rec = f.do("GET", "/audit?target=u3&actor=u2&from=2020-01-01T00:00:00Z", f.secrets[domain.RoleAdmin], "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Entries []domain.AuditEntry `json:"entries"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Entries) != 1 {
+		t.Fatalf("Expected one entry, got %+v", resp.Entries)
+	}
+	entry := resp.Entries[0]
+	if entry.Action != domain.AuditUserSetActive || entry.RequestID != requestID || requestID == "" {
+		t.Errorf("Unexpected entry %+v (request id %q)", entry, requestID)
+	}
+	if string(entry.After) == "" || string(entry.Before) == "" {
+		t.Errorf("Expected before and after snapshots, got %+v", entry)
+	}
+}
+
+func TestAudit_InvalidFilters(t *testing.T) {
+	f := newAuthFixture(t)
+
+	rec := f.do("GET", "/audit?from=yesterday&limit=0", f.secrets[domain.RoleAdmin], "")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp domain.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode error envelope: %v", err)
+	}
+	fields := map[string]bool{}
+	for _, d := range resp.Error.Details {
+		fields[d.Field] = true
+	}
+	if resp.Error.Code != domain.ErrValidationFailed || !fields["from"] || !fields["limit"] {
+		t.Errorf("Expected field errors for from and limit, got %+v", resp.Error)
+	}
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"Backend/internal/auth"
+	"Backend/internal/domain"
+	"Backend/internal/service"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Authenticator resolves a bearer credential to the caller's identity.
+type Authenticator = auth.Authenticator
+
+// routePolicy lists the roles allowed to call each protected route; an empty
+// list admits any authenticated caller. Routes without an entry are public.
+type routePolicy map[*mux.Route][]domain.Role
+
+func (p routePolicy) allow(route *mux.Route, roles ...domain.Role) {
+	p[route] = append([]domain.Role{}, roles...)
+}
+
+// requireAuth authenticates callers of protected routes and enforces the
+// route's roles. It runs before the idempotency middleware so that stored
+// responses are never replayed to unauthorized callers.
+func requireAuth(authn Authenticator, policy routePolicy) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			roles, protected := policy[mux.CurrentRoute(r)]
+			if !protected {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, ok := bearerToken(r)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="pr-reviewer"`)
+				writeError(w, r, http.StatusUnauthorized, domain.NewBusinessError(domain.ErrUnauthorized, "Missing bearer token"))
+				return
+			}
+			identity, err := authn.Authenticate(r.Context(), token)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="pr-reviewer", error="invalid_token"`)
+				handleServiceError(w, r, err)
+				return
+			}
+			if state := getRequestState(r.Context()); state != nil {
+				state.subject = identity.Subject
+			}
+			if !identity.HasAnyRole(roles...) {
+				writeError(w, r, http.StatusForbidden, domain.NewBusinessError(domain.ErrForbidden, "Insufficient role for this operation"))
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithIdentity(r.Context(), identity)))
+		})
+	}
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// privileged reports whether the caller may act on behalf of other users. It
+// is true when authentication is disabled.
+func privileged(r *http.Request) bool {
+	identity, ok := auth.IdentityFromContext(r.Context())
+	return !ok || identity.HasAnyRole(domain.RoleAdmin, domain.RoleTeamLead, domain.RoleBot)
+}
+
+// callerIs reports whether the authenticated caller is the user userID.
+func callerIs(r *http.Request, userID string) bool {
+	identity, _ := auth.IdentityFromContext(r.Context())
+	return identity.UserID != "" && identity.UserID == userID
+}
+
+type TokenHandler struct{ authService service.AuthService }
+
+func NewTokenHandler(authService service.AuthService) *TokenHandler {
+	return &TokenHandler{authService: authService}
+}
+
+func (h *TokenHandler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	var reqBody TokenCreateRequestDTO
+	if err := decodeJSONBody(r, &reqBody); err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	roles := make([]domain.Role, len(reqBody.Roles))
+	for i, role := range reqBody.Roles {
+		roles[i] = domain.Role(role)
+	}
+	token, secret, err := h.authService.IssueToken(r.Context(), reqBody.Name, reqBody.UserID, roles, reqBody.ExpiresAt)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	sendJSONResponse(w, http.StatusCreated, TokenCreateResponseDTO{Token: secret, APIToken: token})
+}
+
+func (h *TokenHandler) ListTokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := h.authService.ListTokens(r.Context())
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, map[string]interface{}{"tokens": tokens})
+}
+
+func (h *TokenHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	var reqBody TokenRevokeRequestDTO
+	if err := decodeJSONBody(r, &reqBody); err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	token, err := h.authService.RevokeToken(r.Context(), reqBody.ID)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, token)
+}
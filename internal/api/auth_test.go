@@ -0,0 +1,202 @@
+package api
+
+import (
+	"Backend/internal/domain"
+	"Backend/internal/repository/memory"
+	"Backend/internal/service"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type authFixture struct {
+	router        http.Handler
+	authenticator Authenticator
+	secrets       map[domain.Role]string
+	repo          *memory.MemoryRepository
+}
+
+// newAuthFixture seeds a team with u1..u3, a PR by u1 reviewed by u2, and one
+// token per role (member and team-lead tokens belong to u2).
+func newAuthFixture(t *testing.T) authFixture {
+	t.Helper()
+	ctx := context.Background()
+	repo := memory.NewMemoryRepository()
+
+	members := []domain.User{}
+	for _, id := range []string{"u1", "u2", "u3"} {
+		members = append(members, domain.User{UserID: id, Username: id, TeamName: "backend", IsActive: true})
+	}
+	if _, err := repo.CreateOrUpdateTeam(ctx, domain.Team{TeamName: "backend", Members: members}); err != nil {
+		t.Fatalf("Failed to seed team: %v", err)
+	}
+	now := time.Now().UTC()
+	_, err := repo.CreatePullRequest(ctx, domain.PullRequest{
+		PullRequestID: "pr-1", PullRequestName: "Fix", AuthorID: "u1",
+		Status: domain.StatusOpen, AssignedReviewers: []string{"u2"}, CreatedAt: &now,
+	})
+	if err != nil {
+		t.Fatalf("Failed to seed PR: %v", err)
+	}
+
+	authService := service.NewAuthService(repo, repo)
+	secrets := map[domain.Role]string{}
+	for role, userID := range map[domain.Role]string{domain.RoleAdmin: "", domain.RoleTeamLead: "u2", domain.RoleMember: "u2", domain.RoleBot: ""} {
+		_, secret, err := authService.IssueToken(ctx, string(role), userID, []domain.Role{role}, nil)
+		if err != nil {
+			t.Fatalf("Failed to issue %s token: %v", role, err)
+		}
+		secrets[role] = secret
+	}
+
+	prService := service.NewAuditedPRService(service.NewPRService(repo, repo))
+	router := NewRouter(
+		NewPRHandler(prService),
+		NewTeamHandler(service.NewAuditedTeamService(service.NewTeamService(repo))),
+		NewUserHandler(service.NewAuditedUserService(service.NewUserService(repo, repo))),
+		RouterOptions{Auth: authService, Tokens: NewTokenHandler(authService), Audit: NewAuditHandler(service.NewAuditService(repo)),
+			Webhooks:     NewWebhookHandler(service.NewWebhookService(repo)),
+			Integrations: NewIntegrationHandler(service.NewIntegrationService(prService, repo, repo), IntegrationOptions{}),
+			CodeOwners:   NewCodeOwnersHandler(service.NewAuditedCodeOwnersService(service.NewCodeOwnersService(repo, repo))),
+			Expertise:    NewExpertiseHandler(service.NewExpertiseService(repo, repo, service.ExpertiseOptions{}))},
+	)
+	return authFixture{router: router, authenticator: authService, secrets: secrets, repo: repo}
+}
+
+func (f authFixture) do(method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	f.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func errorCodeOf(t *testing.T, rec *httptest.ResponseRecorder) domain.ErrorCode {
+	t.Helper()
+	var resp domain.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode error envelope: %v", err)
+	}
+	return resp.Error.Code
+}
+
+func TestAuth_RoutePolicy(t *testing.T) {
+	f := newAuthFixture(t)
+	team := `{"team_name":"qa","members":[{"user_id":"q1","username":"Q","is_active":true}]}`
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		role   domain.Role
+		body   string
+		status int
+	}{
+		{"public health", "GET", "/health", "", "", http.StatusOK},
+		{"missing token", "GET", "/pullRequest/get?pull_request_id=pr-1", "", "", http.StatusUnauthorized},
+		{"any role reads", "GET", "/pullRequest/get?pull_request_id=pr-1", domain.RoleBot, "", http.StatusOK},
+		{"member cannot add team", "POST", "/team/add", domain.RoleMember, team, http.StatusForbidden},
+		{"lead adds team", "POST", "/team/add", domain.RoleTeamLead, team, http.StatusOK},
+		{"member cannot upload codeowners", "POST", "/team/codeowners", domain.RoleMember, `{"team_name":"backend","rules":"* u3"}`, http.StatusForbidden},
+		{"lead uploads codeowners", "POST", "/team/codeowners", domain.RoleTeamLead, `{"team_name":"backend","rules":"* u3"}`, http.StatusOK},
+		{"lead cannot upload unknown owners", "POST", "/team/codeowners", domain.RoleTeamLead, `{"team_name":"backend","rules":"* nobody"}`, http.StatusBadRequest},
+		{"member reads codeowners", "GET", "/team/codeowners?team_name=backend", domain.RoleMember, "", http.StatusOK},
+		{"member reads expertise", "GET", "/users/expertise?user_id=u2", domain.RoleMember, "", http.StatusOK},
+		{"expertise of unknown user", "GET", "/users/expertise?user_id=nobody", domain.RoleMember, "", http.StatusNotFound},
+		{"expertise of invalid user id", "GET", "/users/expertise?user_id=a%20b", domain.RoleMember, "", http.StatusBadRequest},
+		{"member cannot deactivate", "POST", "/users/setIsActive", domain.RoleMember, `{"user_id":"u3","is_active":false}`, http.StatusForbidden},
+		{"member cannot manage tokens", "GET", "/admin/tokens", domain.RoleMember, "", http.StatusForbidden},
+		{"admin lists tokens", "GET", "/admin/tokens", domain.RoleAdmin, "", http.StatusOK},
+		{"member cannot read audit", "GET", "/audit", domain.RoleMember, "", http.StatusForbidden},
+		{"lead reads audit", "GET", "/audit", domain.RoleTeamLead, "", http.StatusOK},
+		{"lead cannot map logins", "POST", "/admin/integrations/mappings", domain.RoleTeamLead, `{"provider":"github","login":"alice","user_id":"u1"}`, http.StatusForbidden},
+		{"admin maps login", "POST", "/admin/integrations/mappings", domain.RoleAdmin, `{"provider":"github","login":"Alice","user_id":"u1"}`, http.StatusOK},
+		{"admin cannot map unknown provider", "POST", "/admin/integrations/mappings", domain.RoleAdmin, `{"provider":"svn","login":"alice","user_id":"u1"}`, http.StatusBadRequest},
+		{"admin cannot map unknown user", "POST", "/admin/integrations/mappings", domain.RoleAdmin, `{"provider":"github","login":"bob","user_id":"nobody"}`, http.StatusNotFound},
+		{"admin lists mappings", "GET", "/admin/integrations/mappings?provider=github", domain.RoleAdmin, "", http.StatusOK},
+		{"github webhook is off without a secret", "POST", "/integrations/github/webhook", "", "{}", http.StatusNotFound},
+		{"gitlab webhook is off without a token", "POST", "/integrations/gitlab/webhook", "", "{}", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := f.do(tt.method, tt.path, f.secrets[tt.role], tt.body)
+			if rec.Code != tt.status {
+				t.Errorf("Expected %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuth_InvalidTokenIsRejected(t *testing.T) {
+	f := newAuthFixture(t)
+
+	rec := f.do("GET", "/pullRequest/get?pull_request_id=pr-1", "prr_not-a-real-token", "")
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") == "" {
+		t.Fatalf("Expected 401 with WWW-Authenticate, got %d", rec.Code)
+	}
+	if code := errorCodeOf(t, rec); code != domain.ErrUnauthorized {
+		t.Errorf("Expected UNAUTHORIZED, got %s", code)
+	}
+}
+
+func TestAuth_ReviewersMayOnlyReassignThemselves(t *testing.T) {
+	f := newAuthFixture(t)
+	member := f.secrets[domain.RoleMember] // u2
+
+	rec := f.do("POST", "/pullRequest/reassign", member, `{"pull_request_id":"pr-1","old_user_id":"u3"}`)
+	if rec.Code != http.StatusForbidden || errorCodeOf(t, rec) != domain.ErrForbidden {
+		t.Fatalf("Expected 403 FORBIDDEN when reassigning someone else, got %d", rec.Code)
+	}
+
+	rec = f.do("POST", "/pullRequest/reassign", member, `{"pull_request_id":"pr-1","old_user_id":"u2"}`)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected reviewer to reassign themselves, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAuth_MembersMayOnlyMergeOwnPRs(t *testing.T) {
+	f := newAuthFixture(t)
+
+	rec := f.do("POST", "/pullRequest/merge", f.secrets[domain.RoleMember], `{"pull_request_id":"pr-1"}`)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("Expected 403 for a member merging another user's PR, got %d", rec.Code)
+	}
+
+	rec = f.do("POST", "/pullRequest/merge", f.secrets[domain.RoleBot], `{"pull_request_id":"pr-1"}`)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected bot to merge, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAuth_AdminIssuesAndRevokesTokens(t *testing.T) {
+	f := newAuthFixture(t)
+	admin := f.secrets[domain.RoleAdmin]
+
+	rec := f.do("POST", "/admin/tokens", admin, `{"name":"ci","roles":["bot"]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created TokenCreateResponseDTO
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !strings.HasPrefix(created.Token, "prr_") {
+		t.Fatalf("Expected a prr_ token, got %q", created.Token)
+	}
+
+	if rec := f.do("GET", "/pullRequest/get?pull_request_id=pr-1", created.Token, ""); rec.Code != http.StatusOK {
+		t.Fatalf("Expected new token to work, got %d", rec.Code)
+	}
+	if rec := f.do("POST", "/admin/tokens/revoke", admin, `{"id":"`+created.APIToken.ID+`"}`); rec.Code != http.StatusOK {
+		t.Fatalf("Expected revoke to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := f.do("GET", "/pullRequest/get?pull_request_id=pr-1", created.Token, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected revoked token to be rejected, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"Backend/internal/domain"
+	"Backend/internal/service"
+	"net/http"
+)
+
+type CodeOwnersHandler struct{ codeOwnersService service.CodeOwnersService }
+
+func NewCodeOwnersHandler(codeOwnersService service.CodeOwnersService) *CodeOwnersHandler {
+	return &CodeOwnersHandler{codeOwnersService: codeOwnersService}
+}
+
+// SetCodeOwners serves POST /team/codeowners, replacing the team's rules.
+func (h *CodeOwnersHandler) SetCodeOwners(w http.ResponseWriter, r *http.Request) {
+	var reqBody CodeOwnersRequestDTO
+	if err := decodeJSONBody(r, &reqBody); err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	saved, err := h.codeOwnersService.SetCodeOwners(r.Context(), reqBody.TeamName, reqBody.Rules)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, saved)
+}
+
+// GetCodeOwners serves GET /team/codeowners?team_name=.
+func (h *CodeOwnersHandler) GetCodeOwners(w http.ResponseWriter, r *http.Request) {
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		handleServiceError(w, r, domain.NewValidationError(domain.FieldError{Field: "team_name", Message: "is required"}))
+		return
+	}
+
+	rules, err := h.codeOwnersService.GetCodeOwners(r.Context(), teamName)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, rules)
+}
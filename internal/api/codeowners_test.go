@@ -0,0 +1,57 @@
+package api
+
+import (
+	"Backend/internal/domain"
+	"Backend/internal/repository/memory"
+	"Backend/internal/service"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCodeOwners_UploadAndCreatePRWithChangedPaths(t *testing.T) {
+	repo := memory.NewMemoryRepository()
+	members := []domain.User{}
+	for _, id := range []string{"u1", "u2", "u3"} {
+		members = append(members, domain.User{UserID: id, Username: id, TeamName: "backend", IsActive: true})
+	}
+	if _, err := repo.CreateOrUpdateTeam(context.Background(), domain.Team{TeamName: "backend", Members: members}); err != nil {
+		t.Fatalf("Failed to seed team: %v", err)
+	}
+	prService := service.NewPRServiceWithOptions(repo, repo, service.PRServiceOptions{ReviewerCount: 1, CodeOwners: repo})
+	router := NewRouter(NewPRHandler(prService), NewTeamHandler(service.NewTeamService(repo)), NewUserHandler(nil),
+		RouterOptions{CodeOwners: NewCodeOwnersHandler(service.NewCodeOwnersService(repo, repo))})
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := do("GET", "/team/codeowners?team_name=backend", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404 before an upload, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do("POST", "/team/codeowners", `{"team_name":"backend","rules":"/internal/ u3\n*.md u2"}`); rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec := do("GET", "/team/codeowners?team_name=backend", "")
+	var saved domain.CodeOwners
+	if err := json.NewDecoder(rec.Body).Decode(&saved); err != nil || saved.Rules != "/internal/ u3\n*.md u2" {
+		t.Fatalf("Expected the uploaded rules, got %+v, %v", saved, err)
+	}
+
+	rec = do("POST", "/pullRequest/create", `{"pull_request_id":"pr-1","pull_request_name":"Fix","author_id":"u1","changed_paths":["internal/api/router.go"]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var pr domain.PullRequest
+	if err := json.NewDecoder(rec.Body).Decode(&pr); err != nil {
+		t.Fatalf("Failed to decode PR: %v", err)
+	}
+	if !slices.Equal(pr.AssignedReviewers, []string{"u3"}) || !slices.Equal(pr.ChangedPaths, []string{"internal/api/router.go"}) {
+		t.Errorf("Expected owner u3 and the changed paths, got %v and %v", pr.AssignedReviewers, pr.ChangedPaths)
+	}
+}
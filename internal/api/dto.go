@@ -1,13 +1,20 @@
 package api
 
+import (
+	"Backend/internal/domain"
+	"time"
+)
+
+// Request DTOs are checked by validate (see validate.go) after decoding.
+
 type TeamMemberDTO struct {
-	UserID   string `json:"user_id"`
-	Username string `json:"username"`
+	UserID   string `json:"user_id" validate:"required,id"`
+	Username string `json:"username" validate:"required,max=128"`
 	IsActive bool   `json:"is_active"`
 }
 type TeamRequestDTO struct {
-	TeamName string          `json:"team_name"`
-	Members  []TeamMemberDTO `json:"members"`
+	TeamName string          `json:"team_name" validate:"required,max=128"`
+	Members  []TeamMemberDTO `json:"members" validate:"max=500,dive,unique=user_id"`
 }
 
 type TeamResponseDTO struct {
@@ -16,17 +23,85 @@ type TeamResponseDTO struct {
 }
 
 type UserIsActiveRequestDTO struct {
-	UserID   string `json:"user_id"`
-	IsActive bool   `json:"is_active"`
+	UserID   string `json:"user_id" validate:"required,id"`
+	IsActive *bool  `json:"is_active" validate:"required"`
 }
 
 type PullRequestCreateRequestDTO struct {
-	PullRequestID   string `json:"pull_request_id"`
-	PullRequestName string `json:"pull_request_name"`
-	AuthorID        string `json:"author_id"`
+	PullRequestID   string `json:"pull_request_id" validate:"required,id"`
+	PullRequestName string `json:"pull_request_name" validate:"required,max=256"`
+	AuthorID        string `json:"author_id" validate:"required,id"`
+	// ChangedPaths are the paths touched by the PR, used to find their owners.
+	ChangedPaths []string `json:"changed_paths" validate:"max=1000"`
+	Labels       []string `json:"labels" validate:"max=100"`
+}
+
+type CodeOwnersRequestDTO struct {
+	TeamName string `json:"team_name" validate:"required,max=128"`
+	Rules    string `json:"rules" validate:"required,max=65536"`
+}
+
+type PullRequestMergeRequestDTO struct {
+	PullRequestID string `json:"pull_request_id" validate:"required,id"`
 }
 
 type PullRequestReassignRequestDTO struct {
-	PullRequestID string `json:"pull_request_id"`
-	OldUserID     string `json:"old_user_id"`
+	PullRequestID string `json:"pull_request_id" validate:"required,id"`
+	OldUserID     string `json:"old_user_id" validate:"required,id"`
+}
+
+type TokenCreateRequestDTO struct {
+	Name      string     `json:"name" validate:"required,max=128"`
+	UserID    string     `json:"user_id" validate:"id"`
+	Roles     []string   `json:"roles" validate:"required,max=4"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type TokenCreateResponseDTO struct {
+	// Token is the bearer secret; it is only ever returned here.
+	Token    string          `json:"token"`
+	APIToken domain.APIToken `json:"api_token"`
+}
+
+type TokenRevokeRequestDTO struct {
+	ID string `json:"id" validate:"required,id"`
+}
+
+type WebhookCreateRequestDTO struct {
+	URL string `json:"url" validate:"required,max=2048"`
+	// Secret signs the payloads; one is generated when it is empty.
+	Secret string   `json:"secret" validate:"max=256"`
+	Events []string `json:"events" validate:"required,max=16"`
+}
+
+type WebhookCreateResponseDTO struct {
+	// Secret is only ever returned here.
+	Secret       string                     `json:"secret"`
+	Subscription domain.WebhookSubscription `json:"subscription"`
+}
+
+type WebhookDeleteRequestDTO struct {
+	ID string `json:"id" validate:"required,id"`
+}
+
+type WebhookRetryRequestDTO struct {
+	ID string `json:"id" validate:"required,id"`
+}
+
+type UserMappingRequestDTO struct {
+	Provider string `json:"provider" validate:"required,max=32"`
+	Login    string `json:"login" validate:"required,max=255"`
+	UserID   string `json:"user_id" validate:"required,id"`
+}
+
+type UserMappingDeleteRequestDTO struct {
+	Provider string `json:"provider" validate:"required,max=32"`
+	Login    string `json:"login" validate:"required,max=255"`
+}
+
+// IntegrationResponseDTO answers a code host webhook. Result is "applied" with
+// the resulting PR, or "ignored" for events that do not affect PRs.
+type IntegrationResponseDTO struct {
+	Result      string              `json:"result"`
+	PullRequest *domain.PullRequest `json:"pull_request,omitempty"`
 }
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"Backend/internal/domain"
+	"Backend/internal/service"
+	"net/http"
+	"strconv"
+)
+
+const maxAreaLen = 256
+
+type ExpertiseHandler struct{ expertiseService service.ExpertiseService }
+
+func NewExpertiseHandler(expertiseService service.ExpertiseService) *ExpertiseHandler {
+	return &ExpertiseHandler{expertiseService: expertiseService}
+}
+
+// ListExpertise serves GET /users/expertise?user_id=&area=, best scores first.
+func (h *ExpertiseHandler) ListExpertise(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	userID, area := query.Get("user_id"), query.Get("area")
+	if userID != "" {
+		if msg := checkID(userID); msg != "" {
+			handleServiceError(w, r, domain.NewValidationError(domain.FieldError{Field: "user_id", Message: msg}))
+			return
+		}
+	}
+	if len(area) > maxAreaLen {
+		handleServiceError(w, r, domain.NewValidationError(domain.FieldError{
+			Field: "area", Message: "must be at most " + strconv.Itoa(maxAreaLen) + " characters"}))
+		return
+	}
+
+	scores, err := h.expertiseService.ListExpertise(r.Context(), userID, area)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, map[string]interface{}{"scores": scores})
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"Backend/internal/codehost"
+	"Backend/internal/domain"
+	"Backend/internal/service"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+const (
+	gitHubSignatureHeader = "X-Hub-Signature-256"
+	gitHubEventHeader     = "X-GitHub-Event"
+)
+
+// gitHubPullRequestEvent holds the fields of a pull_request webhook payload
+// that are used.
+type gitHubPullRequestEvent struct {
+	Action      string `json:"action"`
+	Number      int    `json:"number"`
+	PullRequest struct {
+		Title  string `json:"title"`
+		Merged bool   `json:"merged"`
+		User   struct {
+			Login string `json:"login"`
+		} `json:"user"`
+		Labels []struct {
+			Name string `json:"name"`
+		} `json:"labels"`
+	} `json:"pull_request"`
+	Repository struct {
+		ID int64 `json:"id"`
+	} `json:"repository"`
+}
+
+// GitHubWebhook serves POST /integrations/github/webhook. Pull requests are
+// identified as github-<repository id>-<number>, which survives renames of
+// the repository.
+func (h *IntegrationHandler) GitHubWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := readWebhookBody(w, r)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+	if !validGitHubSignature(h.opts.GitHubSecret, r.Header.Get(gitHubSignatureHeader), body) {
+		writeError(w, r, http.StatusUnauthorized, domain.NewBusinessError(domain.ErrUnauthorized, "Invalid webhook signature"))
+		return
+	}
+	if r.Header.Get(gitHubEventHeader) != "pull_request" {
+		ignoreEvent(w)
+		return
+	}
+
+	var event gitHubPullRequestEvent
+	if err := json.Unmarshal(body, &event); err != nil || event.Number == 0 || event.Repository.ID == 0 {
+		handleServiceError(w, r, domain.NewBusinessError(domain.ErrInvalidRequest, "Invalid pull_request payload"))
+		return
+	}
+
+	change := service.PullRequestChange{
+		Provider:      domain.ProviderGitHub,
+		PullRequestID: codehost.PullRequestRef{Provider: domain.ProviderGitHub, RepoID: event.Repository.ID, Number: event.Number}.String(),
+		Title:         event.PullRequest.Title,
+		AuthorLogin:   event.PullRequest.User.Login,
+	}
+	for _, label := range event.PullRequest.Labels {
+		change.Labels = append(change.Labels, label.Name)
+	}
+	switch {
+	case event.Action == "opened":
+		change.Action = service.ChangeOpened
+	case event.Action == "closed" && event.PullRequest.Merged:
+		change.Action = service.ChangeMerged
+	case event.Action == "closed":
+		change.Action = service.ChangeClosed
+	case event.Action == "reopened":
+		change.Action = service.ChangeReopened
+	default:
+		ignoreEvent(w)
+		return
+	}
+	h.applyChange(w, r, change)
+}
+
+// validGitHubSignature checks header, "sha256=" followed by the hex HMAC-SHA256
+// of body keyed with secret.
+func validGitHubSignature(secret, header string, body []byte) bool {
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"Backend/internal/domain"
+	"Backend/internal/metrics"
+	"Backend/internal/repository/memory"
+	"Backend/internal/service"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	testGitHubSecret = "0123456789abcdef-github"
+	testGitLabToken  = "0123456789abcdef-gitlab"
+)
+
+// fixturePRID is the id of the pull request in testdata/github.
+const fixturePRID = "github-712345678-42"
+
+func newIntegrationFixture(t *testing.T) (http.Handler, *memory.MemoryRepository) {
+	t.Helper()
+	ctx := context.Background()
+	repo := memory.NewMemoryRepository()
+	_, err := repo.CreateOrUpdateTeam(ctx, domain.Team{TeamName: "backend", Members: []domain.User{
+		{UserID: "u1", Username: "Alice", TeamName: "backend", IsActive: true},
+		{UserID: "u2", Username: "Bob", TeamName: "backend", IsActive: true},
+		{UserID: "u3", Username: "Carol", TeamName: "backend", IsActive: true},
+	}})
+	if err != nil {
+		t.Fatalf("Failed to seed team: %v", err)
+	}
+
+	prService := service.NewAuditedPRService(service.NewPRService(repo, repo))
+	integrations := service.NewIntegrationService(prService, repo, repo)
+	for provider, login := range map[string]string{domain.ProviderGitHub: "octo-dev", domain.ProviderGitLab: "jane.doe"} {
+		if _, err := integrations.SetUserMapping(ctx, provider, login, "u1"); err != nil {
+			t.Fatalf("Failed to map %s login: %v", provider, err)
+		}
+	}
+
+	router := NewRouter(NewPRHandler(prService), NewTeamHandler(nil), NewUserHandler(nil), RouterOptions{
+		Metrics: metrics.New(),
+		Integrations: NewIntegrationHandler(integrations, IntegrationOptions{
+			GitHubSecret: testGitHubSecret,
+			GitLabToken:  testGitLabToken,
+		}),
+	})
+	return router, repo
+}
+
+// deliver posts testdata/github/<fixture> as GitHub does, signed with secret.
+func deliver(t *testing.T, router http.Handler, event, fixture, secret string) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", "github", fixture))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	req := httptest.NewRequest("POST", "/integrations/github/webhook", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", event)
+	req.Header.Set("X-GitHub-Delivery", "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeIntegrationResponse(t *testing.T, rec *httptest.ResponseRecorder) IntegrationResponseDTO {
+	t.Helper()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp IntegrationResponseDTO
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp
+}
+
+func TestGitHubWebhook_DrivesPullRequestLifecycle(t *testing.T) {
+	router, repo := newIntegrationFixture(t)
+
+	steps := []struct {
+		fixture string
+		status  domain.PullRequestStatus
+	}{
+		{"pull_request_opened.json", domain.StatusOpen},
+		// GitHub redelivers events; the second delivery changes nothing.
+		{"pull_request_opened.json", domain.StatusOpen},
+		{"pull_request_closed.json", domain.StatusClosed},
+		{"pull_request_reopened.json", domain.StatusOpen},
+		{"pull_request_merged.json", domain.StatusMerged},
+	}
+	var reviewers []string
+	for _, step := range steps {
+		resp := decodeIntegrationResponse(t, deliver(t, router, "pull_request", step.fixture, testGitHubSecret))
+		if resp.Result != "applied" || resp.PullRequest == nil {
+			t.Fatalf("%s: expected the change to be applied, got %+v", step.fixture, resp)
+		}
+		pr := resp.PullRequest
+		if pr.PullRequestID != fixturePRID || pr.Status != step.status {
+			t.Fatalf("%s: expected %s in %s, got %s in %s", step.fixture, fixturePRID, step.status, pr.PullRequestID, pr.Status)
+		}
+		if reviewers == nil {
+			reviewers = pr.AssignedReviewers
+		} else if len(pr.AssignedReviewers) != len(reviewers) {
+			t.Fatalf("%s: expected reviewers %v to be kept, got %v", step.fixture, reviewers, pr.AssignedReviewers)
+		}
+	}
+
+	pr, err := repo.GetPullRequestByID(context.Background(), fixturePRID)
+	if err != nil {
+		t.Fatalf("Failed to get PR: %v", err)
+	}
+	if pr.AuthorID != "u1" || pr.PullRequestName != "Add retry to payment client" || pr.CodeHostRef != fixturePRID || len(pr.AssignedReviewers) != 2 ||
+		len(pr.Labels) != 1 || pr.Labels[0] != "payments" {
+		t.Errorf("Expected u1's labelled PR with two reviewers, got %+v", pr)
+	}
+
+	entries, err := repo.ListAuditEntries(context.Background(), domain.AuditFilter{Target: fixturePRID, Limit: 10})
+	if err != nil {
+		t.Fatalf("Failed to list audit entries: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("Expected create, close, reopen and merge to be audited, got %d entries", len(entries))
+	}
+	for _, e := range entries {
+		if e.Actor != "integration:github" {
+			t.Errorf("Expected the integration to be the actor of %s, got %q", e.Action, e.Actor)
+		}
+	}
+}
+
+func TestGitHubWebhook_RejectsInvalidSignature(t *testing.T) {
+	router, repo := newIntegrationFixture(t)
+
+	rec := deliver(t, router, "pull_request", "pull_request_opened.json", "not-the-configured-secret")
+	if rec.Code != http.StatusUnauthorized || errorCodeOf(t, rec) != domain.ErrUnauthorized {
+		t.Fatalf("Expected 401 UNAUTHORIZED, got %d", rec.Code)
+	}
+	if _, err := repo.GetPullRequestByID(context.Background(), fixturePRID); err == nil {
+		t.Error("Expected no PR to be created from an unsigned payload")
+	}
+}
+
+func TestGitHubWebhook_IgnoresOtherEvents(t *testing.T) {
+	router, repo := newIntegrationFixture(t)
+
+	for _, tc := range []struct{ event, fixture string }{
+		{"ping", "ping.json"},
+		{"pull_request", "pull_request_synchronize.json"},
+	} {
+		resp := decodeIntegrationResponse(t, deliver(t, router, tc.event, tc.fixture, testGitHubSecret))
+		if resp.Result != "ignored" || resp.PullRequest != nil {
+			t.Errorf("%s: expected the event to be ignored, got %+v", tc.fixture, resp)
+		}
+	}
+	if _, err := repo.GetPullRequestByID(context.Background(), fixturePRID); err == nil {
+		t.Error("Expected ignored events to leave PRs alone")
+	}
+}
+
+func TestGitHubWebhook_IgnoresUntrackedPullRequests(t *testing.T) {
+	router, repo := newIntegrationFixture(t)
+
+	for _, fixture := range []string{"pull_request_closed.json", "pull_request_reopened.json", "pull_request_merged.json"} {
+		resp := decodeIntegrationResponse(t, deliver(t, router, "pull_request", fixture, testGitHubSecret))
+		if resp.Result != "ignored" {
+			t.Errorf("%s: expected a PR opened before the integration to be ignored, got %+v", fixture, resp)
+		}
+	}
+	if _, err := repo.GetPullRequestByID(context.Background(), fixturePRID); err == nil {
+		t.Error("Expected ignored events to leave PRs alone")
+	}
+}
+
+func TestGitHubWebhook_UnmappedAuthor(t *testing.T) {
+	router, repo := newIntegrationFixture(t)
+	if _, err := repo.DeleteUserMapping(context.Background(), domain.ProviderGitHub, "octo-dev"); err != nil {
+		t.Fatalf("Failed to delete mapping: %v", err)
+	}
+
+	rec := deliver(t, router, "pull_request", "pull_request_opened.json", testGitHubSecret)
+	if rec.Code != http.StatusNotFound || errorCodeOf(t, rec) != domain.ErrNotFound {
+		t.Errorf("Expected 404 NOT_FOUND for an unmapped author, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestUserMappings_AvailableWithoutAuth(t *testing.T) {
+	router, _ := newIntegrationFixture(t)
+
+	req := httptest.NewRequest("POST", "/admin/integrations/mappings", bytes.NewBufferString(`{"provider":"github","login":"octocat","user_id":"u2"}`))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the mapping to be saved without auth, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/admin/integrations/mappings?provider=github", nil))
+	if rec.Code != http.StatusOK || !bytes.Contains(rec.Body.Bytes(), []byte("octocat")) {
+		t.Errorf("Expected the mapping to be listed, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"Backend/internal/codehost"
+	"Backend/internal/domain"
+	"Backend/internal/service"
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+const (
+	gitLabTokenHeader = "X-Gitlab-Token"
+	gitLabEventHeader = "X-Gitlab-Event"
+)
+
+// gitLabMergeRequestEvent holds the fields of a Merge Request Hook payload
+// that are used.
+type gitLabMergeRequestEvent struct {
+	ObjectKind string `json:"object_kind"`
+	// User triggered the event, which is not necessarily the author.
+	User struct {
+		ID       int64  `json:"id"`
+		Username string `json:"username"`
+	} `json:"user"`
+	Project struct {
+		ID int64 `json:"id"`
+	} `json:"project"`
+	ObjectAttributes struct {
+		IID      int    `json:"iid"`
+		AuthorID int64  `json:"author_id"`
+		Title    string `json:"title"`
+		Action   string `json:"action"`
+		Draft    bool   `json:"draft"`
+		// WorkInProgress is the draft flag of GitLab releases before 13.2.
+		WorkInProgress bool `json:"work_in_progress"`
+	} `json:"object_attributes"`
+	Labels []struct {
+		Title string `json:"title"`
+	} `json:"labels"`
+	Changes struct {
+		Draft *struct {
+			Previous bool `json:"previous"`
+			Current  bool `json:"current"`
+		} `json:"draft"`
+	} `json:"changes"`
+}
+
+// GitLabWebhook serves POST /integrations/gitlab/webhook. Merge requests are
+// identified as gitlab-<project id>-<iid>. Draft merge requests are not
+// reviewed: reviewers are assigned when one is opened as ready or marked as
+// ready, and closing or reopening one that never was ready is ignored. The
+// payload names the author only by id, so a merge request that someone else
+// marks as ready is ignored rather than opened on behalf of the wrong user.
+func (h *IntegrationHandler) GitLabWebhook(w http.ResponseWriter, r *http.Request) {
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(gitLabTokenHeader)), []byte(h.opts.GitLabToken)) != 1 {
+		writeError(w, r, http.StatusUnauthorized, domain.NewBusinessError(domain.ErrUnauthorized, "Invalid webhook token"))
+		return
+	}
+	body, err := readWebhookBody(w, r)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+	if r.Header.Get(gitLabEventHeader) != "Merge Request Hook" {
+		ignoreEvent(w)
+		return
+	}
+
+	var event gitLabMergeRequestEvent
+	if err := json.Unmarshal(body, &event); err != nil || event.ObjectKind != "merge_request" ||
+		event.ObjectAttributes.IID == 0 || event.Project.ID == 0 {
+		handleServiceError(w, r, domain.NewBusinessError(domain.ErrInvalidRequest, "Invalid merge_request payload"))
+		return
+	}
+
+	attrs := event.ObjectAttributes
+	draft := attrs.Draft || attrs.WorkInProgress
+	change := service.PullRequestChange{
+		Provider:      domain.ProviderGitLab,
+		PullRequestID: codehost.PullRequestRef{Provider: domain.ProviderGitLab, RepoID: event.Project.ID, Number: attrs.IID}.String(),
+		Title:         attrs.Title,
+		AuthorLogin:   event.User.Username,
+	}
+	for _, label := range event.Labels {
+		change.Labels = append(change.Labels, label.Title)
+	}
+	switch {
+	case attrs.Action == "open" && !draft:
+		change.Action = service.ChangeOpened
+	case attrs.Action == "update" && event.Changes.Draft != nil && event.Changes.Draft.Previous && !event.Changes.Draft.Current:
+		change.Action = service.ChangeOpened
+	case attrs.Action == "merge":
+		change.Action = service.ChangeMerged
+	case attrs.Action == "close":
+		change.Action = service.ChangeClosed
+	case attrs.Action == "reopen":
+		change.Action = service.ChangeReopened
+	default:
+		ignoreEvent(w)
+		return
+	}
+	if change.Action == service.ChangeOpened && event.User.ID != attrs.AuthorID {
+		slog.InfoContext(r.Context(), "merge request opened by someone other than its author ignored",
+			"pr_id", change.PullRequestID, "user", event.User.Username)
+		ignoreEvent(w)
+		return
+	}
+	h.applyChange(w, r, change)
+}
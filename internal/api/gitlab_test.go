@@ -0,0 +1,120 @@
+package api
+
+import (
+	"Backend/internal/domain"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fixtureMRID is the id of the merge request in testdata/gitlab.
+const fixtureMRID = "gitlab-2081-17"
+
+// deliverGitLab posts testdata/gitlab/<fixture> as GitLab does, with token.
+func deliverGitLab(t *testing.T, router http.Handler, event, fixture, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", "gitlab", fixture))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	req := httptest.NewRequest("POST", "/integrations/gitlab/webhook", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gitlab-Event", event)
+	req.Header.Set("X-Gitlab-Event-UUID", "3f4e1c2a-9b8d-4e7f-a6c5-1d2e3f4a5b6c")
+	if token != "" {
+		req.Header.Set("X-Gitlab-Token", token)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGitLabWebhook_DrivesMergeRequestLifecycle(t *testing.T) {
+	router, repo := newIntegrationFixture(t)
+
+	steps := []struct {
+		fixture string
+		// status is empty for events that are ignored.
+		status domain.PullRequestStatus
+	}{
+		{"merge_request_open_draft.json", ""},
+		{"merge_request_update_ready.json", domain.StatusOpen},
+		{"merge_request_update_push.json", ""},
+		// GitLab retries deliveries; the second one changes nothing.
+		{"merge_request_update_ready.json", domain.StatusOpen},
+		{"merge_request_close.json", domain.StatusClosed},
+		{"merge_request_reopen.json", domain.StatusOpen},
+		{"merge_request_merge.json", domain.StatusMerged},
+	}
+	for _, step := range steps {
+		resp := decodeIntegrationResponse(t, deliverGitLab(t, router, "Merge Request Hook", step.fixture, testGitLabToken))
+		if step.status == "" {
+			if resp.Result != "ignored" || resp.PullRequest != nil {
+				t.Fatalf("%s: expected the event to be ignored, got %+v", step.fixture, resp)
+			}
+			continue
+		}
+		if resp.Result != "applied" || resp.PullRequest == nil || resp.PullRequest.Status != step.status {
+			t.Fatalf("%s: expected %s to be applied, got %+v", step.fixture, step.status, resp)
+		}
+	}
+
+	pr, err := repo.GetPullRequestByID(context.Background(), fixtureMRID)
+	if err != nil {
+		t.Fatalf("Failed to get PR: %v", err)
+	}
+	if pr.AuthorID != "u1" || pr.PullRequestName != "Cache team lookups" || len(pr.AssignedReviewers) != 2 {
+		t.Errorf("Expected u1's PR with two reviewers, got %+v", pr)
+	}
+}
+
+func TestGitLabWebhook_OpenedReady(t *testing.T) {
+	router, _ := newIntegrationFixture(t)
+
+	resp := decodeIntegrationResponse(t, deliverGitLab(t, router, "Merge Request Hook", "merge_request_open.json", testGitLabToken))
+	if resp.PullRequest == nil || resp.PullRequest.PullRequestID != fixtureMRID || resp.PullRequest.AuthorID != "u1" {
+		t.Fatalf("Expected %s by u1 to be opened, got %+v", fixtureMRID, resp)
+	}
+	if labels := resp.PullRequest.Labels; len(labels) != 1 || labels[0] != "backend" {
+		t.Errorf("Expected the merge request's labels, got %v", labels)
+	}
+}
+
+func TestGitLabWebhook_IgnoresUntrackedMergeRequests(t *testing.T) {
+	router, repo := newIntegrationFixture(t)
+
+	for _, tc := range []struct{ event, fixture string }{
+		{"Push Hook", "merge_request_open.json"},
+		{"Merge Request Hook", "merge_request_open_draft.json"},
+		// The merge request was a draft until it was closed.
+		{"Merge Request Hook", "merge_request_close.json"},
+		{"Merge Request Hook", "merge_request_reopen.json"},
+		{"Merge Request Hook", "merge_request_merge.json"},
+		// Someone other than the author marked it as ready.
+		{"Merge Request Hook", "merge_request_update_ready_by_maintainer.json"},
+	} {
+		resp := decodeIntegrationResponse(t, deliverGitLab(t, router, tc.event, tc.fixture, testGitLabToken))
+		if resp.Result != "ignored" {
+			t.Errorf("%s %s: expected the event to be ignored, got %+v", tc.event, tc.fixture, resp)
+		}
+	}
+	if _, err := repo.GetPullRequestByID(context.Background(), fixtureMRID); err == nil {
+		t.Error("Expected ignored events to leave PRs alone")
+	}
+}
+
+func TestGitLabWebhook_RejectsInvalidToken(t *testing.T) {
+	router, _ := newIntegrationFixture(t)
+
+	for _, token := range []string{"", "not-the-configured-token", testGitHubSecret} {
+		rec := deliverGitLab(t, router, "Merge Request Hook", "merge_request_open.json", token)
+		if rec.Code != http.StatusUnauthorized || errorCodeOf(t, rec) != domain.ErrUnauthorized {
+			t.Errorf("Expected 401 UNAUTHORIZED for token %q, got %d", token, rec.Code)
+		}
+	}
+}
@@ -2,10 +2,18 @@ package api
 
 import (
 	"Backend/internal/domain"
+	"Backend/internal/logging"
 	"Backend/internal/service"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 func sendJSONResponse(w http.ResponseWriter, status int, data interface{}) {
@@ -14,28 +22,170 @@ func sendJSONResponse(w http.ResponseWriter, status int, data interface{}) {
 	json.NewEncoder(w).Encode(data)
 }
 
-func handleServiceError(w http.ResponseWriter, err error) {
-	if bErr, ok := err.(*domain.BusinessError); ok {
+// writeError sends the JSON error envelope. It is the only way errors reach clients.
+func writeError(w http.ResponseWriter, r *http.Request, status int, bErr *domain.BusinessError) {
+	recordErrorCode(r, bErr.Code)
+	sendJSONResponse(w, status, domain.ErrorResponse{Error: domain.ErrorBody{
+		Code:      bErr.Code,
+		Message:   bErr.Message,
+		Details:   bErr.Details,
+		RequestID: logging.RequestID(r.Context()),
+	}})
+}
+
+// handleServiceError maps business errors to their status codes. Any other
+// error is logged and reported as INTERNAL without its text, which may come
+// from the database.
+func handleServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	var bErr *domain.BusinessError
+	if errors.As(err, &bErr) {
 		var status int
 		switch bErr.Code {
 		case domain.ErrNotFound:
 			status = http.StatusNotFound // 404
-		case domain.ErrPRExists, domain.ErrPRMerged, domain.ErrNotAssigned, domain.ErrNoCandidate, domain.ErrTeamExists:
+		case domain.ErrPRExists, domain.ErrPRMerged, domain.ErrPRClosed, domain.ErrNotAssigned, domain.ErrNoCandidate, domain.ErrTeamExists,
+			domain.ErrIdempotencyInProgress, domain.ErrTokenExists, domain.ErrWebhookExists:
 			status = http.StatusConflict // 409
+		case domain.ErrVersionConflict:
+			status = http.StatusPreconditionFailed // 412
+		case domain.ErrIdempotencyMismatch:
+			status = http.StatusUnprocessableEntity // 422
+		case domain.ErrUnauthorized:
+			status = http.StatusUnauthorized // 401
+		case domain.ErrForbidden:
+			status = http.StatusForbidden // 403
+		case domain.ErrRateLimited:
+			status = http.StatusTooManyRequests // 429
+		case domain.ErrTimeout:
+			status = http.StatusServiceUnavailable // 503
+		case domain.ErrInternal:
+			status = http.StatusInternalServerError // 500
 		default:
 			status = http.StatusBadRequest // 400
 		}
+		writeError(w, r, status, bErr)
+		return
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		slog.WarnContext(r.Context(), "request timed out", "error", err)
+		writeError(w, r, http.StatusServiceUnavailable, domain.NewBusinessError(domain.ErrTimeout, "Request timed out"))
+		return
+	}
+	slog.ErrorContext(r.Context(), "request failed", "error", err)
+	writeError(w, r, http.StatusInternalServerError, domain.NewBusinessError(domain.ErrInternal, "Internal server error"))
+}
+
+// decodeJSONBody decodes the request body into v and validates it. Malformed
+// JSON is reported as INVALID_REQUEST; unknown fields, wrongly typed values and
+// failed validation rules as VALIDATION_FAILED with field details.
+func decodeJSONBody(r *http.Request, v any) error {
+	var raw json.RawMessage
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&raw); err != nil || dec.More() {
+		return domain.NewBusinessError(domain.ErrInvalidRequest, "Invalid request body")
+	}
+	if field := unknownField(raw, reflect.TypeOf(v), ""); field != "" {
+		return domain.NewValidationError(domain.FieldError{Field: field, Message: "is not allowed"})
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return domain.NewValidationError(domain.FieldError{Field: typeErr.Field, Message: "must be a " + typeErr.Type.String()})
+		}
+		return domain.NewBusinessError(domain.ErrInvalidRequest, "Invalid request body")
+	}
+	return validate(v)
+}
 
-		resp := domain.ErrorResponse{
-			Error: struct {
-				Code    domain.ErrorCode `json:"code"`
-				Message string           `json:"message"`
-			}{Code: bErr.Code, Message: bErr.Message},
+// unknownField returns the path of the first object key in raw that has no
+// matching field in t, or "" if there is none. Keys match JSON names the way
+// encoding/json does, case-insensitively.
+func unknownField(raw json.RawMessage, t reflect.Type, prefix string) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		var obj map[string]json.RawMessage
+		if json.Unmarshal(raw, &obj) != nil {
+			return ""
+		}
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			sf, ok := fieldByJSONKey(t, key)
+			if !ok {
+				return prefix + key
+			}
+			if field := unknownField(obj[key], sf.Type, prefix+jsonName(sf)+"."); field != "" {
+				return field
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		var items []json.RawMessage
+		if json.Unmarshal(raw, &items) != nil {
+			return ""
+		}
+		name := strings.TrimSuffix(prefix, ".")
+		for i, item := range items {
+			if field := unknownField(item, t.Elem(), fmt.Sprintf("%s[%d].", name, i)); field != "" {
+				return field
+			}
+		}
+	}
+	return ""
+}
+
+func fieldByJSONKey(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() || sf.Tag.Get("json") == "-" {
+			continue
+		}
+		if strings.EqualFold(jsonName(sf), key) {
+			return sf, true
 		}
-		sendJSONResponse(w, status, resp)
-		return
 	}
-	http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	return reflect.StructField{}, false
+}
+
+// requireIDParam returns the query parameter name, which must be a valid identifier.
+func requireIDParam(r *http.Request, name string) (string, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return "", domain.NewValidationError(domain.FieldError{Field: name, Message: "is required"})
+	}
+	if msg := checkID(value); msg != "" {
+		return "", domain.NewValidationError(domain.FieldError{Field: name, Message: msg})
+	}
+	return value, nil
+}
+
+func setETag(w http.ResponseWriter, pr domain.PullRequest) {
+	w.Header().Set("ETag", strconv.Quote(strconv.Itoa(pr.Version)))
+}
+
+// parseIfMatch returns the PR version required by the If-Match header.
+// A missing header or "*" means any version is acceptable.
+func parseIfMatch(r *http.Request) (int, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return domain.AnyVersion, nil
+	}
+
+	mismatch := domain.NewBusinessError(domain.ErrVersionConflict, fmt.Sprintf("If-Match %s does not match current version", header))
+	tag, err := strconv.Unquote(header)
+	if err != nil {
+		return 0, mismatch
+	}
+	version, err := strconv.Atoi(tag)
+	if err != nil || version <= 0 {
+		return 0, mismatch
+	}
+	return version, nil
 }
 
 type PRHandler struct{ prService service.PRService }
@@ -44,51 +194,98 @@ func NewPRHandler(prService service.PRService) *PRHandler { return &PRHandler{pr
 
 func (h *PRHandler) CreatePR(w http.ResponseWriter, r *http.Request) {
 	var reqBody PullRequestCreateRequestDTO
-	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := decodeJSONBody(r, &reqBody); err != nil {
+		handleServiceError(w, r, err)
 		return
 	}
 
-	pr, err := h.prService.CreateAndAssignReviewers(context.Background(), reqBody.PullRequestID, reqBody.PullRequestName, reqBody.AuthorID)
+	pr, err := h.prService.CreateAndAssignReviewers(r.Context(), reqBody.PullRequestID, reqBody.PullRequestName, reqBody.AuthorID, reqBody.ChangedPaths, reqBody.Labels, "")
 	if err != nil {
-		handleServiceError(w, err)
+		handleServiceError(w, r, err)
 		return
 	}
 
+	setETag(w, pr)
 	sendJSONResponse(w, http.StatusCreated, pr)
 }
 
+func (h *PRHandler) GetPR(w http.ResponseWriter, r *http.Request) {
+	prID, err := requireIDParam(r, "pull_request_id")
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	pr, err := h.prService.GetPullRequest(r.Context(), prID)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	setETag(w, pr)
+	sendJSONResponse(w, http.StatusOK, pr)
+}
+
 func (h *PRHandler) MergePR(w http.ResponseWriter, r *http.Request) {
-	var reqBody struct {
-		PullRequestID string `json:"pull_request_id"`
+	var reqBody PullRequestMergeRequestDTO
+	if err := decodeJSONBody(r, &reqBody); err != nil {
+		handleServiceError(w, r, err)
+		return
 	}
-	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+
+	expectedVersion, err := parseIfMatch(r)
+	if err != nil {
+		handleServiceError(w, r, err)
 		return
 	}
 
-	pr, err := h.prService.MergePullRequest(context.Background(), reqBody.PullRequestID)
+	if !privileged(r) {
+		current, err := h.prService.GetPullRequest(r.Context(), reqBody.PullRequestID)
+		if err != nil {
+			handleServiceError(w, r, err)
+			return
+		}
+		if !callerIs(r, current.AuthorID) {
+			handleServiceError(w, r, domain.NewBusinessError(domain.ErrForbidden, "Members may only merge their own pull requests"))
+			return
+		}
+	}
+
+	pr, err := h.prService.MergePullRequest(r.Context(), reqBody.PullRequestID, expectedVersion)
 	if err != nil {
-		handleServiceError(w, err)
+		handleServiceError(w, r, err)
 		return
 	}
 
+	setETag(w, pr)
 	sendJSONResponse(w, http.StatusOK, pr)
 }
 
 func (h *PRHandler) ReassignReviewer(w http.ResponseWriter, r *http.Request) {
 	var reqBody PullRequestReassignRequestDTO
-	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := decodeJSONBody(r, &reqBody); err != nil {
+		handleServiceError(w, r, err)
 		return
 	}
 
-	pr, newReviewerID, err := h.prService.ReassignReviewer(context.Background(), reqBody.PullRequestID, reqBody.OldUserID)
+	expectedVersion, err := parseIfMatch(r)
 	if err != nil {
-		handleServiceError(w, err)
+		handleServiceError(w, r, err)
 		return
 	}
 
+	if !privileged(r) && !callerIs(r, reqBody.OldUserID) {
+		handleServiceError(w, r, domain.NewBusinessError(domain.ErrForbidden, "Reviewers may only reassign themselves"))
+		return
+	}
+
+	pr, newReviewerID, err := h.prService.ReassignReviewer(r.Context(), reqBody.PullRequestID, reqBody.OldUserID, expectedVersion)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	setETag(w, pr)
 	sendJSONResponse(w, http.StatusOK, map[string]interface{}{
 		"pr":          pr,
 		"replaced_by": newReviewerID,
@@ -103,8 +300,8 @@ func NewTeamHandler(teamService service.TeamService) *TeamHandler {
 
 func (h *TeamHandler) CreateTeam(w http.ResponseWriter, r *http.Request) {
 	var reqBody TeamRequestDTO
-	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := decodeJSONBody(r, &reqBody); err != nil {
+		handleServiceError(w, r, err)
 		return
 	}
 
@@ -123,9 +320,9 @@ func (h *TeamHandler) CreateTeam(w http.ResponseWriter, r *http.Request) {
 		Members:  domainUsers,
 	}
 
-	team, err := h.teamService.CreateOrUpdateTeam(context.Background(), domainTeam)
+	team, err := h.teamService.CreateOrUpdateTeam(r.Context(), domainTeam)
 	if err != nil {
-		handleServiceError(w, err)
+		handleServiceError(w, r, err)
 		return
 	}
 
@@ -153,14 +350,14 @@ func NewUserHandler(userService service.UserService) *UserHandler {
 
 func (h *UserHandler) SetUserIsActive(w http.ResponseWriter, r *http.Request) {
 	var reqBody UserIsActiveRequestDTO
-	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := decodeJSONBody(r, &reqBody); err != nil {
+		handleServiceError(w, r, err)
 		return
 	}
 
-	user, err := h.userService.SetUserIsActive(context.Background(), reqBody.UserID, reqBody.IsActive)
+	user, err := h.userService.SetUserIsActive(r.Context(), reqBody.UserID, *reqBody.IsActive)
 	if err != nil {
-		handleServiceError(w, err)
+		handleServiceError(w, r, err)
 		return
 	}
 
@@ -168,15 +365,15 @@ func (h *UserHandler) SetUserIsActive(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *UserHandler) GetReviewPRs(w http.ResponseWriter, r *http.Request) {
-	userID := r.URL.Query().Get("user_id")
-	if userID == "" {
-		http.Error(w, "Missing user_id query parameter", http.StatusBadRequest)
+	userID, err := requireIDParam(r, "user_id")
+	if err != nil {
+		handleServiceError(w, r, err)
 		return
 	}
 
-	prs, err := h.userService.GetReviewPRsByUserID(context.Background(), userID)
+	prs, err := h.userService.GetReviewPRsByUserID(r.Context(), userID)
 	if err != nil {
-		handleServiceError(w, err)
+		handleServiceError(w, r, err)
 		return
 	}
 
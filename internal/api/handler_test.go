@@ -0,0 +1,102 @@
+package api
+
+import (
+	"Backend/internal/domain"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type stubPRService struct {
+	err error
+}
+
+func (s stubPRService) CreateAndAssignReviewers(ctx context.Context, prID, prName, authorID string, changedPaths, labels []string, codeHostRef string) (domain.PullRequest, error) {
+	return domain.PullRequest{}, s.err
+}
+
+func (s stubPRService) GetPullRequest(ctx context.Context, prID string) (domain.PullRequest, error) {
+	return domain.PullRequest{}, s.err
+}
+
+func (s stubPRService) MergePullRequest(ctx context.Context, prID string, expectedVersion int) (domain.PullRequest, error) {
+	return domain.PullRequest{}, s.err
+}
+
+func (s stubPRService) ReassignReviewer(ctx context.Context, prID, oldUserID string, expectedVersion int) (domain.PullRequest, string, error) {
+	return domain.PullRequest{}, "", s.err
+}
+
+func (s stubPRService) ClosePullRequest(ctx context.Context, prID string, expectedVersion int) (domain.PullRequest, error) {
+	return domain.PullRequest{}, s.err
+}
+
+func (s stubPRService) ReopenPullRequest(ctx context.Context, prID string, expectedVersion int) (domain.PullRequest, error) {
+	return domain.PullRequest{}, s.err
+}
+
+func serveError(t *testing.T, prService stubPRService, req *http.Request) (int, domain.ErrorBody) {
+	t.Helper()
+	router := NewRouter(NewPRHandler(prService), NewTeamHandler(nil), NewUserHandler(nil), RouterOptions{})
+	req.Header.Set(requestIDHeader, "req-1")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Expected JSON error, got Content-Type %q and body %q", ct, rec.Body.String())
+	}
+	var resp domain.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode error envelope: %v", err)
+	}
+	if resp.Error.RequestID != "req-1" {
+		t.Errorf("Expected request_id in envelope, got %q", resp.Error.RequestID)
+	}
+	return rec.Code, resp.Error
+}
+
+func TestErrorEnvelope_MalformedBody(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/pullRequest/create", strings.NewReader("{not json"))
+	code, body := serveError(t, stubPRService{}, req)
+
+	if code != http.StatusBadRequest || body.Code != domain.ErrInvalidRequest {
+		t.Errorf("Expected 400 INVALID_REQUEST, got %d %+v", code, body)
+	}
+}
+
+func TestErrorEnvelope_MissingQueryParam(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/pullRequest/get?pull_request_id=", nil)
+	code, body := serveError(t, stubPRService{}, req)
+
+	if code != http.StatusBadRequest || body.Code != domain.ErrValidationFailed {
+		t.Fatalf("Expected 400 VALIDATION_FAILED, got %d %+v", code, body)
+	}
+	if len(body.Details) != 1 || body.Details[0].Field != "pull_request_id" {
+		t.Errorf("Expected field detail for pull_request_id, got %+v", body.Details)
+	}
+}
+
+func TestErrorEnvelope_InternalErrorIsNotLeaked(t *testing.T) {
+	dbErr := errors.New(`pq: relation "pull_requests" does not exist`)
+	req := httptest.NewRequest(http.MethodGet, "/pullRequest/get?pull_request_id=pr-1", nil)
+	code, body := serveError(t, stubPRService{err: dbErr}, req)
+
+	if code != http.StatusInternalServerError || body.Code != domain.ErrInternal {
+		t.Fatalf("Expected 500 INTERNAL, got %d %+v", code, body)
+	}
+	if strings.Contains(body.Message, "pull_requests") {
+		t.Errorf("Expected database error to stay out of the response, got %q", body.Message)
+	}
+}
+
+func TestErrorEnvelope_UnknownRoute(t *testing.T) {
+	code, body := serveError(t, stubPRService{}, httptest.NewRequest(http.MethodGet, "/nope", nil))
+
+	if code != http.StatusNotFound || body.Code != domain.ErrNotFound {
+		t.Errorf("Expected 404 NOT_FOUND, got %d %+v", code, body)
+	}
+}
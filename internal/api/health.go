@@ -0,0 +1,87 @@
+package api
+
+import (
+	"Backend/internal/repository"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	checkOK   = "ok"
+	checkFail = "fail"
+)
+
+var errShuttingDown = errors.New("shutdown in progress")
+
+type HealthHandler struct {
+	repo         repository.HealthRepository
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+func NewHealthHandler(repo repository.HealthRepository, timeout time.Duration) *HealthHandler {
+	return &HealthHandler{repo: repo, timeout: timeout}
+}
+
+// SetShuttingDown makes readiness fail so that load balancers stop sending
+// new traffic while in-flight requests drain.
+func (h *HealthHandler) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+type checkResult struct {
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+type healthResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks,omitempty"`
+}
+
+// Livez reports that the process is up and serving HTTP; it has no dependencies.
+func (h *HealthHandler) Livez(w http.ResponseWriter, r *http.Request) {
+	sendJSONResponse(w, http.StatusOK, healthResponse{Status: checkOK})
+}
+
+// Readyz reports whether the instance can serve traffic. Check errors are
+// logged; the response only names the failed check.
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+
+	resp := healthResponse{Status: checkOK, Checks: make(map[string]checkResult)}
+	run := func(name, failure string, check func(ctx context.Context) error) {
+		start := time.Now()
+		err := check(ctx)
+		result := checkResult{Status: checkOK, DurationMs: float64(time.Since(start).Microseconds()) / 1000}
+		if err != nil {
+			slog.WarnContext(ctx, "readiness check failed", "check", name, "error", err)
+			result.Status = checkFail
+			result.Error = failure
+			resp.Status = checkFail
+		}
+		resp.Checks[name] = result
+	}
+
+	run("shutdown", "server is shutting down", func(context.Context) error {
+		if h.shuttingDown.Load() {
+			return errShuttingDown
+		}
+		return nil
+	})
+	run("database", "database is unreachable", h.repo.Ping)
+	run("migrations", "database schema is not up to date", h.repo.CheckMigrations)
+
+	status := http.StatusOK
+	if resp.Status != checkOK {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	sendJSONResponse(w, status, resp)
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type stubHealthRepo struct {
+	pingErr      error
+	migrationErr error
+}
+
+func (s stubHealthRepo) Ping(ctx context.Context) error            { return s.pingErr }
+func (s stubHealthRepo) CheckMigrations(ctx context.Context) error { return s.migrationErr }
+
+func readyz(t *testing.T, h *HealthHandler) (int, healthResponse) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.Readyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var resp healthResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode readiness response: %v", err)
+	}
+	return rec.Code, resp
+}
+
+func TestReadyz_AllChecksPass(t *testing.T) {
+	code, resp := readyz(t, NewHealthHandler(stubHealthRepo{}, time.Second))
+
+	if code != http.StatusOK || resp.Status != checkOK {
+		t.Fatalf("Expected ready, got %d %+v", code, resp)
+	}
+	for _, name := range []string{"shutdown", "database", "migrations"} {
+		if resp.Checks[name].Status != checkOK {
+			t.Errorf("Expected check %s to pass, got %+v", name, resp.Checks[name])
+		}
+	}
+}
+
+func TestReadyz_DatabaseDownDoesNotLeakError(t *testing.T) {
+	repo := stubHealthRepo{pingErr: errors.New("dial tcp 10.0.0.5:5432: connection refused")}
+	code, resp := readyz(t, NewHealthHandler(repo, time.Second))
+
+	if code != http.StatusServiceUnavailable || resp.Status != checkFail {
+		t.Fatalf("Expected not ready, got %d %+v", code, resp)
+	}
+	if got := resp.Checks["database"]; got.Status != checkFail || got.Error != "database is unreachable" {
+		t.Errorf("Unexpected database check %+v", got)
+	}
+	if resp.Checks["migrations"].Status != checkOK {
+		t.Errorf("Expected migrations check to be reported separately, got %+v", resp.Checks["migrations"])
+	}
+}
+
+func TestReadyz_FailsDuringShutdown(t *testing.T) {
+	h := NewHealthHandler(stubHealthRepo{}, time.Second)
+	h.SetShuttingDown()
+
+	code, resp := readyz(t, h)
+	if code != http.StatusServiceUnavailable || resp.Checks["shutdown"].Status != checkFail {
+		t.Fatalf("Expected readiness to fail during shutdown, got %d %+v", code, resp)
+	}
+
+	rec := httptest.NewRecorder()
+	h.Livez(rec, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected liveness to keep passing during shutdown, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"Backend/internal/auth"
+	"Backend/internal/domain"
+	"Backend/internal/repository"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	maxIdempotencyKeyLen = 255
+	maxIdempotentBody    = 1 << 20
+)
+
+// perRequestHeaders describe the request that produced a response rather than
+// the response itself, so they are neither stored nor replayed.
+var perRequestHeaders = []string{requestIDHeader, "Retry-After"}
+
+type IdempotencyMiddleware struct {
+	repo repository.IdempotencyRepository
+	ttl  time.Duration
+}
+
+func NewIdempotencyMiddleware(repo repository.IdempotencyRepository, ttl time.Duration) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{repo: repo, ttl: ttl}
+}
+
+// Middleware replays the stored response for a POST request whose Idempotency-Key
+// was already used with the same body, and rejects reuse with a different body.
+func (m *IdempotencyMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if r.Method != http.MethodPost || key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			handleServiceError(w, r, domain.NewBusinessError(domain.ErrInvalidRequest, "Idempotency-Key is too long"))
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentBody+1))
+		if err != nil || len(body) > maxIdempotentBody {
+			handleServiceError(w, r, domain.NewBusinessError(domain.ErrInvalidRequest, "Invalid request body"))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		now := time.Now().UTC()
+		rec := domain.IdempotencyRecord{
+			Key:         key,
+			RequestHash: requestHash(r, body),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(m.ttl),
+		}
+
+		stored, reserved, err := m.repo.ReserveIdempotencyKey(r.Context(), rec)
+		if err != nil {
+			handleServiceError(w, r, err)
+			return
+		}
+		if !reserved {
+			m.replay(w, r, stored, rec.RequestHash)
+			return
+		}
+
+		// The outcome must be recorded even if the client has already gone away,
+		// otherwise the key would stay in flight until it expires.
+		ctx := context.WithoutCancel(r.Context())
+
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			// The handler panicked; free the key so the retry is not rejected as
+			// in progress, and let recoverPanics answer the request.
+			if err := m.repo.ReleaseIdempotencyKey(ctx, key); err != nil {
+				slog.ErrorContext(ctx, "failed to release idempotency key", "key", key, "error", err)
+			}
+		}()
+
+		rw := &capturingResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rw, r)
+		completed = true
+
+		if rw.status >= http.StatusInternalServerError || strings.Contains(w.Header().Get("Cache-Control"), "no-store") {
+			// Server failures are not cached so the client can retry them, and
+			// no-store responses carry secrets that must not be persisted.
+			if err := m.repo.ReleaseIdempotencyKey(ctx, key); err != nil {
+				slog.ErrorContext(ctx, "failed to release idempotency key", "key", key, "error", err)
+			}
+			return
+		}
+
+		rec.StatusCode = rw.status
+		headers := w.Header().Clone()
+		for _, name := range perRequestHeaders {
+			headers.Del(name)
+		}
+		rec.Headers = map[string][]string(headers)
+		rec.Body = rw.body.Bytes()
+		if err := m.repo.CompleteIdempotencyKey(ctx, rec); err != nil {
+			slog.ErrorContext(ctx, "failed to store idempotent response", "key", key, "error", err)
+		}
+	})
+}
+
+func (m *IdempotencyMiddleware) replay(w http.ResponseWriter, r *http.Request, stored domain.IdempotencyRecord, hash string) {
+	if stored.RequestHash != hash {
+		handleServiceError(w, r, domain.NewBusinessError(domain.ErrIdempotencyMismatch,
+			"Idempotency-Key was already used with a different request"))
+		return
+	}
+	if stored.StatusCode == 0 {
+		handleServiceError(w, r, domain.NewBusinessError(domain.ErrIdempotencyInProgress,
+			"A request with this Idempotency-Key is still being processed"))
+		return
+	}
+
+	for name, values := range stored.Headers {
+		if isPerRequestHeader(name) {
+			continue
+		}
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(stored.StatusCode)
+	w.Write(stored.Body)
+}
+
+func isPerRequestHeader(name string) bool {
+	for _, h := range perRequestHeaders {
+		if http.CanonicalHeaderKey(h) == http.CanonicalHeaderKey(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// RunCleanup periodically deletes expired idempotency records until ctx is done.
+func (m *IdempotencyMiddleware) RunCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := m.repo.DeleteExpiredIdempotencyKeys(ctx, time.Now().UTC())
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to delete expired idempotency keys", "error", err)
+				continue
+			}
+			slog.DebugContext(ctx, "expired idempotency keys deleted", "count", deleted)
+		}
+	}
+}
+
+// requestHash binds a key to the request and its caller, so a key reused by
+// another caller is rejected instead of replaying someone else's response.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	if identity, ok := auth.IdentityFromContext(r.Context()); ok {
+		io.WriteString(h, identity.Subject+"\n")
+	}
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type capturingResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (w *capturingResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *capturingResponseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"Backend/internal/domain"
+)
+
+type stubIdempotencyRepo struct {
+	mu      sync.Mutex
+	records map[string]domain.IdempotencyRecord
+}
+
+func (s *stubIdempotencyRepo) ReserveIdempotencyKey(ctx context.Context, rec domain.IdempotencyRecord) (domain.IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.records[rec.Key]; ok && existing.ExpiresAt.After(rec.CreatedAt) {
+		return existing, false, nil
+	}
+	s.records[rec.Key] = rec
+	return rec, true, nil
+}
+
+func (s *stubIdempotencyRepo) CompleteIdempotencyKey(ctx context.Context, rec domain.IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[rec.Key] = rec
+	return nil
+}
+
+func (s *stubIdempotencyRepo) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+func (s *stubIdempotencyRepo) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestIdempotencyMiddleware_ReplaysAndRejectsMismatch(t *testing.T) {
+	calls := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		sendJSONResponse(w, http.StatusCreated, map[string]int{"calls": calls})
+	})
+
+	m := NewIdempotencyMiddleware(&stubIdempotencyRepo{records: map[string]domain.IdempotencyRecord{}}, time.Hour)
+	h := m.Middleware(handler)
+
+	do := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/pullRequest/create", strings.NewReader(body))
+		req.Header.Set(idempotencyKeyHeader, "key-1")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := do(`{"pull_request_id":"pr-1"}`)
+	second := do(`{"pull_request_id":"pr-1"}`)
+
+	if calls != 1 {
+		t.Fatalf("Expected handler to run once, ran %d times", calls)
+	}
+	if second.Code != first.Code || second.Body.String() != first.Body.String() {
+		t.Errorf("Expected replayed response %d %q, got %d %q", first.Code, first.Body, second.Code, second.Body)
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Expected replayed response to be marked")
+	}
+
+	mismatch := do(`{"pull_request_id":"pr-2"}`)
+	if mismatch.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for reused key with different body, got %d", mismatch.Code)
+	}
+}
+
+func TestIdempotencyMiddleware_ReleasesKeyOnPanicAndDropsPerRequestHeaders(t *testing.T) {
+	calls := 0
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			panic("boom")
+		}
+		w.Header().Set(requestIDHeader, "req-"+strings.Repeat("x", calls))
+		sendJSONResponse(w, http.StatusCreated, map[string]int{"calls": calls})
+	})
+
+	repo := &stubIdempotencyRepo{records: map[string]domain.IdempotencyRecord{}}
+	h := NewIdempotencyMiddleware(repo, time.Hour).Middleware(handler)
+
+	do := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/pullRequest/create", strings.NewReader(`{}`))
+		req.Header.Set(idempotencyKeyHeader, "key-1")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("Expected the handler panic to propagate")
+			}
+		}()
+		do()
+	}()
+	if _, ok := repo.records["key-1"]; ok {
+		t.Fatal("Expected the key to be released after a panic")
+	}
+
+	if first := do(); first.Code != http.StatusCreated {
+		t.Fatalf("Expected retry to reach the handler, got %d", first.Code)
+	}
+	if stored := repo.records["key-1"]; http.Header(stored.Headers).Get(requestIDHeader) != "" {
+		t.Errorf("Expected %s not to be stored, got %v", requestIDHeader, stored.Headers)
+	}
+	if replayed := do(); replayed.Header().Get(requestIDHeader) != "" {
+		t.Errorf("Expected %s not to be replayed, got %q", requestIDHeader, replayed.Header().Get(requestIDHeader))
+	}
+}
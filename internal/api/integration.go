@@ -0,0 +1,111 @@
+package api
+
+import (
+	"Backend/internal/auth"
+	"Backend/internal/domain"
+	"Backend/internal/service"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+// maxWebhookBody bounds the payloads accepted from code hosts; GitHub caps
+// its own at 25 MB, but pull request events are far smaller.
+const maxWebhookBody = 5 << 20
+
+type IntegrationOptions struct {
+	// GitHubSecret verifies X-Hub-Signature-256; the GitHub webhook is served
+	// only when it is set.
+	GitHubSecret string
+	// GitLabToken is compared with X-Gitlab-Token; the GitLab webhook is served
+	// only when it is set.
+	GitLabToken string
+}
+
+type IntegrationHandler struct {
+	integrationService service.IntegrationService
+	opts               IntegrationOptions
+}
+
+func NewIntegrationHandler(integrationService service.IntegrationService, opts IntegrationOptions) *IntegrationHandler {
+	return &IntegrationHandler{integrationService: integrationService, opts: opts}
+}
+
+func (h *IntegrationHandler) SetUserMapping(w http.ResponseWriter, r *http.Request) {
+	var reqBody UserMappingRequestDTO
+	if err := decodeJSONBody(r, &reqBody); err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	m, err := h.integrationService.SetUserMapping(r.Context(), reqBody.Provider, reqBody.Login, reqBody.UserID)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, m)
+}
+
+// ListUserMappings serves GET /admin/integrations/mappings?provider=.
+func (h *IntegrationHandler) ListUserMappings(w http.ResponseWriter, r *http.Request) {
+	mappings, err := h.integrationService.ListUserMappings(r.Context(), r.URL.Query().Get("provider"))
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, map[string]interface{}{"mappings": mappings})
+}
+
+func (h *IntegrationHandler) DeleteUserMapping(w http.ResponseWriter, r *http.Request) {
+	var reqBody UserMappingDeleteRequestDTO
+	if err := decodeJSONBody(r, &reqBody); err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	m, err := h.integrationService.DeleteUserMapping(r.Context(), reqBody.Provider, reqBody.Login)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, m)
+}
+
+// readWebhookBody returns the raw body, which signatures are computed over.
+func readWebhookBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	if err != nil {
+		return nil, domain.NewBusinessError(domain.ErrInvalidRequest, "Invalid request body")
+	}
+	return body, nil
+}
+
+// applyChange applies change on behalf of the code host, which the audit log
+// then names as the actor, and reports the outcome. Closing, reopening or
+// merging a pull request that was never opened here is ignored: answering
+// with an error would only make the code host retry and eventually disable
+// the webhook.
+func (h *IntegrationHandler) applyChange(w http.ResponseWriter, r *http.Request, change service.PullRequestChange) {
+	ctx := auth.WithIdentity(r.Context(), domain.Identity{Subject: "integration:" + change.Provider, Roles: []domain.Role{domain.RoleBot}})
+	pr, err := h.integrationService.ApplyPullRequestChange(ctx, change)
+	var bErr *domain.BusinessError
+	if change.Action != service.ChangeOpened && errors.As(err, &bErr) && bErr.Code == domain.ErrNotFound {
+		ignoreEvent(w)
+		return
+	}
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	slog.InfoContext(ctx, "code host event applied", "provider", change.Provider, "action", change.Action, "pr_id", pr.PullRequestID)
+	sendJSONResponse(w, http.StatusOK, IntegrationResponseDTO{Result: "applied", PullRequest: &pr})
+}
+
+func ignoreEvent(w http.ResponseWriter) {
+	sendJSONResponse(w, http.StatusOK, IntegrationResponseDTO{Result: "ignored"})
+}
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"Backend/internal/domain"
+	"Backend/internal/logging"
+	"Backend/internal/metrics"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+var tracer = otel.Tracer("Backend/internal/api")
+
+// requestState collects details about a request that are only known deep in
+// the handler chain but are needed by middleware once the handler returns.
+type requestState struct {
+	errorCode domain.ErrorCode
+	subject   string
+}
+
+type requestStateKey struct{}
+
+func getRequestState(ctx context.Context) *requestState {
+	state, _ := ctx.Value(requestStateKey{}).(*requestState)
+	return state
+}
+
+func recordErrorCode(r *http.Request, code domain.ErrorCode) {
+	if state := getRequestState(r.Context()); state != nil {
+		state.errorCode = code
+	}
+}
+
+// observeRequests assigns a request id (reusing a sane X-Request-ID from the client),
+// starts the server span, writes one structured log line per request and records
+// request metrics when m is set.
+func observeRequests(m *metrics.Metrics) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			requestID := r.Header.Get(requestIDHeader)
+			if !validRequestID(requestID) {
+				requestID = newRequestID()
+			}
+			w.Header().Set(requestIDHeader, requestID)
+
+			route := routeTemplate(r)
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracer.Start(ctx, r.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", r.Method),
+					attribute.String("http.route", route),
+					attribute.String("url.path", r.URL.Path),
+					attribute.String("request.id", requestID),
+				))
+			defer span.End()
+
+			state := &requestState{}
+			ctx = logging.WithRequestID(ctx, requestID)
+			ctx = context.WithValue(ctx, requestStateKey{}, state)
+			r = r.WithContext(ctx)
+
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(sw, r)
+			duration := time.Since(start)
+
+			span.SetAttributes(attribute.Int("http.response.status_code", sw.status))
+			if state.errorCode != "" {
+				span.SetAttributes(attribute.String("error.code", string(state.errorCode)))
+			}
+			if sw.status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(sw.status))
+			}
+
+			level := slog.LevelInfo
+			if sw.status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("route", route),
+				slog.Int("status", sw.status),
+				slog.Float64("duration_ms", float64(duration.Microseconds())/1000),
+				slog.Int64("bytes", sw.bytes),
+				slog.String("remote_addr", r.RemoteAddr),
+			}
+			if state.errorCode != "" {
+				attrs = append(attrs, slog.String("error_code", string(state.errorCode)))
+			}
+			if state.subject != "" {
+				attrs = append(attrs, slog.String("subject", state.subject))
+				span.SetAttributes(attribute.String("enduser.id", state.subject))
+			}
+			slog.LogAttrs(ctx, level, "http request", attrs...)
+
+			if m != nil {
+				m.ObserveHTTPRequest(r.Method, route, sw.status, duration)
+				if state.errorCode != "" {
+					m.IncBusinessError(state.errorCode)
+				}
+			}
+		})
+	}
+}
+
+// recoverPanics turns a handler panic into an INTERNAL error response instead
+// of a dropped connection.
+func recoverPanics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+				slog.ErrorContext(r.Context(), "handler panicked", "panic", rec, "stack", string(debug.Stack()))
+				writeError(w, r, http.StatusInternalServerError, domain.NewBusinessError(domain.ErrInternal, "Internal server error"))
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return ""
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	bytes       int64
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the connection, e.g. to flush
+// event streams.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"Backend/internal/domain"
+	"Backend/internal/logging"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestObserveRequests_LogsRequestIDAndErrorCode(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, "info", "json")
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	prev := slog.Default()
+	slog.SetDefault(logger)
+	defer slog.SetDefault(prev)
+
+	h := observeRequests(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slog.InfoContext(r.Context(), "inside handler")
+		handleServiceError(w, r, domain.NewBusinessError(domain.ErrNotFound, "missing"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/users/getReview", nil)
+	req.Header.Set(requestIDHeader, "req-42")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get(requestIDHeader); got != "req-42" {
+		t.Errorf("Expected X-Request-ID to be echoed, got %q", got)
+	}
+
+	var lines []map[string]any
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var line map[string]any
+		if err := dec.Decode(&line); err != nil {
+			t.Fatalf("Failed to decode log line: %v", err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 log lines, got %d", len(lines))
+	}
+	for _, line := range lines {
+		if line["request_id"] != "req-42" {
+			t.Errorf("Expected request_id on every line, got %v", line)
+		}
+	}
+	access := lines[1]
+	if access["status"] != float64(http.StatusNotFound) || access["error_code"] != string(domain.ErrNotFound) {
+		t.Errorf("Unexpected access log line %v", access)
+	}
+}
+
+func TestObserveRequests_StartsServerSpan(t *testing.T) {
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+
+	rec := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	r := mux.NewRouter()
+	r.Use(observeRequests(nil))
+	r.HandleFunc("/pullRequest/get", func(w http.ResponseWriter, r *http.Request) {
+		handleServiceError(w, r, domain.NewBusinessError(domain.ErrNotFound, "missing"))
+	}).Methods("GET")
+
+	req := httptest.NewRequest(http.MethodGet, "/pullRequest/get?pull_request_id=pr-1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := rec.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /pullRequest/get" {
+		t.Errorf("Unexpected span name %q", span.Name())
+	}
+	if span.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected incoming traceparent to be continued, got trace %s", span.SpanContext().TraceID())
+	}
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	if attrs["http.response.status_code"].AsInt64() != http.StatusNotFound || attrs["error.code"].AsString() != string(domain.ErrNotFound) {
+		t.Errorf("Unexpected span attributes %v", span.Attributes())
+	}
+}
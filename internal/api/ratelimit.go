@@ -0,0 +1,202 @@
+package api
+
+import (
+	"Backend/internal/auth"
+	"Backend/internal/domain"
+	"Backend/internal/metrics"
+	"context"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/time/rate"
+)
+
+// RateLimit allows RPS requests per second on average with bursts of up to
+// Burst requests; a zero RPS means unlimited.
+type RateLimit struct {
+	RPS   float64
+	Burst int
+}
+
+type RateLimiterOptions struct {
+	// PerClient limits all API requests from one client address together. It
+	// is enforced before authentication, so bad credentials are counted too.
+	PerClient RateLimit
+	Default   RateLimit
+	// Routes overrides Default by route path template.
+	Routes map[string]RateLimit
+	// ClientIPHeader names a header set by a trusted proxy that carries the
+	// client address; the connection address is used when it is empty.
+	ClientIPHeader string
+	// IdleTimeout is how long the bucket of an idle caller is kept.
+	IdleTimeout time.Duration
+	Metrics     *metrics.Metrics
+}
+
+// RateLimiter keeps a token bucket per caller and route. Authenticated callers
+// are keyed by their credential, everyone else by client address. Every client
+// address additionally has one bucket shared by all routes.
+type RateLimiter struct {
+	opts RateLimiterOptions
+
+	mu      sync.Mutex
+	buckets map[bucketKey]*bucket
+}
+
+type bucketKey struct {
+	route  string
+	caller string
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func NewRateLimiter(opts RateLimiterOptions) *RateLimiter {
+	l := &RateLimiter{opts: opts, buckets: make(map[bucketKey]*bucket)}
+	if opts.Metrics != nil {
+		opts.Metrics.RegisterRateLimitBuckets(l.size)
+	}
+	return l
+}
+
+// limitClients throttles the routes listed in policy per client address. It
+// runs before authentication so that requests with a missing or invalid token
+// are limited too instead of each costing a credential lookup.
+func (l *RateLimiter) limitClients(policy routePolicy) func(http.Handler) http.Handler {
+	return l.throttle(policy, func(r *http.Request, route string) (bucketKey, RateLimit) {
+		return bucketKey{caller: l.clientAddr(r)}, l.opts.PerClient
+	})
+}
+
+// limit throttles the routes listed in policy, i.e. the API routes; probes
+// and /metrics are never limited. It runs after authentication so that a
+// token is limited as a whole regardless of the addresses it is used from.
+func (l *RateLimiter) limit(policy routePolicy) func(http.Handler) http.Handler {
+	return l.throttle(policy, func(r *http.Request, route string) (bucketKey, RateLimit) {
+		limit, ok := l.opts.Routes[route]
+		if !ok {
+			limit = l.opts.Default
+		}
+		return bucketKey{route: route, caller: l.caller(r)}, limit
+	})
+}
+
+func (l *RateLimiter) throttle(policy routePolicy, bucketFor func(r *http.Request, route string) (bucketKey, RateLimit)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := routeTemplate(r)
+			if _, ok := policy[mux.CurrentRoute(r)]; !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			key, limit := bucketFor(r, route)
+			if limit.RPS <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			delay := l.reserve(key, limit, time.Now())
+			if l.opts.Metrics != nil {
+				l.opts.Metrics.ObserveRateLimit(route, delay == 0)
+			}
+			if delay > 0 {
+				retryAfter := int(math.Ceil(delay.Seconds()))
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				writeError(w, r, http.StatusTooManyRequests, domain.NewBusinessError(domain.ErrRateLimited,
+					"Rate limit exceeded, retry in "+strconv.Itoa(retryAfter)+" seconds"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// reserve takes a token from the caller's bucket and returns zero, or leaves
+// the bucket untouched and returns how long until a token is available.
+func (l *RateLimiter) reserve(key bucketKey, limit RateLimit, now time.Time) time.Duration {
+	l.mu.Lock()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(rate.Limit(limit.RPS), limit.Burst)}
+		l.buckets[key] = b
+	}
+	b.lastSeen = now
+	l.mu.Unlock()
+
+	res := b.limiter.ReserveN(now, 1)
+	if !res.OK() {
+		return time.Duration(float64(time.Second) / limit.RPS)
+	}
+	delay := res.DelayFrom(now)
+	if delay > 0 {
+		res.CancelAt(now)
+	}
+	return delay
+}
+
+func (l *RateLimiter) caller(r *http.Request) string {
+	if identity, ok := auth.IdentityFromContext(r.Context()); ok {
+		return identity.Subject
+	}
+	return l.clientAddr(r)
+}
+
+func (l *RateLimiter) clientAddr(r *http.Request) string {
+	if l.opts.ClientIPHeader != "" {
+		// Proxies append to the header, so the last address is the one they saw.
+		values := strings.Split(r.Header.Get(l.opts.ClientIPHeader), ",")
+		if ip := net.ParseIP(strings.TrimSpace(values[len(values)-1])); ip != nil {
+			return "ip:" + ip.String()
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+func (l *RateLimiter) size() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+// RunCleanup periodically drops the buckets of idle callers until ctx is done;
+// a caller returning later starts with a full bucket.
+func (l *RateLimiter) RunCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			slog.DebugContext(ctx, "idle rate limit buckets dropped", "count", l.evictIdle(now))
+		}
+	}
+}
+
+func (l *RateLimiter) evictIdle(now time.Time) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	evicted := 0
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) >= l.opts.IdleTimeout {
+			delete(l.buckets, key)
+			evicted++
+		}
+	}
+	return evicted
+}
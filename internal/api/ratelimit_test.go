@@ -0,0 +1,166 @@
+package api
+
+import (
+	"Backend/internal/domain"
+	"Backend/internal/metrics"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_LimitsPerRouteAndCaller(t *testing.T) {
+	m := metrics.New()
+	limiter := NewRateLimiter(RateLimiterOptions{
+		Default:     RateLimit{RPS: 0.001, Burst: 1},
+		Routes:      map[string]RateLimit{"/pullRequest/get": {RPS: 0.5, Burst: 2}},
+		IdleTimeout: time.Minute,
+		Metrics:     m,
+	})
+	router := NewRouter(NewPRHandler(stubPRService{}), NewTeamHandler(nil), NewUserHandler(nil),
+		RouterOptions{Metrics: m, RateLimiter: limiter})
+
+	get := func(path, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec := get("/pullRequest/get?pull_request_id=pr-1", "10.0.0.1:1000"); rec.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected 200 within the burst, got %d", i+1, rec.Code)
+		}
+	}
+	rec := get("/pullRequest/get?pull_request_id=pr-1", "10.0.0.1:2000")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 once the burst is spent, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Expected Retry-After 2, got %q", got)
+	}
+	var resp domain.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp.Error.Code != domain.ErrRateLimited {
+		t.Errorf("Expected RATE_LIMITED envelope, got %+v (%v)", resp, err)
+	}
+
+	if rec := get("/pullRequest/get?pull_request_id=pr-1", "10.0.0.2:1000"); rec.Code != http.StatusOK {
+		t.Errorf("Expected another client to have its own bucket, got %d", rec.Code)
+	}
+	if rec := get("/users/getReview?user_id=u1", "10.0.0.1:1000"); rec.Code == http.StatusTooManyRequests {
+		t.Errorf("Expected another route to have its own bucket")
+	}
+	for i := 0; i < 5; i++ {
+		if rec := get("/health", "10.0.0.1:1000"); rec.Code != http.StatusOK {
+			t.Fatalf("Expected /health not to be limited, got %d", rec.Code)
+		}
+	}
+
+	body, _ := io.ReadAll(get("/metrics", "10.0.0.1:1000").Body)
+	for _, want := range []string{
+		`pr_reviewer_rate_limit_decisions_total{result="allowed",route="/pullRequest/get"} 3`,
+		`pr_reviewer_rate_limit_decisions_total{result="limited",route="/pullRequest/get"} 1`,
+		`pr_reviewer_rate_limit_buckets 3`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("Expected metrics to contain %s", want)
+		}
+	}
+}
+
+func TestRateLimiter_KeysByTokenAndProxyHeader(t *testing.T) {
+	limiter := NewRateLimiter(RateLimiterOptions{
+		Default:        RateLimit{RPS: 0.001, Burst: 1},
+		ClientIPHeader: "X-Forwarded-For",
+		IdleTimeout:    time.Minute,
+	})
+	f := newAuthFixture(t)
+	router := NewRouter(NewPRHandler(stubPRService{}), NewTeamHandler(nil), NewUserHandler(nil),
+		RouterOptions{Auth: f.authenticator, RateLimiter: limiter})
+
+	do := func(token, forwardedFor, remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodGet, "/pullRequest/get?pull_request_id=pr-1", nil)
+		req.RemoteAddr = remoteAddr
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	bot := f.secrets[domain.RoleBot]
+	if code := do(bot, "", "10.0.0.1:1"); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if code := do(bot, "", "10.0.0.2:1"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected the token to be limited from any address, got %d", code)
+	}
+	if code := do(f.secrets[domain.RoleAdmin], "", "10.0.0.1:1"); code != http.StatusOK {
+		t.Errorf("Expected another token to have its own bucket, got %d", code)
+	}
+
+	// Address keying is exercised directly; see TestRateLimiter_LimitsClientsBeforeAuth.
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.9:1"
+	req.Header.Set("X-Forwarded-For", "spoofed, 192.0.2.7")
+	if got := limiter.caller(req); got != "ip:192.0.2.7" {
+		t.Errorf("Expected the address appended by the proxy, got %q", got)
+	}
+	req.Header.Set("X-Forwarded-For", "garbage")
+	if got := limiter.caller(req); got != "ip:10.0.0.9" {
+		t.Errorf("Expected the connection address for an invalid header, got %q", got)
+	}
+}
+
+func TestRateLimiter_LimitsClientsBeforeAuth(t *testing.T) {
+	limiter := NewRateLimiter(RateLimiterOptions{
+		PerClient:   RateLimit{RPS: 0.001, Burst: 2},
+		IdleTimeout: time.Minute,
+	})
+	f := newAuthFixture(t)
+	router := NewRouter(NewPRHandler(stubPRService{}), NewTeamHandler(nil), NewUserHandler(nil),
+		RouterOptions{Auth: f.authenticator, RateLimiter: limiter})
+
+	do := func(token, remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodGet, "/pullRequest/get?pull_request_id=pr-1", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := do("invalid", "10.0.0.1:1"); code != http.StatusUnauthorized {
+			t.Fatalf("Expected 401, got %d", code)
+		}
+	}
+	if code := do("invalid", "10.0.0.1:1"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected invalid tokens to be limited per address, got %d", code)
+	}
+	if code := do(f.secrets[domain.RoleBot], "10.0.0.1:1"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected the address limit to apply to valid tokens too, got %d", code)
+	}
+	if code := do(f.secrets[domain.RoleBot], "10.0.0.2:1"); code != http.StatusOK {
+		t.Errorf("Expected another address to have its own bucket, got %d", code)
+	}
+}
+
+func TestRateLimiter_EvictsIdleBuckets(t *testing.T) {
+	limiter := NewRateLimiter(RateLimiterOptions{IdleTimeout: time.Minute})
+	now := time.Now()
+	limiter.reserve(bucketKey{route: "/a", caller: "ip:1"}, RateLimit{RPS: 1, Burst: 1}, now.Add(-2*time.Minute))
+	limiter.reserve(bucketKey{route: "/a", caller: "ip:2"}, RateLimit{RPS: 1, Burst: 1}, now)
+
+	if evicted := limiter.evictIdle(now); evicted != 1 || limiter.size() != 1 {
+		t.Errorf("Expected one idle bucket to be evicted, got %d evicted and %d left", evicted, limiter.size())
+	}
+}
@@ -1,38 +1,164 @@
 package api
 
 import (
-	"log"
+	"Backend/internal/domain"
+	"Backend/internal/metrics"
+	"context"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 )
 
-func NewRouter(prH *PRHandler, teamH *TeamHandler, userH *UserHandler) http.Handler {
+type RouterOptions struct {
+	// Health serves /livez and /readyz when set.
+	Health *HealthHandler
+	// Idempotency enables Idempotency-Key handling on POST routes when set.
+	Idempotency *IdempotencyMiddleware
+	// RequestTimeout bounds the context passed to services and repositories.
+	RequestTimeout time.Duration
+	// Metrics records request metrics and serves /metrics when set.
+	Metrics *metrics.Metrics
+	// Auth enables bearer authentication and role checks when set; Tokens then
+	// serves the admin token endpoints.
+	Auth   Authenticator
+	Tokens *TokenHandler
+	// Webhooks serves the admin webhook endpoints when set; they are limited to
+	// admins when Auth is set.
+	Webhooks *WebhookHandler
+	// Audit serves GET /audit when set.
+	Audit *AuditHandler
+	// RateLimiter throttles API routes per caller when set.
+	RateLimiter *RateLimiter
+	// Stream serves GET /events/stream when set.
+	Stream *StreamHandler
+	// Integrations serves the code host webhooks whose secrets are configured,
+	// and the admin user mapping endpoints, limited to admins when Auth is set.
+	Integrations *IntegrationHandler
+	// CodeOwners serves the team CODEOWNERS endpoints when set.
+	CodeOwners *CodeOwnersHandler
+	// Expertise serves GET /users/expertise when set.
+	Expertise *ExpertiseHandler
+}
+
+func NewRouter(prH *PRHandler, teamH *TeamHandler, userH *UserHandler, opts RouterOptions) http.Handler {
 	r := mux.NewRouter()
 
-	r.Use(func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			log.Printf("Request: %s %s", r.Method, r.URL.Path)
-			next.ServeHTTP(w, r)
+	observe := observeRequests(opts.Metrics)
+	r.Use(observe, recoverPanics)
+
+	// Unmatched requests bypass router middleware, so they are wrapped explicitly.
+	r.NotFoundHandler = observe(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, r, http.StatusNotFound, domain.NewBusinessError(domain.ErrNotFound, "Route not found"))
+	}))
+	r.MethodNotAllowedHandler = observe(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, r, http.StatusMethodNotAllowed, domain.NewBusinessError(domain.ErrInvalidRequest, "Method not allowed"))
+	}))
+
+	// Event streams stay open until the client leaves, so they have no timeout.
+	streams := map[*mux.Route]bool{}
+
+	if opts.RequestTimeout > 0 {
+		r.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if streams[mux.CurrentRoute(r)] {
+					next.ServeHTTP(w, r)
+					return
+				}
+				ctx, cancel := context.WithTimeout(r.Context(), opts.RequestTimeout)
+				defer cancel()
+				next.ServeHTTP(w, r.WithContext(ctx))
+			})
 		})
-	})
+	}
+
+	policy := routePolicy{}
+	if opts.RateLimiter != nil {
+		r.Use(opts.RateLimiter.limitClients(policy))
+	}
+	if opts.Auth != nil {
+		r.Use(requireAuth(opts.Auth, policy))
+	}
+	if opts.RateLimiter != nil {
+		r.Use(opts.RateLimiter.limit(policy))
+	}
+
+	if opts.Idempotency != nil {
+		r.Use(opts.Idempotency.Middleware)
+	}
 
+	leads := []domain.Role{domain.RoleAdmin, domain.RoleTeamLead}
+
+	if opts.Metrics != nil {
+		r.Handle("/metrics", opts.Metrics.Handler()).Methods("GET")
+	}
+
+	// /health is kept for existing clients; it only reports that the process is alive.
 	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	}).Methods("GET")
+	if opts.Health != nil {
+		r.HandleFunc("/livez", opts.Health.Livez).Methods("GET")
+		r.HandleFunc("/readyz", opts.Health.Readyz).Methods("GET")
+	}
 
 	// Teams
-	r.HandleFunc("/team/add", teamH.CreateTeam).Methods("POST")
+	policy.allow(r.HandleFunc("/team/add", teamH.CreateTeam).Methods("POST"), leads...)
+	if opts.CodeOwners != nil {
+		policy.allow(r.HandleFunc("/team/codeowners", opts.CodeOwners.SetCodeOwners).Methods("POST"), leads...)
+		policy.allow(r.HandleFunc("/team/codeowners", opts.CodeOwners.GetCodeOwners).Methods("GET"))
+	}
 
 	// Users
-	r.HandleFunc("/users/setIsActive", userH.SetUserIsActive).Methods("POST")
-	r.HandleFunc("/users/getReview", userH.GetReviewPRs).Methods("GET").Queries("user_id", "{user_id}")
+	policy.allow(r.HandleFunc("/users/setIsActive", userH.SetUserIsActive).Methods("POST"), leads...)
+	policy.allow(r.HandleFunc("/users/getReview", userH.GetReviewPRs).Methods("GET").Queries("user_id", "{user_id}"))
+	if opts.Expertise != nil {
+		policy.allow(r.HandleFunc("/users/expertise", opts.Expertise.ListExpertise).Methods("GET"))
+	}
+
+	// PullRequests; merge and reassign further restrict members to their own PRs and reviews.
+	policy.allow(r.HandleFunc("/pullRequest/create", prH.CreatePR).Methods("POST"))
+	policy.allow(r.HandleFunc("/pullRequest/get", prH.GetPR).Methods("GET").Queries("pull_request_id", "{pull_request_id}"))
+	policy.allow(r.HandleFunc("/pullRequest/merge", prH.MergePR).Methods("POST")) // Используем body для PR_ID
+	policy.allow(r.HandleFunc("/pullRequest/reassign", prH.ReassignReviewer).Methods("POST"))
+
+	if opts.Stream != nil {
+		route := r.HandleFunc("/events/stream", opts.Stream.Stream).Methods("GET")
+		streams[route] = true
+		policy.allow(route)
+	}
+
+	// Code host webhooks authenticate with their own signatures rather than bearer tokens.
+	if opts.Integrations != nil && opts.Integrations.opts.GitHubSecret != "" {
+		r.HandleFunc("/integrations/github/webhook", opts.Integrations.GitHubWebhook).Methods("POST")
+	}
+	if opts.Integrations != nil && opts.Integrations.opts.GitLabToken != "" {
+		r.HandleFunc("/integrations/gitlab/webhook", opts.Integrations.GitLabWebhook).Methods("POST")
+	}
+
+	if opts.Audit != nil {
+		policy.allow(r.HandleFunc("/audit", opts.Audit.ListAudit).Methods("GET"), leads...)
+	}
 
-	// PullRequests
-	r.HandleFunc("/pullRequest/create", prH.CreatePR).Methods("POST")
-	r.HandleFunc("/pullRequest/merge", prH.MergePR).Methods("POST") // Используем body для PR_ID
-	r.HandleFunc("/pullRequest/reassign", prH.ReassignReviewer).Methods("POST")
+	// Admin
+	if opts.Auth != nil && opts.Tokens != nil {
+		policy.allow(r.HandleFunc("/admin/tokens", opts.Tokens.CreateToken).Methods("POST"), domain.RoleAdmin)
+		policy.allow(r.HandleFunc("/admin/tokens", opts.Tokens.ListTokens).Methods("GET"), domain.RoleAdmin)
+		policy.allow(r.HandleFunc("/admin/tokens/revoke", opts.Tokens.RevokeToken).Methods("POST"), domain.RoleAdmin)
+	}
+	if opts.Webhooks != nil {
+		policy.allow(r.HandleFunc("/admin/webhooks", opts.Webhooks.CreateWebhook).Methods("POST"), domain.RoleAdmin)
+		policy.allow(r.HandleFunc("/admin/webhooks", opts.Webhooks.ListWebhooks).Methods("GET"), domain.RoleAdmin)
+		policy.allow(r.HandleFunc("/admin/webhooks/delete", opts.Webhooks.DeleteWebhook).Methods("POST"), domain.RoleAdmin)
+		policy.allow(r.HandleFunc("/admin/webhooks/deadletters", opts.Webhooks.ListDeadLetters).Methods("GET"), domain.RoleAdmin)
+		policy.allow(r.HandleFunc("/admin/webhooks/deadletters/retry", opts.Webhooks.RetryDeadLetter).Methods("POST"), domain.RoleAdmin)
+	}
+	if opts.Integrations != nil {
+		policy.allow(r.HandleFunc("/admin/integrations/mappings", opts.Integrations.SetUserMapping).Methods("POST"), domain.RoleAdmin)
+		policy.allow(r.HandleFunc("/admin/integrations/mappings", opts.Integrations.ListUserMappings).Methods("GET"), domain.RoleAdmin)
+		policy.allow(r.HandleFunc("/admin/integrations/mappings/delete", opts.Integrations.DeleteUserMapping).Methods("POST"), domain.RoleAdmin)
+	}
 
 	return r
 }
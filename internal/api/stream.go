@@ -0,0 +1,109 @@
+package api
+
+import (
+	"Backend/internal/domain"
+	"Backend/internal/service"
+	"Backend/internal/stream"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type StreamHandler struct {
+	broker      *stream.Broker
+	teamService service.TeamService
+	keepAlive   time.Duration
+}
+
+// NewStreamHandler serves the events of broker; a comment is sent every
+// keepAlive so that idle connections are not closed by proxies.
+func NewStreamHandler(broker *stream.Broker, teamService service.TeamService, keepAlive time.Duration) *StreamHandler {
+	return &StreamHandler{broker: broker, teamService: teamService, keepAlive: keepAlive}
+}
+
+// Stream sends reviewer assignment, reassignment and merge events as
+// Server-Sent Events, optionally only those involving ?user_id or a member of
+// ?team_name. Clients resume with the Last-Event-ID header.
+func (h *StreamHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	var filter stream.Filter
+	var err error
+	if r.URL.Query().Has("user_id") {
+		if filter.UserID, err = requireIDParam(r, "user_id"); err != nil {
+			handleServiceError(w, r, err)
+			return
+		}
+	}
+	if r.URL.Query().Has("team_name") {
+		if filter.Team, err = requireIDParam(r, "team_name"); err != nil {
+			handleServiceError(w, r, err)
+			return
+		}
+		team, err := h.teamService.GetTeamByName(r.Context(), filter.Team)
+		if err != nil {
+			handleServiceError(w, r, err)
+			return
+		}
+		for _, member := range team.Members {
+			filter.Members = append(filter.Members, member.UserID)
+		}
+	}
+
+	// The server's write timeout would end the stream.
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		handleServiceError(w, r, fmt.Errorf("clear write deadline: %w", err))
+		return
+	}
+
+	missed, sub := h.broker.Subscribe(filter, r.Header.Get("Last-Event-ID"))
+	defer h.broker.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	for _, event := range missed {
+		if err := writeEvent(w, event); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		slog.WarnContext(r.Context(), "event stream cannot be flushed", "error", err)
+		return
+	}
+
+	keepAlive := time.NewTicker(h.keepAlive)
+	defer keepAlive.Stop()
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			err = writeEvent(w, event)
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event domain.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"Backend/internal/domain"
+	"Backend/internal/metrics"
+	"Backend/internal/repository/memory"
+	"Backend/internal/service"
+	"Backend/internal/stream"
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newStreamServer(t *testing.T) (*httptest.Server, *stream.Broker) {
+	t.Helper()
+	repo := memory.NewMemoryRepository()
+	_, err := repo.CreateOrUpdateTeam(context.Background(), domain.Team{TeamName: "backend", Members: []domain.User{
+		{UserID: "u1", Username: "Alice", TeamName: "backend", IsActive: true},
+	}})
+	if err != nil {
+		t.Fatalf("Failed to seed team: %v", err)
+	}
+
+	broker := stream.NewBroker(10)
+	teams := service.NewTeamService(repo)
+	router := NewRouter(NewPRHandler(nil), NewTeamHandler(teams), NewUserHandler(nil), RouterOptions{
+		RequestTimeout: 20 * time.Millisecond,
+		Metrics:        metrics.New(),
+		Stream:         NewStreamHandler(broker, teams, time.Hour),
+	})
+	srv := httptest.NewServer(router)
+	t.Cleanup(func() {
+		broker.Close()
+		srv.Close()
+	})
+	return srv, broker
+}
+
+func assigned(id, author string, reviewers ...string) domain.Event {
+	return domain.Event{ID: id, Type: domain.EventReviewersAssigned,
+		PullRequest: &domain.PullRequest{PullRequestID: "pr-" + id, AuthorID: author, AssignedReviewers: reviewers}}
+}
+
+// readEvent returns the id, event and data lines of the next event.
+func readEvent(t *testing.T, r *bufio.Reader) []string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Stream ended: %v", err)
+		}
+		if line = strings.TrimSuffix(line, "\n"); line == "" {
+			return lines
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestStream_DeliversAndResumes(t *testing.T) {
+	srv, broker := newStreamServer(t)
+	ctx := context.Background()
+	broker.Publish(ctx, assigned("1", "u1", "u2"))
+	broker.Publish(ctx, assigned("2", "u3", "u4"))
+	broker.Publish(ctx, assigned("3", "u5", "u2"))
+
+	req, _ := http.NewRequest("GET", srv.URL+"/events/stream?user_id=u2", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	body := bufio.NewReader(resp.Body)
+	if lines := readEvent(t, body); len(lines) != 3 || lines[0] != "id: 3" || lines[1] != "event: "+domain.EventReviewersAssigned {
+		t.Fatalf("Expected the missed event 3, got %q", lines)
+	}
+
+	// The stream outlives the request timeout.
+	time.Sleep(50 * time.Millisecond)
+	broker.Publish(ctx, assigned("4", "u6", "u7"))
+	broker.Publish(ctx, assigned("5", "u2", "u1"))
+	if lines := readEvent(t, body); len(lines) != 3 || lines[0] != "id: 5" || !strings.Contains(lines[2], `"author_id":"u2"`) {
+		t.Fatalf("Expected the live event 5, got %q", lines)
+	}
+}
+
+func TestStream_ValidatesFilters(t *testing.T) {
+	srv, _ := newStreamServer(t)
+	for query, want := range map[string]int{"team_name=missing": http.StatusNotFound, "user_id=": http.StatusBadRequest} {
+		resp, err := http.Get(srv.URL + "/events/stream?" + query)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("%s: expected %d, got %d", query, want, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"Backend/internal/domain"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxIDLen = 64
+
+// validate checks v against the `validate` tags of its fields and returns a
+// VALIDATION_FAILED error listing every offending field by its JSON path.
+// Rules are comma separated:
+//
+//	required  non-blank string, non-nil pointer, non-empty slice
+//	max=N     at most N characters (strings) or elements (slices)
+//	id        at most maxIDLen characters from [A-Za-z0-9._-]
+//	dive      validate every element of a slice of structs
+//	unique=F  no two slice elements share the value of their JSON field F
+func validate(v any) error {
+	var errs []domain.FieldError
+	validateStruct(reflect.Indirect(reflect.ValueOf(v)), "", &errs)
+	if len(errs) > 0 {
+		return domain.NewValidationError(errs...)
+	}
+	return nil
+}
+
+func validateStruct(rv reflect.Value, prefix string, errs *[]domain.FieldError) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		tag := sf.Tag.Get("validate")
+		if tag == "" {
+			continue
+		}
+		name := prefix + jsonName(sf)
+		fv := rv.Field(i)
+		fail := func(format string, args ...any) {
+			*errs = append(*errs, domain.FieldError{Field: name, Message: fmt.Sprintf(format, args...)})
+		}
+
+		rules := strings.Split(tag, ",")
+		if isEmpty(fv) {
+			for _, rule := range rules {
+				if rule == "required" {
+					fail("is required")
+				}
+			}
+			continue
+		}
+
+		for _, rule := range rules {
+			rule, arg, _ := strings.Cut(rule, "=")
+			switch rule {
+			case "required":
+			case "max":
+				limit, _ := strconv.Atoi(arg)
+				if fv.Kind() == reflect.String && utf8.RuneCountInString(fv.String()) > limit {
+					fail("must be at most %d characters", limit)
+				} else if fv.Kind() == reflect.Slice && fv.Len() > limit {
+					fail("must have at most %d items", limit)
+				}
+			case "id":
+				if msg := checkID(fv.String()); msg != "" {
+					fail("%s", msg)
+				}
+			case "dive":
+				for j := 0; j < fv.Len(); j++ {
+					validateStruct(fv.Index(j), fmt.Sprintf("%s[%d].", name, j), errs)
+				}
+			case "unique":
+				seen := make(map[any]int)
+				for j := 0; j < fv.Len(); j++ {
+					key := fieldByJSONName(fv.Index(j), arg)
+					if !key.IsValid() || key.IsZero() {
+						continue
+					}
+					if first, ok := seen[key.Interface()]; ok {
+						*errs = append(*errs, domain.FieldError{
+							Field:   fmt.Sprintf("%s[%d].%s", name, j, arg),
+							Message: fmt.Sprintf("duplicates %s[%d].%s", name, first, arg),
+						})
+						continue
+					}
+					seen[key.Interface()] = j
+				}
+			default:
+				panic(fmt.Sprintf("validate: unknown rule %q on %s.%s", rule, rt.Name(), sf.Name))
+			}
+		}
+	}
+}
+
+func isEmpty(fv reflect.Value) bool {
+	switch fv.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(fv.String()) == ""
+	case reflect.Slice:
+		return fv.Len() == 0
+	}
+	return fv.IsZero()
+}
+
+// checkID returns why s is not an acceptable identifier, or "" if it is.
+func checkID(s string) string {
+	if len(s) > maxIDLen {
+		return fmt.Sprintf("must be at most %d characters", maxIDLen)
+	}
+	for _, c := range s {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '_' || c == '-') {
+			return "may only contain letters, digits, '.', '_' and '-'"
+		}
+	}
+	return ""
+}
+
+func jsonName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "" {
+		return sf.Name
+	}
+	return name
+}
+
+func fieldByJSONName(rv reflect.Value, name string) reflect.Value {
+	rv = reflect.Indirect(rv)
+	for i := 0; i < rv.NumField(); i++ {
+		if jsonName(rv.Type().Field(i)) == name {
+			return rv.Field(i)
+		}
+	}
+	return reflect.Value{}
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"Backend/internal/domain"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func decodeFields(t *testing.T, body string, v any) []domain.FieldError {
+	t.Helper()
+	err := decodeJSONBody(httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)), v)
+	if err == nil {
+		return nil
+	}
+	var bErr *domain.BusinessError
+	if !errors.As(err, &bErr) || bErr.Code != domain.ErrValidationFailed {
+		t.Fatalf("Expected VALIDATION_FAILED, got %v", err)
+	}
+	return bErr.Details
+}
+
+func TestDecodeJSONBody_Validation(t *testing.T) {
+	long := strings.Repeat("a", maxIDLen+1)
+	tests := []struct {
+		name string
+		body string
+		dto  any
+		want []domain.FieldError
+	}{
+		{
+			name: "valid pull request",
+			body: `{"pull_request_id":"pr-1","pull_request_name":"Fix","author_id":"u1"}`,
+			dto:  &PullRequestCreateRequestDTO{},
+		},
+		{
+			name: "missing and malformed ids",
+			body: `{"pull_request_id":"pr 1","pull_request_name":"  ","author_id":"` + long + `"}`,
+			dto:  &PullRequestCreateRequestDTO{},
+			want: []domain.FieldError{
+				{Field: "pull_request_id", Message: "may only contain letters, digits, '.', '_' and '-'"},
+				{Field: "pull_request_name", Message: "is required"},
+				{Field: "author_id", Message: "must be at most 64 characters"},
+			},
+		},
+		{
+			name: "empty team name and duplicate members",
+			body: `{"team_name":"","members":[{"user_id":"u1","username":"A"},{"user_id":"u2","username":""},{"user_id":"u1","username":"C"}]}`,
+			dto:  &TeamRequestDTO{},
+			want: []domain.FieldError{
+				{Field: "team_name", Message: "is required"},
+				{Field: "members[1].username", Message: "is required"},
+				{Field: "members[2].user_id", Message: "duplicates members[0].user_id"},
+			},
+		},
+		{
+			name: "missing is_active",
+			body: `{"user_id":"u1"}`,
+			dto:  &UserIsActiveRequestDTO{},
+			want: []domain.FieldError{{Field: "is_active", Message: "is required"}},
+		},
+		{
+			name: "unknown field",
+			body: `{"pull_request_id":"pr-1","old_user_id":"u1","new_user_id":"u2"}`,
+			dto:  &PullRequestReassignRequestDTO{},
+			want: []domain.FieldError{{Field: "new_user_id", Message: "is not allowed"}},
+		},
+		{
+			name: "unknown nested field",
+			body: `{"team_name":"backend","members":[{"user_id":"u1","username":"A"},{"user_id":"u2","username":"B","role":"admin"}]}`,
+			dto:  &TeamRequestDTO{},
+			want: []domain.FieldError{{Field: "members[1].role", Message: "is not allowed"}},
+		},
+		{
+			name: "wrong type",
+			body: `{"user_id":"u1","is_active":"yes"}`,
+			dto:  &UserIsActiveRequestDTO{},
+			want: []domain.FieldError{{Field: "is_active", Message: "must be a bool"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := decodeFields(t, tt.body, tt.dto)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDecodeJSONBody_MalformedJSON(t *testing.T) {
+	for _, body := range []string{"", "{", `{"user_id":"u1"} {}`} {
+		err := decodeJSONBody(httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)), &UserIsActiveRequestDTO{})
+		var bErr *domain.BusinessError
+		if !errors.As(err, &bErr) || bErr.Code != domain.ErrInvalidRequest {
+			t.Errorf("Expected INVALID_REQUEST for %q, got %v", body, err)
+		}
+	}
+}
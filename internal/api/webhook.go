@@ -0,0 +1,95 @@
+package api
+
+import (
+	"Backend/internal/domain"
+	"Backend/internal/service"
+	"net/http"
+	"strconv"
+)
+
+type WebhookHandler struct{ webhookService service.WebhookService }
+
+func NewWebhookHandler(webhookService service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{webhookService: webhookService}
+}
+
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var reqBody WebhookCreateRequestDTO
+	if err := decodeJSONBody(r, &reqBody); err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	sub, secret, err := h.webhookService.CreateSubscription(r.Context(), reqBody.URL, reqBody.Secret, reqBody.Events)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	sendJSONResponse(w, http.StatusCreated, WebhookCreateResponseDTO{Secret: secret, Subscription: sub})
+}
+
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.webhookService.ListSubscriptions(r.Context())
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, map[string]interface{}{"subscriptions": subs})
+}
+
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	var reqBody WebhookDeleteRequestDTO
+	if err := decodeJSONBody(r, &reqBody); err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	sub, err := h.webhookService.DeleteSubscription(r.Context(), reqBody.ID)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, sub)
+}
+
+// ListDeadLetters serves GET /admin/webhooks/deadletters?limit=, newest first.
+func (h *WebhookHandler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > service.MaxDeadLetterLimit {
+			handleServiceError(w, r, domain.NewValidationError(domain.FieldError{
+				Field: "limit", Message: "must be an integer between 1 and " + strconv.Itoa(service.MaxDeadLetterLimit)}))
+			return
+		}
+	}
+
+	deliveries, err := h.webhookService.ListDeadLetters(r.Context(), limit)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, map[string]interface{}{"deliveries": deliveries})
+}
+
+func (h *WebhookHandler) RetryDeadLetter(w http.ResponseWriter, r *http.Request) {
+	var reqBody WebhookRetryRequestDTO
+	if err := decodeJSONBody(r, &reqBody); err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	delivery, err := h.webhookService.RetryDeadLetter(r.Context(), reqBody.ID)
+	if err != nil {
+		handleServiceError(w, r, err)
+		return
+	}
+
+	sendJSONResponse(w, http.StatusOK, delivery)
+}
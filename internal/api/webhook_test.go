@@ -0,0 +1,94 @@
+package api
+
+import (
+	"Backend/internal/domain"
+	"Backend/internal/repository/memory"
+	"Backend/internal/service"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebhooks_SubscribeAndRetryDeadLetters(t *testing.T) {
+	f := newAuthFixture(t)
+	admin := f.secrets[domain.RoleAdmin]
+
+	if rec := f.do("POST", "/admin/webhooks", f.secrets[domain.RoleTeamLead], `{"url":"https://hooks.example.com","events":["*"]}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("Expected 403 for a team lead, got %d", rec.Code)
+	}
+	rec := f.do("POST", "/admin/webhooks", admin, `{"url":"https://hooks.example.com","events":["pull_request.merged"]}`)
+	if rec.Code != http.StatusCreated || rec.Header().Get("Cache-Control") != "no-store" {
+		t.Fatalf("Expected 201 with no-store, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created WebhookCreateResponseDTO
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil || created.Secret == "" || created.Subscription.ID == "" {
+		t.Fatalf("Expected the subscription and its secret, got %+v (%v)", created, err)
+	}
+
+	if rec := f.do("POST", "/pullRequest/merge", admin, `{"pull_request_id":"pr-1"}`); rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	ctx := context.Background()
+	events, _ := f.repo.ClaimOutboxEvents(ctx, time.Now().UTC(), time.Minute, 10)
+	for _, e := range events {
+		if err := service.NewWebhookService(f.repo).Publish(ctx, e.Event); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+	pending, _ := f.repo.ListWebhookDeliveries(ctx, domain.DeliveryPending, 10)
+	if len(pending) != 1 || pending[0].EventType != domain.EventPRMerged {
+		t.Fatalf("Expected a merge delivery, got %+v", pending)
+	}
+	dead := pending[0]
+	dead.Status, dead.Attempts, dead.LastError = domain.DeliveryDead, 8, "unexpected status 500"
+	if err := f.repo.UpdateWebhookDelivery(ctx, dead); err != nil {
+		t.Fatalf("UpdateWebhookDelivery failed: %v", err)
+	}
+
+	rec = f.do("GET", "/admin/webhooks/deadletters?limit=10", admin, "")
+	var list struct {
+		Deliveries []domain.WebhookDelivery `json:"deliveries"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil || len(list.Deliveries) != 1 || list.Deliveries[0].LastError != "unexpected status 500" {
+		t.Fatalf("Expected the dead delivery to be listed, got %+v (%v)", list, err)
+	}
+	if rec := f.do("GET", "/admin/webhooks/deadletters?limit=0", admin, ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid limit, got %d", rec.Code)
+	}
+
+	if rec := f.do("POST", "/admin/webhooks/deadletters/retry", admin, `{"id":"`+dead.ID+`"}`); rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := f.do("POST", "/admin/webhooks/deadletters/retry", admin, `{"id":"`+dead.ID+`"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 once the delivery is pending again, got %d", rec.Code)
+	}
+
+	if rec := f.do("POST", "/admin/webhooks/delete", admin, `{"id":"`+created.Subscription.ID+`"}`); rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = f.do("GET", "/admin/webhooks", admin, "")
+	var subs struct {
+		Subscriptions []domain.WebhookSubscription `json:"subscriptions"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&subs); err != nil || len(subs.Subscriptions) != 0 {
+		t.Errorf("Expected no subscriptions left, got %+v (%v)", subs, err)
+	}
+}
+
+func TestWebhooks_AvailableWithoutAuth(t *testing.T) {
+	repo := memory.NewMemoryRepository()
+	router := NewRouter(NewPRHandler(nil), NewTeamHandler(nil), NewUserHandler(nil), RouterOptions{
+		Webhooks: NewWebhookHandler(service.NewWebhookService(repo)),
+	})
+
+	for _, path := range []string{"/admin/webhooks", "/admin/webhooks/deadletters?limit=10"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("Expected 200 for %s without auth, got %d: %s", path, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// Package auth carries the authenticated caller through request contexts and
+// implements the credential formats accepted by the API.
+package auth
+
+import (
+	"Backend/internal/domain"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// TokenPrefix marks API tokens so that they are easy to spot in configs and logs.
+const TokenPrefix = "prr_"
+
+type ctxKey struct{}
+
+func WithIdentity(ctx context.Context, identity domain.Identity) context.Context {
+	return context.WithValue(ctx, ctxKey{}, identity)
+}
+
+// IdentityFromContext returns the caller of the request; ok is false when the
+// request was not authenticated, e.g. because authentication is disabled.
+func IdentityFromContext(ctx context.Context) (domain.Identity, bool) {
+	identity, ok := ctx.Value(ctxKey{}).(domain.Identity)
+	return identity, ok
+}
+
+// GenerateToken returns a new random API token secret.
+func GenerateToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return TokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+}
+
+// HashToken returns the value stored instead of the token. Tokens are random
+// 256-bit secrets, so a plain SHA-256 is enough to make a leaked table useless.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Authenticator resolves a bearer credential to the caller's identity.
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (domain.Identity, error)
+}
+
+// Chain sends API tokens, recognised by TokenPrefix, to APITokens and every
+// other bearer credential to JWT. Either may be nil to reject that kind.
+type Chain struct {
+	APITokens Authenticator
+	JWT       Authenticator
+}
+
+func (c Chain) Authenticate(ctx context.Context, token string) (domain.Identity, error) {
+	next := c.JWT
+	if strings.HasPrefix(token, TokenPrefix) {
+		next = c.APITokens
+	}
+	if next == nil {
+		return domain.Identity{}, domain.NewBusinessError(domain.ErrUnauthorized, "Unsupported bearer token")
+	}
+	return next.Authenticate(ctx, token)
+}
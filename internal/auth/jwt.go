@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"Backend/internal/domain"
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTOptions describes which tokens a JWTVerifier accepts and how their claims
+// map to an identity.
+type JWTOptions struct {
+	// Issuer and Audience, when set, must match the iss and aud claims.
+	Issuer   string
+	Audience string
+	// UserClaim names the claim holding the user id; it defaults to sub.
+	UserClaim string
+	// RolesClaim names the claim holding the roles, either as an array or a
+	// space-separated string. A dotted path such as realm_access.roles reaches
+	// into nested objects. It defaults to roles.
+	RolesClaim string
+	// Leeway tolerates clock skew when checking exp, nbf and iat.
+	Leeway time.Duration
+}
+
+// JWTVerifier authenticates RS256 and ES256 signed JWTs against a KeySet.
+type JWTVerifier struct {
+	keys   *KeySet
+	opts   JWTOptions
+	parser *jwt.Parser
+}
+
+func NewJWTVerifier(keys *KeySet, opts JWTOptions) *JWTVerifier {
+	if opts.UserClaim == "" {
+		opts.UserClaim = "sub"
+	}
+	if opts.RolesClaim == "" {
+		opts.RolesClaim = "roles"
+	}
+
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(opts.Leeway),
+	}
+	if opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.Issuer))
+	}
+	if opts.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(opts.Audience))
+	}
+	return &JWTVerifier{keys: keys, opts: opts, parser: jwt.NewParser(parserOpts...)}
+}
+
+var errInvalidJWT = domain.NewBusinessError(domain.ErrUnauthorized, "Invalid or expired bearer token")
+
+// Authenticate verifies the token's signature and registered claims and maps
+// it to an identity. Roles the service does not know are ignored.
+func (v *JWTVerifier) Authenticate(ctx context.Context, token string) (domain.Identity, error) {
+	claims := jwt.MapClaims{}
+	_, err := v.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.keys.Key(ctx, kid)
+	})
+	if err != nil {
+		// The reason is useful to operators but must not be echoed to callers.
+		slog.DebugContext(ctx, "JWT rejected", "error", err)
+		return domain.Identity{}, errInvalidJWT
+	}
+
+	subject, _ := claims["sub"].(string)
+	userID, _ := claimValue(claims, v.opts.UserClaim).(string)
+	if subject == "" || userID == "" {
+		slog.DebugContext(ctx, "JWT rejected", "error", "missing subject or user claim")
+		return domain.Identity{}, errInvalidJWT
+	}
+
+	return domain.Identity{
+		Subject: "jwt:" + subject,
+		UserID:  userID,
+		Roles:   parseRoles(claimValue(claims, v.opts.RolesClaim)),
+	}, nil
+}
+
+func claimValue(claims jwt.MapClaims, path string) any {
+	var value any = map[string]any(claims)
+	for _, part := range strings.Split(path, ".") {
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = obj[part]
+	}
+	return value
+}
+
+func parseRoles(value any) []domain.Role {
+	var names []string
+	switch v := value.(type) {
+	case string:
+		names = strings.Fields(v)
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				names = append(names, s)
+			}
+		}
+	}
+
+	var roles []domain.Role
+	for _, name := range names {
+		role := domain.Role(name)
+		if slices.Contains(domain.Roles, role) && !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
@@ -0,0 +1,260 @@
+package auth_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"Backend/internal/auth"
+	"Backend/internal/domain"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "https://id.example.com"
+	testAudience = "pr-reviewer"
+)
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func jwkOf(t *testing.T, kid string, key crypto.PublicKey) map[string]string {
+	t.Helper()
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return map[string]string{"kty": "RSA", "kid": kid, "use": "sig", "n": b64(k.N.Bytes()), "e": b64(big.NewInt(int64(k.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		return map[string]string{"kty": "EC", "kid": kid, "crv": "P-256", "x": b64(k.X.FillBytes(make([]byte, 32))), "y": b64(k.Y.FillBytes(make([]byte, 32)))}
+	}
+	t.Fatalf("Unsupported key type %T", key)
+	return nil
+}
+
+func jwksJSON(t *testing.T, keys ...map[string]string) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{"keys": keys})
+	if err != nil {
+		t.Fatalf("Failed to marshal JWKS: %v", err)
+	}
+	return data
+}
+
+type testKeys struct {
+	rsa *rsa.PrivateKey
+	ec  *ecdsa.PrivateKey
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+	return testKeys{rsa: rsaKey, ec: ecKey}
+}
+
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":   testIssuer,
+		"aud":   testAudience,
+		"sub":   "00u1",
+		"uid":   "u1",
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"roles": []string{"member", "unknown-role"},
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return s
+}
+
+func newVerifier(t *testing.T, keys testKeys, opts auth.JWTOptions) *auth.JWTVerifier {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	data := jwksJSON(t, jwkOf(t, "rsa-1", &keys.rsa.PublicKey), jwkOf(t, "ec-1", &keys.ec.PublicKey))
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("Failed to write JWKS: %v", err)
+	}
+	ks, err := auth.NewKeySetFromFile(context.Background(), path, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to load JWKS: %v", err)
+	}
+	return auth.NewJWTVerifier(ks, opts)
+}
+
+func isUnauthorized(err error) bool {
+	var be *domain.BusinessError
+	return errors.As(err, &be) && be.Code == domain.ErrUnauthorized
+}
+
+func TestJWTVerifier_AcceptsRS256AndES256(t *testing.T) {
+	keys := newTestKeys(t)
+	v := newVerifier(t, keys, auth.JWTOptions{Issuer: testIssuer, Audience: testAudience, UserClaim: "uid"})
+
+	tokens := map[string]string{
+		"RS256": sign(t, jwt.SigningMethodRS256, "rsa-1", keys.rsa, validClaims()),
+		"ES256": sign(t, jwt.SigningMethodES256, "ec-1", keys.ec, validClaims()),
+	}
+	for name, token := range tokens {
+		identity, err := v.Authenticate(context.Background(), token)
+		if err != nil {
+			t.Fatalf("%s: expected token to be accepted, got %v", name, err)
+		}
+		if identity.Subject != "jwt:00u1" || identity.UserID != "u1" {
+			t.Errorf("%s: unexpected identity %+v", name, identity)
+		}
+		if !slices.Equal(identity.Roles, []domain.Role{domain.RoleMember}) {
+			t.Errorf("%s: expected unknown roles to be dropped, got %v", name, identity.Roles)
+		}
+	}
+}
+
+func TestJWTVerifier_NestedRolesClaim(t *testing.T) {
+	keys := newTestKeys(t)
+	v := newVerifier(t, keys, auth.JWTOptions{Issuer: testIssuer, Audience: testAudience, RolesClaim: "realm_access.roles"})
+
+	claims := validClaims()
+	claims["realm_access"] = map[string]any{"roles": []string{"team-lead", "admin"}}
+	identity, err := v.Authenticate(context.Background(), sign(t, jwt.SigningMethodRS256, "rsa-1", keys.rsa, claims))
+	if err != nil {
+		t.Fatalf("Expected token to be accepted, got %v", err)
+	}
+	if identity.UserID != "00u1" {
+		t.Errorf("Expected the user id to default to sub, got %q", identity.UserID)
+	}
+	if !slices.Equal(identity.Roles, []domain.Role{domain.RoleTeamLead, domain.RoleAdmin}) {
+		t.Errorf("Unexpected roles %v", identity.Roles)
+	}
+}
+
+func TestJWTVerifier_Rejects(t *testing.T) {
+	keys := newTestKeys(t)
+	other := newTestKeys(t)
+	v := newVerifier(t, keys, auth.JWTOptions{Issuer: testIssuer, Audience: testAudience})
+
+	with := func(mutate func(jwt.MapClaims)) jwt.MapClaims {
+		c := validClaims()
+		mutate(c)
+		return c
+	}
+	cases := map[string]string{
+		"expired":        sign(t, jwt.SigningMethodRS256, "rsa-1", keys.rsa, with(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() })),
+		"no exp":         sign(t, jwt.SigningMethodRS256, "rsa-1", keys.rsa, with(func(c jwt.MapClaims) { delete(c, "exp") })),
+		"wrong issuer":   sign(t, jwt.SigningMethodRS256, "rsa-1", keys.rsa, with(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" })),
+		"wrong audience": sign(t, jwt.SigningMethodRS256, "rsa-1", keys.rsa, with(func(c jwt.MapClaims) { c["aud"] = "other-service" })),
+		"no subject":     sign(t, jwt.SigningMethodRS256, "rsa-1", keys.rsa, with(func(c jwt.MapClaims) { delete(c, "sub") })),
+		"foreign key":    sign(t, jwt.SigningMethodRS256, "rsa-1", other.rsa, validClaims()),
+		"unknown kid":    sign(t, jwt.SigningMethodRS256, "rsa-2", keys.rsa, validClaims()),
+		"key mismatch":   sign(t, jwt.SigningMethodES256, "rsa-1", keys.ec, validClaims()),
+		"HS256":          sign(t, jwt.SigningMethodHS256, "rsa-1", []byte("secret"), validClaims()),
+		"RS384":          sign(t, jwt.SigningMethodRS384, "rsa-1", keys.rsa, validClaims()),
+		"garbage":        "not.a.jwt",
+	}
+	for name, token := range cases {
+		if _, err := v.Authenticate(context.Background(), token); !isUnauthorized(err) {
+			t.Errorf("%s: expected UNAUTHORIZED, got %v", name, err)
+		}
+	}
+}
+
+func TestKeySet_ReloadsOnUnknownKeyID(t *testing.T) {
+	first, second := newTestKeys(t), newTestKeys(t)
+	var rotated atomic.Bool
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if rotated.Load() {
+			w.Write(jwksJSON(t, jwkOf(t, "k2", &second.ec.PublicKey)))
+			return
+		}
+		w.Write(jwksJSON(t, jwkOf(t, "k1", &first.ec.PublicKey)))
+	}))
+	defer srv.Close()
+
+	ks, err := auth.NewKeySetFromURL(context.Background(), srv.URL, srv.Client(), 0)
+	if err != nil {
+		t.Fatalf("Failed to load JWKS: %v", err)
+	}
+	v := auth.NewJWTVerifier(ks, auth.JWTOptions{Issuer: testIssuer, Audience: testAudience})
+
+	if _, err := v.Authenticate(context.Background(), sign(t, jwt.SigningMethodES256, "k1", first.ec, validClaims())); err != nil {
+		t.Fatalf("Expected token signed with k1 to be accepted, got %v", err)
+	}
+
+	rotated.Store(true)
+	if _, err := v.Authenticate(context.Background(), sign(t, jwt.SigningMethodES256, "k2", second.ec, validClaims())); err != nil {
+		t.Fatalf("Expected the rotated key to be picked up, got %v", err)
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("Expected 2 JWKS fetches, got %d", got)
+	}
+}
+
+func TestParseJWKS_RejectsWeakAndInvalidKeys(t *testing.T) {
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	bad := map[string]string{"kty": "EC", "kid": "bad", "crv": "P-256", "x": b64([]byte{1}), "y": b64([]byte{2})}
+
+	for name, data := range map[string][]byte{
+		"small RSA key": jwksJSON(t, jwkOf(t, "small", &small.PublicKey)),
+		"off-curve EC":  jwksJSON(t, bad),
+		"empty":         jwksJSON(t),
+		"not JSON":      []byte("{"),
+	} {
+		if _, err := auth.ParseJWKS(data); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+type stubAuthenticator string
+
+func (s stubAuthenticator) Authenticate(context.Context, string) (domain.Identity, error) {
+	return domain.Identity{Subject: string(s)}, nil
+}
+
+func TestChain_RoutesByTokenShape(t *testing.T) {
+	chain := auth.Chain{APITokens: stubAuthenticator("api"), JWT: stubAuthenticator("jwt")}
+
+	if id, _ := chain.Authenticate(context.Background(), auth.TokenPrefix+"abc"); id.Subject != "api" {
+		t.Errorf("Expected API tokens to reach the API token authenticator, got %q", id.Subject)
+	}
+	if id, _ := chain.Authenticate(context.Background(), "eyJ.a.b"); id.Subject != "jwt" {
+		t.Errorf("Expected other tokens to reach the JWT verifier, got %q", id.Subject)
+	}
+	if _, err := (auth.Chain{APITokens: stubAuthenticator("api")}).Authenticate(context.Background(), "eyJ.a.b"); !isUnauthorized(err) {
+		t.Errorf("Expected UNAUTHORIZED without a JWT verifier, got %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+// Package codehost pushes reviewer assignments back to the code hosts that
+// pull requests come from, so that reviewers are requested on the real pull
+// request rather than only here.
+package codehost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"Backend/internal/domain"
+	"Backend/internal/repository"
+)
+
+// PullRequestRef locates a pull request on a code host. Pull requests opened
+// by code host webhooks store it as their CodeHostRef, encoded as
+// <provider>-<repository id>-<number>, and use the same string as their id.
+type PullRequestRef struct {
+	Provider string
+	RepoID   int64
+	Number   int
+}
+
+func (r PullRequestRef) String() string {
+	return fmt.Sprintf("%s-%d-%d", r.Provider, r.RepoID, r.Number)
+}
+
+// ParsePullRequestRef decodes a reference encoded by PullRequestRef.String.
+func ParsePullRequestRef(s string) (PullRequestRef, bool) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 3 {
+		return PullRequestRef{}, false
+	}
+	repoID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || repoID <= 0 {
+		return PullRequestRef{}, false
+	}
+	number, err := strconv.Atoi(parts[2])
+	if err != nil || number <= 0 {
+		return PullRequestRef{}, false
+	}
+	return PullRequestRef{Provider: parts[0], RepoID: repoID, Number: number}, true
+}
+
+// Client changes pull requests on a code host. Its methods must be safe to
+// repeat, as events are published at least once.
+type Client interface {
+	RequestReviewers(ctx context.Context, ref PullRequestRef, logins []string) error
+	RemoveRequestedReviewers(ctx context.Context, ref PullRequestRef, logins []string) error
+	// Comment posts body on the pull request unless a comment with the same
+	// key has been posted after since.
+	Comment(ctx context.Context, ref PullRequestRef, key string, since time.Time, body string) error
+}
+
+// APIError is an unsuccessful response of a code host API.
+type APIError struct {
+	StatusCode int
+	Message    string
+	// Retryable is set for server errors and rate limiting; other errors are
+	// not resolved by trying again.
+	Retryable bool
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("code host API returned %d: %s", e.StatusCode, e.Message)
+}
+
+const SinkName = "codehost"
+
+// Sink is an outbox sink that requests the assigned reviewers on the code host
+// of the pull request and comments on reassignments. Reviewers are addressed
+// by the logins they are mapped from; unmapped reviewers are skipped.
+type Sink struct {
+	mappings repository.UserMappingRepository
+	clients  map[string]Client
+}
+
+// NewSink returns a sink for the pull requests of the providers in clients.
+func NewSink(mappings repository.UserMappingRepository, clients map[string]Client) *Sink {
+	return &Sink{mappings: mappings, clients: clients}
+}
+
+// Publish returns an error only when trying again may succeed. Other failures,
+// such as a reviewer who cannot be requested on the repository, are logged,
+// so that they do not hold back the later events of the pull request.
+func (s *Sink) Publish(ctx context.Context, event domain.Event) error {
+	if event.PullRequest == nil || (event.Type != domain.EventReviewersAssigned && event.Type != domain.EventReviewerReassigned) {
+		return nil
+	}
+	// Only the code host integration sets the reference; an id of the same
+	// shape may as well have been chosen by whoever created the PR.
+	ref, ok := ParsePullRequestRef(event.PullRequest.CodeHostRef)
+	if !ok {
+		return nil
+	}
+	client, ok := s.clients[ref.Provider]
+	if !ok {
+		return nil
+	}
+
+	err := s.publish(ctx, client, ref, event)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && !apiErr.Retryable {
+		slog.WarnContext(ctx, "code host rejected reviewer update", "event_id", event.ID, "pr_id", event.PullRequest.PullRequestID, "error", err)
+		return nil
+	}
+	return err
+}
+
+func (s *Sink) publish(ctx context.Context, client Client, ref PullRequestRef, event domain.Event) error {
+	mappings, err := s.mappings.ListUserMappings(ctx, ref.Provider)
+	if err != nil {
+		return fmt.Errorf("list user mappings: %w", err)
+	}
+	logins := make(map[string]string, len(mappings))
+	for _, m := range mappings {
+		if _, ok := logins[m.UserID]; !ok {
+			logins[m.UserID] = m.Login
+		}
+	}
+
+	if event.Type == domain.EventReviewersAssigned {
+		var requested []string
+		for _, id := range event.PullRequest.AssignedReviewers {
+			if login, ok := logins[id]; ok {
+				requested = append(requested, login)
+			}
+		}
+		if len(requested) == 0 {
+			return nil
+		}
+		return client.RequestReviewers(ctx, ref, requested)
+	}
+
+	oldLogin, oldMapped := logins[event.OldReviewerID]
+	newLogin, newMapped := logins[event.NewReviewerID]
+	if newMapped {
+		if err := client.RequestReviewers(ctx, ref, []string{newLogin}); err != nil {
+			return err
+		}
+	}
+	if oldMapped {
+		if err := client.RemoveRequestedReviewers(ctx, ref, []string{oldLogin}); err != nil {
+			return err
+		}
+	}
+	mention := func(userID, login string, mapped bool) string {
+		if mapped {
+			return "@" + login
+		}
+		return userID
+	}
+	body := fmt.Sprintf("Reviewer %s was replaced by %s.", mention(event.OldReviewerID, oldLogin, oldMapped),
+		mention(event.NewReviewerID, newLogin, newMapped))
+	return client.Comment(ctx, ref, event.ID, event.OccurredAt, body)
+}
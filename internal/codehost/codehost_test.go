@@ -0,0 +1,112 @@
+package codehost
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"Backend/internal/domain"
+	"Backend/internal/repository/memory"
+)
+
+func TestParsePullRequestRef(t *testing.T) {
+	tests := []struct {
+		id string
+		ok bool
+	}{
+		{"github-712345678-42", true},
+		{"gitlab-2081-17", true},
+		{"pr-1", false},
+		{"github-acme-42", false},
+		{"github-712345678-0", false},
+		{"my-github-712345678-42", false},
+	}
+	for _, tt := range tests {
+		ref, ok := ParsePullRequestRef(tt.id)
+		if ok != tt.ok {
+			t.Errorf("%s: expected ok=%v, got %v", tt.id, tt.ok, ok)
+		}
+		if ok && ref.String() != tt.id {
+			t.Errorf("%s: expected a round trip, got %s", tt.id, ref)
+		}
+	}
+}
+
+func newTestSink(t *testing.T) (*fakeGitHub, *Sink) {
+	t.Helper()
+	ctx := context.Background()
+	repo := memory.NewMemoryRepository()
+	for userID, login := range map[string]string{"u2": "bob", "u3": "carol", "u4": "dave"} {
+		if _, err := repo.SetUserMapping(ctx, domain.UserMapping{Provider: domain.ProviderGitHub, Login: login, UserID: userID, CreatedAt: time.Now()}); err != nil {
+			t.Fatalf("Failed to map %s: %v", userID, err)
+		}
+	}
+	f, client := newFakeGitHub(t)
+	return f, NewSink(repo, map[string]Client{domain.ProviderGitHub: client})
+}
+
+func prEvent(id, typ string, reviewers ...string) domain.Event {
+	return domain.Event{ID: id, Type: typ, OccurredAt: time.Now().UTC(), PullRequest: &domain.PullRequest{
+		PullRequestID: "github-712345678-42", CodeHostRef: "github-712345678-42", AuthorID: "u1", Status: domain.StatusOpen,
+		AssignedReviewers: reviewers}}
+}
+
+func TestSink_PushesAssignmentsAndReassignments(t *testing.T) {
+	f, sink := newTestSink(t)
+	ctx := context.Background()
+
+	// u5 has no GitHub login, so only bob is requested.
+	if err := sink.Publish(ctx, prEvent("1", domain.EventReviewersAssigned, "u2", "u5")); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if len(f.reviewers) != 1 || !f.reviewers["bob"] {
+		t.Fatalf("Expected bob to be requested, got %v", f.reviewers)
+	}
+
+	reassigned := prEvent("2", domain.EventReviewerReassigned, "u4", "u5")
+	reassigned.OldReviewerID, reassigned.NewReviewerID = "u2", "u4"
+	for range 2 {
+		if err := sink.Publish(ctx, reassigned); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+	if len(f.reviewers) != 1 || !f.reviewers["dave"] {
+		t.Errorf("Expected bob to be replaced by dave, got %v", f.reviewers)
+	}
+	if len(f.comments) != 1 {
+		t.Fatalf("Expected one comment for a republished event, got %q", f.comments)
+	}
+
+	// Other code hosts, other events and PRs not opened by a code host are left alone.
+	requests := len(f.requests)
+	gitlab := prEvent("3", domain.EventReviewersAssigned, "u2")
+	gitlab.PullRequest.PullRequestID, gitlab.PullRequest.CodeHostRef = "gitlab-2081-17", "gitlab-2081-17"
+	local := prEvent("4", domain.EventReviewersAssigned, "u2")
+	local.PullRequest.PullRequestID, local.PullRequest.CodeHostRef = "pr-1", ""
+	// An id shaped like a reference does not make a PR created through the API a code host PR.
+	spoofed := prEvent("5", domain.EventReviewersAssigned, "u2")
+	spoofed.PullRequest.CodeHostRef = ""
+	for _, e := range []domain.Event{gitlab, local, spoofed, prEvent("6", domain.EventPRMerged, "u4")} {
+		if err := sink.Publish(ctx, e); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+	if len(f.requests) != requests {
+		t.Errorf("Expected no API calls, got %v", f.requests[requests:])
+	}
+}
+
+func TestSink_RetriesOnlyTransientFailures(t *testing.T) {
+	f, sink := newTestSink(t)
+	ctx := context.Background()
+
+	f.status = http.StatusUnprocessableEntity
+	if err := sink.Publish(ctx, prEvent("1", domain.EventReviewersAssigned, "u2")); err != nil {
+		t.Errorf("Expected a rejected request not to be retried, got %v", err)
+	}
+	f.status = http.StatusServiceUnavailable
+	if err := sink.Publish(ctx, prEvent("1", domain.EventReviewersAssigned, "u2")); err == nil {
+		t.Error("Expected an outage to be retried")
+	}
+}
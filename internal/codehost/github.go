@@ -0,0 +1,178 @@
+package codehost
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"Backend/internal/tracing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const DefaultGitHubAPIURL = "https://api.github.com"
+
+var tracer = otel.Tracer("Backend/internal/codehost")
+
+// GitHubClient calls the GitHub REST API with a token that may write pull
+// requests of the repositories, e.g. a fine-grained token with the "Pull
+// requests" permission.
+type GitHubClient struct {
+	baseURL string
+	token   string
+	client  *http.Client
+
+	mu sync.Mutex
+	// repos caches the full names of repositories by id; ids survive renames,
+	// names are what the API is addressed by.
+	repos map[int64]string
+}
+
+// NewGitHubClient returns a client of the API at baseURL, which differs from
+// DefaultGitHubAPIURL for GitHub Enterprise Server.
+func NewGitHubClient(baseURL, token string, timeout time.Duration) *GitHubClient {
+	return &GitHubClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		client:  &http.Client{Timeout: timeout},
+		repos:   make(map[int64]string),
+	}
+}
+
+func (c *GitHubClient) RequestReviewers(ctx context.Context, ref PullRequestRef, logins []string) (err error) {
+	ctx, span := startSpan(ctx, "GitHub.RequestReviewers", ref)
+	defer func() { tracing.End(span, err) }()
+
+	repo, err := c.repository(ctx, ref.RepoID)
+	if err != nil {
+		return err
+	}
+	path := fmt.Sprintf("/repos/%s/pulls/%d/requested_reviewers", repo, ref.Number)
+	return c.do(ctx, http.MethodPost, path, map[string][]string{"reviewers": logins}, nil)
+}
+
+func (c *GitHubClient) RemoveRequestedReviewers(ctx context.Context, ref PullRequestRef, logins []string) (err error) {
+	ctx, span := startSpan(ctx, "GitHub.RemoveRequestedReviewers", ref)
+	defer func() { tracing.End(span, err) }()
+
+	repo, err := c.repository(ctx, ref.RepoID)
+	if err != nil {
+		return err
+	}
+	path := fmt.Sprintf("/repos/%s/pulls/%d/requested_reviewers", repo, ref.Number)
+	return c.do(ctx, http.MethodDelete, path, map[string][]string{"reviewers": logins}, nil)
+}
+
+// Comment marks the comment with key in an HTML comment, which GitHub does
+// not render, and looks for the mark among the comments updated after since.
+func (c *GitHubClient) Comment(ctx context.Context, ref PullRequestRef, key string, since time.Time, body string) (err error) {
+	ctx, span := startSpan(ctx, "GitHub.Comment", ref)
+	defer func() { tracing.End(span, err) }()
+
+	repo, err := c.repository(ctx, ref.RepoID)
+	if err != nil {
+		return err
+	}
+	path := fmt.Sprintf("/repos/%s/issues/%d/comments", repo, ref.Number)
+	mark := "<!-- pr-reviewer:" + key + " -->"
+
+	var existing []struct {
+		Body string `json:"body"`
+	}
+	query := url.Values{"since": {since.UTC().Format(time.RFC3339)}, "per_page": {"100"}}
+	if err := c.do(ctx, http.MethodGet, path+"?"+query.Encode(), nil, &existing); err != nil {
+		return err
+	}
+	for _, comment := range existing {
+		if strings.Contains(comment.Body, mark) {
+			return nil
+		}
+	}
+	return c.do(ctx, http.MethodPost, path, map[string]string{"body": body + "\n\n" + mark}, nil)
+}
+
+func (c *GitHubClient) repository(ctx context.Context, id int64) (string, error) {
+	c.mu.Lock()
+	name, ok := c.repos[id]
+	c.mu.Unlock()
+	if ok {
+		return name, nil
+	}
+
+	var repo struct {
+		FullName string `json:"full_name"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/repositories/"+strconv.FormatInt(id, 10), nil, &repo); err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	c.repos[id] = repo.FullName
+	c.mu.Unlock()
+	return repo.FullName, nil
+}
+
+// do sends in as JSON, unless it is nil, and decodes the response into out,
+// unless it is nil.
+func (c *GitHubClient) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("encode request: %w", err)
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	req.Header.Set("User-Agent", "pr-reviewer/1")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&apiErr)
+		return &APIError{
+			StatusCode: resp.StatusCode,
+			Message:    fmt.Sprintf("%s %s: %s", method, path, apiErr.Message),
+			// GitHub reports exhausted and secondary rate limits with 403 or 429.
+			Retryable: resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests ||
+				resp.Header.Get("X-RateLimit-Remaining") == "0" || resp.Header.Get("Retry-After") != "",
+		}
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("decode response of %s %s: %w", method, path, err)
+		}
+	}
+	return nil
+}
+
+func startSpan(ctx context.Context, name string, ref PullRequestRef) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("codehost.provider", ref.Provider), attribute.Int64("codehost.repository_id", ref.RepoID),
+		attribute.Int("codehost.pull_request", ref.Number)))
+}
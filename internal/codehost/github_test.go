@@ -0,0 +1,168 @@
+package codehost
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeGitHub stands in for the parts of the GitHub REST API the client uses,
+// for the repository acme/backend with id 712345678.
+type fakeGitHub struct {
+	mu        sync.Mutex
+	requests  []string
+	reviewers map[string]bool
+	comments  []string
+	// status, when set, is returned by every request.
+	status int
+	header http.Header
+}
+
+func newFakeGitHub(t *testing.T) (*fakeGitHub, *GitHubClient) {
+	t.Helper()
+	f := &fakeGitHub{reviewers: make(map[string]bool)}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return f, NewGitHubClient(srv.URL+"/", "ghp_test", 5*time.Second)
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+
+	if r.Header.Get("Authorization") != "Bearer ghp_test" || r.Header.Get("X-GitHub-Api-Version") == "" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if f.status != 0 {
+		for k, v := range f.header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(f.status)
+		_ = json.NewEncoder(w).Encode(map[string]string{"message": http.StatusText(f.status)})
+		return
+	}
+
+	var body struct {
+		Reviewers []string `json:"reviewers"`
+		Body      string   `json:"body"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	switch r.Method + " " + r.URL.Path {
+	case "GET /repositories/712345678":
+		_ = json.NewEncoder(w).Encode(map[string]any{"id": 712345678, "full_name": "acme/backend"})
+	case "POST /repos/acme/backend/pulls/42/requested_reviewers":
+		for _, login := range body.Reviewers {
+			f.reviewers[login] = true
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"number":42}`))
+	case "DELETE /repos/acme/backend/pulls/42/requested_reviewers":
+		for _, login := range body.Reviewers {
+			delete(f.reviewers, login)
+		}
+		_, _ = w.Write([]byte(`{"number":42}`))
+	case "GET /repos/acme/backend/issues/42/comments":
+		if r.URL.Query().Get("since") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		list := []map[string]string{}
+		for _, c := range f.comments {
+			list = append(list, map[string]string{"body": c})
+		}
+		_ = json.NewEncoder(w).Encode(list)
+	case "POST /repos/acme/backend/issues/42/comments":
+		f.comments = append(f.comments, body.Body)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":1}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+	}
+}
+
+var testRef = PullRequestRef{Provider: "github", RepoID: 712345678, Number: 42}
+
+func TestGitHubClient_RequestsAndRemovesReviewers(t *testing.T) {
+	f, client := newFakeGitHub(t)
+	ctx := context.Background()
+
+	if err := client.RequestReviewers(ctx, testRef, []string{"bob", "carol"}); err != nil {
+		t.Fatalf("RequestReviewers failed: %v", err)
+	}
+	if err := client.RemoveRequestedReviewers(ctx, testRef, []string{"bob"}); err != nil {
+		t.Fatalf("RemoveRequestedReviewers failed: %v", err)
+	}
+
+	if len(f.reviewers) != 1 || !f.reviewers["carol"] {
+		t.Errorf("Expected only carol to be requested, got %v", f.reviewers)
+	}
+	lookups := 0
+	for _, r := range f.requests {
+		if r == "GET /repositories/712345678" {
+			lookups++
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("Expected the repository name to be looked up once, got %d lookups", lookups)
+	}
+}
+
+func TestGitHubClient_CommentsOncePerKey(t *testing.T) {
+	f, client := newFakeGitHub(t)
+	ctx := context.Background()
+	since := time.Now().Add(-time.Minute)
+
+	for range 2 {
+		if err := client.Comment(ctx, testRef, "17", since, "Reviewer @bob was replaced by @dave."); err != nil {
+			t.Fatalf("Comment failed: %v", err)
+		}
+	}
+	if err := client.Comment(ctx, testRef, "18", since, "Reviewer @dave was replaced by @erin."); err != nil {
+		t.Fatalf("Comment failed: %v", err)
+	}
+
+	if len(f.comments) != 2 {
+		t.Fatalf("Expected one comment per key, got %q", f.comments)
+	}
+	if !strings.HasPrefix(f.comments[0], "Reviewer @bob was replaced by @dave.") || !strings.Contains(f.comments[0], "<!-- pr-reviewer:17 -->") {
+		t.Errorf("Unexpected comment %q", f.comments[0])
+	}
+}
+
+func TestGitHubClient_ClassifiesErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		header    http.Header
+		retryable bool
+	}{
+		{"not a collaborator", http.StatusUnprocessableEntity, nil, false},
+		{"forbidden", http.StatusForbidden, nil, false},
+		{"rate limited", http.StatusForbidden, http.Header{"X-Ratelimit-Remaining": {"0"}}, true},
+		{"secondary rate limit", http.StatusTooManyRequests, http.Header{"Retry-After": {"60"}}, true},
+		{"outage", http.StatusBadGateway, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, client := newFakeGitHub(t)
+			f.status, f.header = tt.status, tt.header
+
+			err := client.RequestReviewers(context.Background(), testRef, []string{"bob"})
+			apiErr, ok := err.(*APIError)
+			if !ok {
+				t.Fatalf("Expected an APIError, got %v", err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.Retryable != tt.retryable {
+				t.Errorf("Expected %d retryable=%v, got %d retryable=%v", tt.status, tt.retryable, apiErr.StatusCode, apiErr.Retryable)
+			}
+		})
+	}
+}
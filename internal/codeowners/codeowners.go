@@ -0,0 +1,137 @@
+// Package codeowners parses CODEOWNERS-style rules and finds the owners of
+// file paths.
+//
+// Every line holds a pattern followed by its owners, separated by spaces;
+// blank lines and lines starting with # are skipped. The last rule matching a
+// path decides its owners, so a rule without owners leaves the paths it
+// matches unowned. Patterns follow the syntax of GitHub's CODEOWNERS files:
+//
+//	*.go          files with the extension in any directory
+//	docs/         everything under any directory named docs
+//	/docs/        everything under the top-level docs directory
+//	/docs/*       files directly in the top-level docs directory
+//	api/**/*.yaml files with the extension at any depth under api
+//
+// A pattern containing a slash other than a trailing one is relative to the
+// repository root; a pattern naming a directory also matches everything under
+// it. Owners are user ids, or team names prefixed with @.
+package codeowners
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MaxRules bounds the number of rules in one file.
+const MaxRules = 1000
+
+// Rule assigns the paths matching Pattern to Owners.
+type Rule struct {
+	Pattern string
+	Owners  []string
+	Line    int
+	re      *regexp.Regexp
+}
+
+// Ruleset is a parsed rules file in file order.
+type Ruleset []Rule
+
+// Parse parses a rules file; errors name the offending line.
+func Parse(content string) (Ruleset, error) {
+	var rules Ruleset
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// Trailing comments are allowed after the owners.
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		re, err := compile(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		for _, owner := range fields[1:] {
+			if owner == "@" {
+				return nil, fmt.Errorf("line %d: owner %q has no team name", n, owner)
+			}
+		}
+		if len(rules) == MaxRules {
+			return nil, fmt.Errorf("line %d: more than %d rules", n, MaxRules)
+		}
+		rules = append(rules, Rule{Pattern: fields[0], Owners: fields[1:], Line: n, re: re})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Owners returns the owners of path, a slash-separated path relative to the
+// repository root, from the last rule matching it.
+func (rs Ruleset) Owners(path string) []string {
+	path = Clean(path)
+	for i := len(rs) - 1; i >= 0; i-- {
+		if rs[i].re.MatchString(path) {
+			return rs[i].Owners
+		}
+	}
+	return nil
+}
+
+// Clean strips the leading "/" or "./" with which paths are sometimes given.
+func Clean(path string) string {
+	return strings.TrimLeft(strings.TrimPrefix(path, "./"), "/")
+}
+
+func compile(pattern string) (*regexp.Regexp, error) {
+	if strings.HasPrefix(pattern, "!") || strings.ContainsAny(pattern, `\[]`) {
+		return nil, fmt.Errorf("pattern %q: negation, escapes and character ranges are not supported", pattern)
+	}
+
+	p := pattern
+	dir := strings.HasSuffix(p, "/")
+	p = strings.TrimSuffix(p, "/")
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+	if p == "" {
+		return nil, fmt.Errorf("pattern %q matches nothing", pattern)
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	if !anchored {
+		expr.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(p); i++ {
+		switch {
+		case strings.HasPrefix(p[i:], "**/"):
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case p[i] == '*':
+			expr.WriteString("[^/]*")
+		case p[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		}
+	}
+	switch {
+	case dir:
+		expr.WriteString("/.*")
+	case !strings.HasSuffix(p, "/*"):
+		// A pattern naming a directory owns its contents; "dir/*" only owns
+		// the files directly in dir.
+		expr.WriteString("(?:/.*)?")
+	}
+	expr.WriteString("$")
+	return regexp.Compile(expr.String())
+}
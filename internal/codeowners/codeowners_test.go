@@ -0,0 +1,63 @@
+package codeowners
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestOwners(t *testing.T) {
+	rules, err := Parse(`
+# Default owners
+*                 u1
+
+*.go              u2 @backend
+docs/             u3
+/build/logs/      u4
+/scripts/*        u5
+api/**/*.yaml     u6
+cmd               u7   # the entry points
+/vendor/          # nobody owns vendored code
+`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	tests := []struct {
+		path   string
+		owners []string
+	}{
+		{"README.md", []string{"u1"}},
+		{"internal/service/service.go", []string{"u2", "@backend"}},
+		{"./main.go", []string{"u2", "@backend"}},
+		{"docs/setup.md", []string{"u3"}},
+		{"internal/docs/notes.md", []string{"u3"}},
+		{"build/logs/run.log", []string{"u4"}},
+		{"ci/build/logs/run.log", []string{"u1"}},
+		{"scripts/lint.sh", []string{"u5"}},
+		{"scripts/ci/lint.sh", []string{"u1"}},
+		{"api/openapi.yaml", []string{"u6"}},
+		{"api/v1/users/schema.yaml", []string{"u6"}},
+		{"cmd/main.go", []string{"u7"}},
+		{"vendor/github.com/lib/pq/conn.go", nil},
+	}
+	for _, tt := range tests {
+		if got := rules.Owners(tt.path); !slices.Equal(got, tt.owners) {
+			t.Errorf("%s: expected %v, got %v", tt.path, tt.owners, got)
+		}
+	}
+}
+
+func TestParse_RejectsUnsupportedSyntax(t *testing.T) {
+	for _, content := range []string{
+		"*.go u1\n!generated.go u2",
+		"[abc].go u1",
+		"*.go @",
+		"/ u1",
+	} {
+		_, err := Parse(content)
+		if err == nil || !strings.HasPrefix(err.Error(), "line ") {
+			t.Errorf("%q: expected an error naming the line, got %v", content, err)
+		}
+	}
+}
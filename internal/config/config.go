@@ -0,0 +1,751 @@
+// Package config loads service settings from a YAML or JSON file, environment
+// variables and command-line flags, in increasing order of precedence.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"Backend/internal/tracing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Accepted values of reviewers.strategy and outbox.sinks; the caller maps
+// them to the service and outbox implementations.
+var (
+	reviewerStrategies = []string{"random", "expertise"}
+	outboxSinks        = []string{"webhook", "log", "file"}
+)
+
+type Config struct {
+	Server       ServerConfig       `yaml:"server"`
+	Storage      StorageConfig      `yaml:"storage"`
+	Reviewers    ReviewersConfig    `yaml:"reviewers"`
+	Idempotency  IdempotencyConfig  `yaml:"idempotency"`
+	Log          LogConfig          `yaml:"log"`
+	Metrics      MetricsConfig      `yaml:"metrics"`
+	Tracing      TracingConfig      `yaml:"tracing"`
+	Auth         AuthConfig         `yaml:"auth"`
+	RateLimit    RateLimitConfig    `yaml:"rate_limit"`
+	Webhooks     WebhooksConfig     `yaml:"webhooks"`
+	Outbox       OutboxConfig       `yaml:"outbox"`
+	Stream       StreamConfig       `yaml:"stream"`
+	Integrations IntegrationsConfig `yaml:"integrations"`
+}
+
+type ServerConfig struct {
+	ListenAddr        string        `yaml:"listen_addr"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	RequestTimeout    time.Duration `yaml:"request_timeout"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"`
+	// ShutdownDelay keeps serving with /readyz failing before draining starts,
+	// giving load balancers time to take the instance out of rotation.
+	ShutdownDelay    time.Duration `yaml:"shutdown_delay"`
+	ReadinessTimeout time.Duration `yaml:"readiness_timeout"`
+}
+
+type StorageConfig struct {
+	// Driver is one of postgres, sqlite or memory.
+	Driver   string         `yaml:"driver"`
+	Postgres PostgresConfig `yaml:"postgres"`
+	SQLite   SQLiteConfig   `yaml:"sqlite"`
+}
+
+type PostgresConfig struct {
+	// DSN, when set, is used as is instead of the individual connection fields.
+	DSN             string        `yaml:"dsn"`
+	Host            string        `yaml:"host"`
+	Port            int           `yaml:"port"`
+	User            string        `yaml:"user"`
+	Password        string        `yaml:"password"`
+	Name            string        `yaml:"name"`
+	SSLMode         string        `yaml:"sslmode"`
+	ConnectTimeout  time.Duration `yaml:"connect_timeout"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+}
+
+type SQLiteConfig struct {
+	Path string `yaml:"path"`
+}
+
+type ReviewersConfig struct {
+	Count    int    `yaml:"count"`
+	Strategy string `yaml:"strategy"`
+	// ExpertiseWindow and ExpertiseHalfLife tune the expertise scores: reviews
+	// older than the window are forgotten, and a review counts half as much
+	// after a half-life.
+	ExpertiseWindow   time.Duration `yaml:"expertise_window"`
+	ExpertiseHalfLife time.Duration `yaml:"expertise_half_life"`
+	// ExpertiseRefresh is how long the scores and open review counts are
+	// reused when picking reviewers.
+	ExpertiseRefresh time.Duration `yaml:"expertise_refresh"`
+}
+
+type IdempotencyConfig struct {
+	TTL             time.Duration `yaml:"ttl"`
+	CleanupInterval time.Duration `yaml:"cleanup_interval"`
+}
+
+type LogConfig struct {
+	// Level is one of debug, info, warn or error.
+	Level string `yaml:"level"`
+	// Format is json or text.
+	Format string `yaml:"format"`
+}
+
+type MetricsConfig struct {
+	// StatsTimeout bounds the review statistics query run on every scrape.
+	StatsTimeout time.Duration `yaml:"stats_timeout"`
+}
+
+type TracingConfig struct {
+	// Exporter is one of none, stdout or otlp-file.
+	Exporter string `yaml:"exporter"`
+	// File receives OTLP/JSON lines when Exporter is otlp-file.
+	File        string `yaml:"file"`
+	ServiceName string `yaml:"service_name"`
+}
+
+type AuthConfig struct {
+	// Enabled requires a bearer token with a suitable role on every API route.
+	Enabled bool `yaml:"enabled"`
+	// BootstrapToken, when set, is registered at startup as an admin token so
+	// that the first tokens can be issued through /admin/tokens.
+	BootstrapToken string    `yaml:"bootstrap_token"`
+	JWT            JWTConfig `yaml:"jwt"`
+}
+
+// JWTConfig enables JWT bearer tokens when a JWKS file or URL is set.
+type JWTConfig struct {
+	JWKSFile string `yaml:"jwks_file"`
+	JWKSURL  string `yaml:"jwks_url"`
+	// RefreshInterval is the minimum time between JWKS reloads triggered by
+	// tokens signed with an unknown key id.
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+	Issuer          string        `yaml:"issuer"`
+	Audience        string        `yaml:"audience"`
+	UserClaim       string        `yaml:"user_claim"`
+	RolesClaim      string        `yaml:"roles_claim"`
+	Leeway          time.Duration `yaml:"leeway"`
+}
+
+type RateLimitConfig struct {
+	// Enabled gives every caller a token bucket per API route.
+	Enabled bool `yaml:"enabled"`
+	// PerClient limits all API requests from one client address, checked
+	// before authentication so that invalid credentials are limited too.
+	PerClient RateLimitRule `yaml:"per_client"`
+	// Default applies to routes without an entry in Routes.
+	Default RateLimitRule `yaml:"default"`
+	// Routes overrides Default by route path template, e.g. /pullRequest/create.
+	Routes map[string]RateLimitRule `yaml:"routes"`
+	// ClientIPHeader names a header, such as X-Real-IP, set by a trusted proxy
+	// in front of the service. Without it the connection address is used.
+	ClientIPHeader string `yaml:"client_ip_header"`
+	// IdleTimeout is how long the bucket of an idle caller is kept.
+	IdleTimeout time.Duration `yaml:"idle_timeout"`
+}
+
+// RateLimitRule allows RPS requests per second on average and bursts of up
+// to Burst requests. A zero RPS disables the limit.
+type RateLimitRule struct {
+	RPS   float64 `yaml:"rps"`
+	Burst int     `yaml:"burst"`
+}
+
+type WebhooksConfig struct {
+	// Enabled runs the delivery worker; subscriptions can be managed either way.
+	Enabled bool `yaml:"enabled"`
+	// MaxAttempts is the number of attempts after which a delivery is moved to
+	// the dead-letter list.
+	MaxAttempts int `yaml:"max_attempts"`
+	// InitialBackoff is the delay before the first retry; it doubles with every
+	// further attempt up to MaxBackoff.
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+	PollInterval   time.Duration `yaml:"poll_interval"`
+	Timeout        time.Duration `yaml:"timeout"`
+	BatchSize      int           `yaml:"batch_size"`
+	// Retention is how long delivered deliveries are kept; 0 keeps them forever.
+	Retention time.Duration `yaml:"retention"`
+}
+
+type OutboxConfig struct {
+	// Sinks are the destinations every recorded event is published to: webhook,
+	// log and file.
+	Sinks []string `yaml:"sinks"`
+	// File is the file the file sink appends events to, one JSON object per line.
+	File         string        `yaml:"file"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+	BatchSize    int           `yaml:"batch_size"`
+	// InitialBackoff is the delay before the first retry of an event; it
+	// doubles with every further attempt up to MaxBackoff.
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+	// Retention is how long published events are kept; 0 keeps them forever.
+	Retention time.Duration `yaml:"retention"`
+}
+
+type StreamConfig struct {
+	// BufferSize is how many recent events are kept for clients resuming with
+	// Last-Event-ID.
+	BufferSize int `yaml:"buffer_size"`
+	// KeepAlive is how often idle streams receive a comment line.
+	KeepAlive time.Duration `yaml:"keep_alive"`
+}
+
+type IntegrationsConfig struct {
+	GitHub GitHubConfig `yaml:"github"`
+	GitLab GitLabConfig `yaml:"gitlab"`
+}
+
+type GitHubConfig struct {
+	// WebhookSecret is the secret of the GitHub webhook; the webhook endpoint
+	// is disabled while it is empty.
+	WebhookSecret string `yaml:"webhook_secret"`
+	// Token authenticates reviewer requests and comments on pull requests
+	// opened by the webhook; they are not sent while it is empty.
+	Token      string        `yaml:"token"`
+	APIURL     string        `yaml:"api_url"`
+	APITimeout time.Duration `yaml:"api_timeout"`
+}
+
+type GitLabConfig struct {
+	// WebhookToken is the secret token of the GitLab webhook; the webhook
+	// endpoint is disabled while it is empty.
+	WebhookToken string `yaml:"webhook_token"`
+}
+
+func (j JWTConfig) Enabled() bool {
+	return j.JWKSFile != "" || j.JWKSURL != ""
+}
+
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			ListenAddr:        ":8080",
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       15 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			RequestTimeout:    10 * time.Second,
+			ShutdownTimeout:   15 * time.Second,
+			ReadinessTimeout:  2 * time.Second,
+		},
+		Storage: StorageConfig{
+			Driver: "postgres",
+			Postgres: PostgresConfig{
+				Port:            5432,
+				SSLMode:         "disable",
+				ConnectTimeout:  5 * time.Second,
+				MaxOpenConns:    20,
+				MaxIdleConns:    10,
+				ConnMaxLifetime: 30 * time.Minute,
+				ConnMaxIdleTime: 5 * time.Minute,
+			},
+			SQLite: SQLiteConfig{Path: "reviewers.db"},
+		},
+		Reviewers: ReviewersConfig{
+			Count:             2,
+			Strategy:          "random",
+			ExpertiseWindow:   180 * 24 * time.Hour,
+			ExpertiseHalfLife: 30 * 24 * time.Hour,
+			ExpertiseRefresh:  5 * time.Minute,
+		},
+		Idempotency: IdempotencyConfig{
+			TTL:             24 * time.Hour,
+			CleanupInterval: time.Hour,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Metrics: MetricsConfig{
+			StatsTimeout: 5 * time.Second,
+		},
+		Tracing: TracingConfig{
+			Exporter:    tracing.ExporterNone,
+			File:        "traces.jsonl",
+			ServiceName: "pr-reviewer",
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:   true,
+			PerClient: RateLimitRule{RPS: 100, Burst: 200},
+			Default:   RateLimitRule{RPS: 50, Burst: 100},
+			Routes: map[string]RateLimitRule{
+				"/pullRequest/create": {RPS: 5, Burst: 20},
+			},
+			IdleTimeout: 10 * time.Minute,
+		},
+		Webhooks: WebhooksConfig{
+			Enabled:        true,
+			MaxAttempts:    8,
+			InitialBackoff: 10 * time.Second,
+			MaxBackoff:     time.Hour,
+			PollInterval:   2 * time.Second,
+			Timeout:        10 * time.Second,
+			BatchSize:      20,
+			Retention:      7 * 24 * time.Hour,
+		},
+		Outbox: OutboxConfig{
+			Sinks:          []string{"webhook"},
+			File:           "events.jsonl",
+			PollInterval:   time.Second,
+			BatchSize:      100,
+			InitialBackoff: time.Second,
+			MaxBackoff:     5 * time.Minute,
+			Retention:      7 * 24 * time.Hour,
+		},
+		Stream: StreamConfig{
+			BufferSize: 1000,
+			KeepAlive:  15 * time.Second,
+		},
+		Integrations: IntegrationsConfig{
+			GitHub: GitHubConfig{
+				APIURL:     "https://api.github.com",
+				APITimeout: 10 * time.Second,
+			},
+		},
+		Auth: AuthConfig{
+			JWT: JWTConfig{
+				RefreshInterval: 5 * time.Minute,
+				UserClaim:       "sub",
+				RolesClaim:      "roles",
+				Leeway:          30 * time.Second,
+			},
+		},
+	}
+}
+
+// option binds one setting to its environment variable and command-line flag.
+type option struct {
+	flag  string
+	env   string
+	usage string
+	set   func(c *Config, value string) error
+}
+
+var options = []option{
+	{"listen-addr", "HTTP_ADDR", "HTTP listen address", str(func(c *Config) *string { return &c.Server.ListenAddr })},
+	{"read-header-timeout", "HTTP_READ_HEADER_TIMEOUT", "time allowed to read request headers", dur(func(c *Config) *time.Duration { return &c.Server.ReadHeaderTimeout })},
+	{"read-timeout", "HTTP_READ_TIMEOUT", "time allowed to read a whole request", dur(func(c *Config) *time.Duration { return &c.Server.ReadTimeout })},
+	{"write-timeout", "HTTP_WRITE_TIMEOUT", "time allowed to write a response", dur(func(c *Config) *time.Duration { return &c.Server.WriteTimeout })},
+	{"idle-timeout", "HTTP_IDLE_TIMEOUT", "keep-alive idle timeout", dur(func(c *Config) *time.Duration { return &c.Server.IdleTimeout })},
+	{"request-timeout", "REQUEST_TIMEOUT", "deadline for handling a single request", dur(func(c *Config) *time.Duration { return &c.Server.RequestTimeout })},
+	{"shutdown-timeout", "SHUTDOWN_TIMEOUT", "time allowed to drain connections on shutdown", dur(func(c *Config) *time.Duration { return &c.Server.ShutdownTimeout })},
+	{"shutdown-delay", "SHUTDOWN_DELAY", "time to keep serving with failing readiness before draining", dur(func(c *Config) *time.Duration { return &c.Server.ShutdownDelay })},
+	{"readiness-timeout", "READINESS_TIMEOUT", "timeout of the /readyz dependency checks", dur(func(c *Config) *time.Duration { return &c.Server.ReadinessTimeout })},
+
+	{"storage", "STORAGE", "storage driver: postgres, sqlite or memory", str(func(c *Config) *string { return &c.Storage.Driver })},
+	{"db-dsn", "DB_DSN", "PostgreSQL connection string, overrides the individual DB settings", str(func(c *Config) *string { return &c.Storage.Postgres.DSN })},
+	{"db-host", "DB_HOST", "PostgreSQL host", str(func(c *Config) *string { return &c.Storage.Postgres.Host })},
+	{"db-port", "DB_PORT", "PostgreSQL port", integer(func(c *Config) *int { return &c.Storage.Postgres.Port })},
+	{"db-user", "DB_USER", "PostgreSQL user", str(func(c *Config) *string { return &c.Storage.Postgres.User })},
+	{"db-password", "DB_PASSWORD", "PostgreSQL password", str(func(c *Config) *string { return &c.Storage.Postgres.Password })},
+	{"db-name", "DB_NAME", "PostgreSQL database name", str(func(c *Config) *string { return &c.Storage.Postgres.Name })},
+	{"db-sslmode", "DB_SSLMODE", "PostgreSQL SSL mode", str(func(c *Config) *string { return &c.Storage.Postgres.SSLMode })},
+	{"db-connect-timeout", "DB_CONNECT_TIMEOUT", "timeout for the startup database ping", dur(func(c *Config) *time.Duration { return &c.Storage.Postgres.ConnectTimeout })},
+	{"db-max-open-conns", "DB_MAX_OPEN_CONNS", "maximum open database connections (0 is unlimited)", integer(func(c *Config) *int { return &c.Storage.Postgres.MaxOpenConns })},
+	{"db-max-idle-conns", "DB_MAX_IDLE_CONNS", "maximum idle database connections", integer(func(c *Config) *int { return &c.Storage.Postgres.MaxIdleConns })},
+	{"db-conn-max-lifetime", "DB_CONN_MAX_LIFETIME", "maximum lifetime of a database connection", dur(func(c *Config) *time.Duration { return &c.Storage.Postgres.ConnMaxLifetime })},
+	{"db-conn-max-idle-time", "DB_CONN_MAX_IDLE_TIME", "maximum idle time of a database connection", dur(func(c *Config) *time.Duration { return &c.Storage.Postgres.ConnMaxIdleTime })},
+	{"sqlite-path", "SQLITE_PATH", "SQLite database file", str(func(c *Config) *string { return &c.Storage.SQLite.Path })},
+
+	{"reviewer-count", "REVIEWER_COUNT", "number of reviewers assigned to a new pull request", integer(func(c *Config) *int { return &c.Reviewers.Count })},
+	{"reviewer-strategy", "REVIEWER_STRATEGY", "reviewer selection strategy: random or expertise", str(func(c *Config) *string { return &c.Reviewers.Strategy })},
+	{"reviewer-expertise-window", "REVIEWER_EXPERTISE_WINDOW", "age of the reviews expertise is learnt from", dur(func(c *Config) *time.Duration { return &c.Reviewers.ExpertiseWindow })},
+	{"reviewer-expertise-half-life", "REVIEWER_EXPERTISE_HALF_LIFE", "age at which a review counts half towards expertise", dur(func(c *Config) *time.Duration { return &c.Reviewers.ExpertiseHalfLife })},
+	{"reviewer-expertise-refresh", "REVIEWER_EXPERTISE_REFRESH", "how long expertise scores and review load are reused when picking reviewers", dur(func(c *Config) *time.Duration { return &c.Reviewers.ExpertiseRefresh })},
+
+	{"idempotency-ttl", "IDEMPOTENCY_TTL", "how long idempotent responses are kept", dur(func(c *Config) *time.Duration { return &c.Idempotency.TTL })},
+	{"idempotency-cleanup-interval", "IDEMPOTENCY_CLEANUP_INTERVAL", "how often expired idempotency keys are purged", dur(func(c *Config) *time.Duration { return &c.Idempotency.CleanupInterval })},
+
+	{"log-level", "LOG_LEVEL", "log level: debug, info, warn or error", str(func(c *Config) *string { return &c.Log.Level })},
+	{"log-format", "LOG_FORMAT", "log format: json or text", str(func(c *Config) *string { return &c.Log.Format })},
+
+	{"tracing-exporter", "TRACING_EXPORTER", "trace exporter: none, stdout or otlp-file", str(func(c *Config) *string { return &c.Tracing.Exporter })},
+	{"tracing-file", "TRACING_FILE", "file written by the otlp-file trace exporter", str(func(c *Config) *string { return &c.Tracing.File })},
+	{"tracing-service-name", "TRACING_SERVICE_NAME", "service.name reported in traces", str(func(c *Config) *string { return &c.Tracing.ServiceName })},
+
+	{"auth-enabled", "AUTH_ENABLED", "require bearer tokens on API routes (true or false)", boolean(func(c *Config) *bool { return &c.Auth.Enabled })},
+	{"auth-bootstrap-token", "AUTH_BOOTSTRAP_TOKEN", "admin token registered at startup", str(func(c *Config) *string { return &c.Auth.BootstrapToken })},
+	{"jwt-jwks-file", "JWT_JWKS_FILE", "JWKS file with the keys JWT bearer tokens are signed with", str(func(c *Config) *string { return &c.Auth.JWT.JWKSFile })},
+	{"jwt-jwks-url", "JWT_JWKS_URL", "URL of the JWKS JWT bearer tokens are signed with", str(func(c *Config) *string { return &c.Auth.JWT.JWKSURL })},
+	{"jwt-refresh-interval", "JWT_REFRESH_INTERVAL", "minimum time between JWKS reloads", dur(func(c *Config) *time.Duration { return &c.Auth.JWT.RefreshInterval })},
+	{"jwt-issuer", "JWT_ISSUER", "required iss claim of JWTs", str(func(c *Config) *string { return &c.Auth.JWT.Issuer })},
+	{"jwt-audience", "JWT_AUDIENCE", "required aud claim of JWTs", str(func(c *Config) *string { return &c.Auth.JWT.Audience })},
+	{"jwt-user-claim", "JWT_USER_CLAIM", "JWT claim holding the user id", str(func(c *Config) *string { return &c.Auth.JWT.UserClaim })},
+	{"jwt-roles-claim", "JWT_ROLES_CLAIM", "JWT claim holding the roles, dots reach into nested objects", str(func(c *Config) *string { return &c.Auth.JWT.RolesClaim })},
+	{"jwt-leeway", "JWT_LEEWAY", "clock skew tolerated when checking JWT times", dur(func(c *Config) *time.Duration { return &c.Auth.JWT.Leeway })},
+
+	{"rate-limit-enabled", "RATE_LIMIT_ENABLED", "limit request rates per caller (true or false)", boolean(func(c *Config) *bool { return &c.RateLimit.Enabled })},
+	{"rate-limit-per-client-rps", "RATE_LIMIT_PER_CLIENT_RPS", "requests per second per client address across all routes, 0 is unlimited", float(func(c *Config) *float64 { return &c.RateLimit.PerClient.RPS })},
+	{"rate-limit-per-client-burst", "RATE_LIMIT_PER_CLIENT_BURST", "burst size per client address across all routes", integer(func(c *Config) *int { return &c.RateLimit.PerClient.Burst })},
+	{"rate-limit-rps", "RATE_LIMIT_RPS", "default requests per second per caller and route, 0 is unlimited", float(func(c *Config) *float64 { return &c.RateLimit.Default.RPS })},
+	{"rate-limit-burst", "RATE_LIMIT_BURST", "default burst size per caller and route", integer(func(c *Config) *int { return &c.RateLimit.Default.Burst })},
+	{"rate-limit-routes", "RATE_LIMIT_ROUTES", "per-route limits as route=rps:burst, comma separated; replaces the configured routes", rateLimitRoutes},
+	{"rate-limit-client-ip-header", "RATE_LIMIT_CLIENT_IP_HEADER", "header with the client address set by a trusted proxy", str(func(c *Config) *string { return &c.RateLimit.ClientIPHeader })},
+	{"rate-limit-idle-timeout", "RATE_LIMIT_IDLE_TIMEOUT", "how long idle callers' buckets are kept", dur(func(c *Config) *time.Duration { return &c.RateLimit.IdleTimeout })},
+	{"webhooks-enabled", "WEBHOOKS_ENABLED", "run the webhook delivery worker (true or false)", boolean(func(c *Config) *bool { return &c.Webhooks.Enabled })},
+	{"webhooks-max-attempts", "WEBHOOKS_MAX_ATTEMPTS", "delivery attempts before a webhook is dead-lettered", integer(func(c *Config) *int { return &c.Webhooks.MaxAttempts })},
+	{"webhooks-initial-backoff", "WEBHOOKS_INITIAL_BACKOFF", "delay before the first webhook retry, doubled on every further attempt", dur(func(c *Config) *time.Duration { return &c.Webhooks.InitialBackoff })},
+	{"webhooks-max-backoff", "WEBHOOKS_MAX_BACKOFF", "maximum delay between webhook retries", dur(func(c *Config) *time.Duration { return &c.Webhooks.MaxBackoff })},
+	{"webhooks-poll-interval", "WEBHOOKS_POLL_INTERVAL", "how often due webhook deliveries are checked", dur(func(c *Config) *time.Duration { return &c.Webhooks.PollInterval })},
+	{"webhooks-timeout", "WEBHOOKS_TIMEOUT", "timeout of a single webhook request", dur(func(c *Config) *time.Duration { return &c.Webhooks.Timeout })},
+	{"webhooks-batch-size", "WEBHOOKS_BATCH_SIZE", "webhook deliveries sent concurrently", integer(func(c *Config) *int { return &c.Webhooks.BatchSize })},
+	{"webhooks-retention", "WEBHOOKS_RETENTION", "how long delivered webhooks are kept, 0 keeps them forever", dur(func(c *Config) *time.Duration { return &c.Webhooks.Retention })},
+
+	{"outbox-sinks", "OUTBOX_SINKS", "sinks events are published to: webhook, log, file, comma separated", list(func(c *Config) *[]string { return &c.Outbox.Sinks })},
+	{"outbox-file", "OUTBOX_FILE", "file the file sink appends events to", str(func(c *Config) *string { return &c.Outbox.File })},
+	{"outbox-poll-interval", "OUTBOX_POLL_INTERVAL", "how often unpublished events are checked", dur(func(c *Config) *time.Duration { return &c.Outbox.PollInterval })},
+	{"outbox-batch-size", "OUTBOX_BATCH_SIZE", "events claimed at once", integer(func(c *Config) *int { return &c.Outbox.BatchSize })},
+	{"outbox-initial-backoff", "OUTBOX_INITIAL_BACKOFF", "delay before the first retry of an event, doubled on every further attempt", dur(func(c *Config) *time.Duration { return &c.Outbox.InitialBackoff })},
+	{"outbox-max-backoff", "OUTBOX_MAX_BACKOFF", "maximum delay between retries of an event", dur(func(c *Config) *time.Duration { return &c.Outbox.MaxBackoff })},
+	{"outbox-retention", "OUTBOX_RETENTION", "how long published events are kept, 0 keeps them forever", dur(func(c *Config) *time.Duration { return &c.Outbox.Retention })},
+
+	{"stream-buffer-size", "STREAM_BUFFER_SIZE", "recent events kept for event stream clients resuming with Last-Event-ID", integer(func(c *Config) *int { return &c.Stream.BufferSize })},
+	{"stream-keep-alive", "STREAM_KEEP_ALIVE", "how often idle event streams receive a keep-alive comment", dur(func(c *Config) *time.Duration { return &c.Stream.KeepAlive })},
+
+	{"github-webhook-secret", "GITHUB_WEBHOOK_SECRET", "secret of the GitHub webhook, enables /integrations/github/webhook", str(func(c *Config) *string { return &c.Integrations.GitHub.WebhookSecret })},
+	{"github-token", "GITHUB_TOKEN", "GitHub token reviewers are requested on pull requests with", str(func(c *Config) *string { return &c.Integrations.GitHub.Token })},
+	{"github-api-url", "GITHUB_API_URL", "base URL of the GitHub REST API", str(func(c *Config) *string { return &c.Integrations.GitHub.APIURL })},
+	{"github-api-timeout", "GITHUB_API_TIMEOUT", "timeout of a GitHub API request", dur(func(c *Config) *time.Duration { return &c.Integrations.GitHub.APITimeout })},
+	{"gitlab-webhook-token", "GITLAB_WEBHOOK_TOKEN", "secret token of the GitLab webhook, enables /integrations/gitlab/webhook", str(func(c *Config) *string { return &c.Integrations.GitLab.WebhookToken })},
+
+	{"metrics-stats-timeout", "METRICS_STATS_TIMEOUT", "timeout of the review statistics query on /metrics scrapes", dur(func(c *Config) *time.Duration { return &c.Metrics.StatsTimeout })},
+}
+
+// Load builds the configuration from defaults, the file named by -config or
+// CONFIG_FILE, environment variables and args, then validates it. All problems
+// found are reported together.
+func Load(args []string, lookupEnv func(string) (string, bool)) (Config, error) {
+	fs := flag.NewFlagSet("reviewer-service", flag.ContinueOnError)
+	configFile := fs.String("config", "", "path to a YAML or JSON configuration file (env CONFIG_FILE)")
+	flagValues := make(map[string]*string, len(options))
+	for _, opt := range options {
+		flagValues[opt.flag] = fs.String(opt.flag, "", fmt.Sprintf("%s (env %s)", opt.usage, opt.env))
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	cfg := Default()
+	var errs []error
+
+	path := *configFile
+	if path == "" {
+		path, _ = lookupEnv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+
+	for _, opt := range options {
+		if value, ok := lookupEnv(opt.env); ok && value != "" {
+			if err := opt.set(&cfg, value); err != nil {
+				errs = append(errs, fmt.Errorf("env %s: %w", opt.env, err))
+			}
+		}
+	}
+
+	setFlags := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+	for _, opt := range options {
+		if setFlags[opt.flag] {
+			if err := opt.set(&cfg, *flagValues[opt.flag]); err != nil {
+				errs = append(errs, fmt.Errorf("flag -%s: %w", opt.flag, err))
+			}
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return Config{}, errors.Join(errs...)
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	// YAML is a superset of JSON, so the same decoder handles both formats.
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	if _, _, err := net.SplitHostPort(c.Server.ListenAddr); err != nil {
+		errs = append(errs, fmt.Errorf("server.listen_addr %q is invalid: %w", c.Server.ListenAddr, err))
+	}
+	check(c.Server.ReadHeaderTimeout >= 0, "server.read_header_timeout must not be negative")
+	check(c.Server.ReadTimeout >= 0, "server.read_timeout must not be negative")
+	check(c.Server.WriteTimeout >= 0, "server.write_timeout must not be negative")
+	check(c.Server.IdleTimeout >= 0, "server.idle_timeout must not be negative")
+	check(c.Server.RequestTimeout > 0, "server.request_timeout must be positive")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	check(c.Server.ShutdownDelay >= 0, "server.shutdown_delay must not be negative")
+	check(c.Server.ReadinessTimeout > 0, "server.readiness_timeout must be positive")
+
+	switch c.Storage.Driver {
+	case "postgres":
+		pg := c.Storage.Postgres
+		if pg.DSN == "" {
+			check(pg.Host != "", "storage.postgres.host is required (env DB_HOST)")
+			check(pg.Name != "", "storage.postgres.name is required (env DB_NAME)")
+			check(pg.Port > 0 && pg.Port <= 65535, "storage.postgres.port %d is out of range", pg.Port)
+			check(slices.Contains(sslModes, pg.SSLMode), "storage.postgres.sslmode %q must be one of %s", pg.SSLMode, strings.Join(sslModes, ", "))
+		}
+		check(pg.ConnectTimeout > 0, "storage.postgres.connect_timeout must be positive")
+		check(pg.MaxOpenConns >= 0, "storage.postgres.max_open_conns must not be negative")
+		check(pg.MaxIdleConns >= 0, "storage.postgres.max_idle_conns must not be negative")
+		check(pg.MaxOpenConns == 0 || pg.MaxIdleConns <= pg.MaxOpenConns,
+			"storage.postgres.max_idle_conns (%d) must not exceed max_open_conns (%d)", pg.MaxIdleConns, pg.MaxOpenConns)
+		check(pg.ConnMaxLifetime >= 0, "storage.postgres.conn_max_lifetime must not be negative")
+		check(pg.ConnMaxIdleTime >= 0, "storage.postgres.conn_max_idle_time must not be negative")
+	case "sqlite":
+		check(c.Storage.SQLite.Path != "", "storage.sqlite.path is required")
+	case "memory":
+	default:
+		errs = append(errs, fmt.Errorf("storage.driver %q must be one of postgres, sqlite, memory", c.Storage.Driver))
+	}
+
+	check(c.Reviewers.Count >= 1 && c.Reviewers.Count <= 10, "reviewers.count %d must be between 1 and 10", c.Reviewers.Count)
+	check(slices.Contains(reviewerStrategies, c.Reviewers.Strategy),
+		"reviewers.strategy %q must be one of %s", c.Reviewers.Strategy, strings.Join(reviewerStrategies, ", "))
+	check(c.Reviewers.ExpertiseWindow > 0, "reviewers.expertise_window must be positive")
+	check(c.Reviewers.ExpertiseHalfLife > 0, "reviewers.expertise_half_life must be positive")
+	check(c.Reviewers.ExpertiseRefresh > 0, "reviewers.expertise_refresh must be positive")
+
+	check(c.Idempotency.TTL > 0, "idempotency.ttl must be positive")
+	check(c.Idempotency.CleanupInterval > 0, "idempotency.cleanup_interval must be positive")
+
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.Log.Level)) == nil, "log.level %q must be one of debug, info, warn, error", c.Log.Level)
+	check(c.Log.Format == "json" || c.Log.Format == "text", "log.format %q must be json or text", c.Log.Format)
+
+	if c.RateLimit.Enabled {
+		checkRule := func(name string, rule RateLimitRule) {
+			check(rule.RPS >= 0, "%s.rps must not be negative", name)
+			check(rule.RPS == 0 || rule.Burst >= 1, "%s.burst must be at least 1", name)
+		}
+		checkRule("rate_limit.per_client", c.RateLimit.PerClient)
+		checkRule("rate_limit.default", c.RateLimit.Default)
+		for route, rule := range c.RateLimit.Routes {
+			check(strings.HasPrefix(route, "/"), "rate_limit.routes key %q must be a route path starting with /", route)
+			checkRule(fmt.Sprintf("rate_limit.routes[%s]", route), rule)
+		}
+		check(c.RateLimit.IdleTimeout > 0, "rate_limit.idle_timeout must be positive")
+	}
+
+	if c.Webhooks.Enabled {
+		check(c.Webhooks.MaxAttempts >= 1, "webhooks.max_attempts must be at least 1")
+		check(c.Webhooks.InitialBackoff > 0, "webhooks.initial_backoff must be positive")
+		check(c.Webhooks.MaxBackoff >= c.Webhooks.InitialBackoff, "webhooks.max_backoff must not be less than webhooks.initial_backoff")
+		check(c.Webhooks.PollInterval > 0, "webhooks.poll_interval must be positive")
+		check(c.Webhooks.Timeout > 0, "webhooks.timeout must be positive")
+		check(c.Webhooks.BatchSize >= 1 && c.Webhooks.BatchSize <= 100, "webhooks.batch_size %d must be between 1 and 100", c.Webhooks.BatchSize)
+		check(c.Webhooks.Retention >= 0, "webhooks.retention must not be negative")
+	}
+
+	for _, sink := range c.Outbox.Sinks {
+		check(slices.Contains(outboxSinks, sink), "outbox.sinks entry %q must be one of %s", sink, strings.Join(outboxSinks, ", "))
+	}
+	check(!slices.Contains(c.Outbox.Sinks, "file") || c.Outbox.File != "", "outbox.file is required for the file sink")
+	check(c.Outbox.PollInterval > 0, "outbox.poll_interval must be positive")
+	check(c.Outbox.BatchSize >= 1 && c.Outbox.BatchSize <= 1000, "outbox.batch_size %d must be between 1 and 1000", c.Outbox.BatchSize)
+	check(c.Outbox.InitialBackoff > 0, "outbox.initial_backoff must be positive")
+	check(c.Outbox.MaxBackoff >= c.Outbox.InitialBackoff, "outbox.max_backoff must not be less than outbox.initial_backoff")
+	check(c.Outbox.Retention >= 0, "outbox.retention must not be negative")
+
+	check(c.Stream.BufferSize >= 1, "stream.buffer_size must be at least 1")
+	check(c.Stream.KeepAlive > 0, "stream.keep_alive must be positive")
+
+	check(c.Integrations.GitHub.WebhookSecret == "" || len(c.Integrations.GitHub.WebhookSecret) >= 16,
+		"integrations.github.webhook_secret must be at least 16 characters")
+	if gh := c.Integrations.GitHub; gh.Token != "" {
+		check(strings.HasPrefix(gh.APIURL, "https://") || strings.HasPrefix(gh.APIURL, "http://"),
+			"integrations.github.api_url %q must be an http or https URL", gh.APIURL)
+		check(gh.APITimeout > 0, "integrations.github.api_timeout must be positive")
+	}
+	check(c.Integrations.GitLab.WebhookToken == "" || len(c.Integrations.GitLab.WebhookToken) >= 16,
+		"integrations.gitlab.webhook_token must be at least 16 characters")
+
+	check(c.Metrics.StatsTimeout > 0, "metrics.stats_timeout must be positive")
+
+	check(slices.Contains(tracing.Exporters, c.Tracing.Exporter),
+		"tracing.exporter %q must be one of %s", c.Tracing.Exporter, strings.Join(tracing.Exporters, ", "))
+	check(c.Tracing.Exporter != tracing.ExporterOTLPFile || c.Tracing.File != "", "tracing.file is required for the otlp-file exporter")
+	check(c.Tracing.ServiceName != "", "tracing.service_name is required")
+
+	check(c.Auth.BootstrapToken == "" || len(c.Auth.BootstrapToken) >= 32, "auth.bootstrap_token must be at least 32 characters")
+	if jwt := c.Auth.JWT; jwt.Enabled() {
+		check(jwt.JWKSFile == "" || jwt.JWKSURL == "", "auth.jwt.jwks_file and auth.jwt.jwks_url are mutually exclusive")
+		check(jwt.JWKSURL == "" || strings.HasPrefix(jwt.JWKSURL, "https://") || strings.HasPrefix(jwt.JWKSURL, "http://"),
+			"auth.jwt.jwks_url %q must be an http or https URL", jwt.JWKSURL)
+		check(jwt.Issuer != "", "auth.jwt.issuer is required when JWTs are accepted")
+		check(jwt.Audience != "", "auth.jwt.audience is required when JWTs are accepted")
+		check(jwt.UserClaim != "", "auth.jwt.user_claim is required")
+		check(jwt.RolesClaim != "", "auth.jwt.roles_claim is required")
+		check(jwt.RefreshInterval > 0, "auth.jwt.refresh_interval must be positive")
+		check(jwt.Leeway >= 0, "auth.jwt.leeway must not be negative")
+	}
+
+	return errors.Join(errs...)
+}
+
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// ConnString returns the lib/pq connection string for the configured database.
+func (p PostgresConfig) ConnString() string {
+	if p.DSN != "" {
+		return p.DSN
+	}
+
+	params := []struct{ key, value string }{
+		{"host", p.Host},
+		{"port", strconv.Itoa(p.Port)},
+		{"user", p.User},
+		{"password", p.Password},
+		{"dbname", p.Name},
+		{"sslmode", p.SSLMode},
+	}
+	var parts []string
+	for _, param := range params {
+		if param.value != "" {
+			parts = append(parts, param.key+"="+quoteConnValue(param.value))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func quoteConnValue(v string) string {
+	if v != "" && !strings.ContainsAny(v, ` '\`) {
+		return v
+	}
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `'`, `\'`)
+	return "'" + v + "'"
+}
+
+func str(field func(*Config) *string) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+func integer(field func(*Config) *int) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func boolean(field func(*Config) *bool) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+		*field(c) = b
+		return nil
+	}
+}
+
+func float(field func(*Config) *float64) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		*field(c) = f
+		return nil
+	}
+}
+
+// rateLimitRoutes parses "/a=10:20,/b=1:5" into per-route rules.
+func rateLimitRoutes(c *Config, value string) error {
+	routes := map[string]RateLimitRule{}
+	for _, item := range strings.Split(value, ",") {
+		route, limit, ok := strings.Cut(strings.TrimSpace(item), "=")
+		rps, burst, ok2 := strings.Cut(limit, ":")
+		if !ok || !ok2 {
+			return fmt.Errorf("%q is not route=rps:burst", item)
+		}
+		var rule RateLimitRule
+		var err error
+		if rule.RPS, err = strconv.ParseFloat(rps, 64); err != nil {
+			return fmt.Errorf("%q: %q is not a number", item, rps)
+		}
+		if rule.Burst, err = strconv.Atoi(burst); err != nil {
+			return fmt.Errorf("%q: %q is not an integer", item, burst)
+		}
+		routes[route] = rule
+	}
+	c.RateLimit.Routes = routes
+	return nil
+}
+
+// list parses a comma-separated list, ignoring blank items.
+func list(field func(*Config) *[]string) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*field(c) = items
+		return nil
+	}
+}
+
+func dur(field func(*Config) *time.Duration) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration", value)
+		}
+		*field(c) = d
+		return nil
+	}
+}
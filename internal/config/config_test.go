@@ -0,0 +1,201 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"Backend/internal/config"
+)
+
+func envFrom(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+server:
+  listen_addr: ":9000"
+  request_timeout: 3s
+storage:
+  driver: postgres
+  postgres:
+    host: file-host
+    name: reviews
+    sslmode: require
+reviewers:
+  count: 3
+`)
+
+	cfg, err := config.Load(
+		[]string{"-config", path, "-reviewer-count", "4"},
+		envFrom(map[string]string{"DB_HOST": "env-host", "REVIEWER_COUNT": "1"}),
+	)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if cfg.Server.ListenAddr != ":9000" || cfg.Server.RequestTimeout != 3*time.Second {
+		t.Errorf("Expected server settings from file, got %+v", cfg.Server)
+	}
+	if cfg.Storage.Postgres.Host != "env-host" {
+		t.Errorf("Expected env to override file, got host %q", cfg.Storage.Postgres.Host)
+	}
+	if cfg.Reviewers.Count != 4 {
+		t.Errorf("Expected flag to override env and file, got count %d", cfg.Reviewers.Count)
+	}
+	if cfg.Server.ShutdownTimeout != config.Default().Server.ShutdownTimeout {
+		t.Errorf("Expected unset values to keep defaults, got %s", cfg.Server.ShutdownTimeout)
+	}
+	if got := cfg.Storage.Postgres.ConnString(); got != "host=env-host port=5432 dbname=reviews sslmode=require" {
+		t.Errorf("Unexpected connection string %q", got)
+	}
+}
+
+func TestLoad_JSONFileFromEnv(t *testing.T) {
+	path := writeFile(t, "config.json", `{"storage": {"driver": "memory"}, "idempotency": {"ttl": "1h"}}`)
+
+	cfg, err := config.Load(nil, envFrom(map[string]string{"CONFIG_FILE": path}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.Storage.Driver != "memory" || cfg.Idempotency.TTL != time.Hour {
+		t.Errorf("Expected settings from JSON file, got %+v", cfg)
+	}
+}
+
+func TestLoad_ReportsAllErrors(t *testing.T) {
+	_, err := config.Load(
+		[]string{"-storage", "postgres", "-db-sslmode", "sometimes", "-reviewer-strategy", "psychic", "-reviewer-expertise-half-life", "0s"},
+		envFrom(map[string]string{"REQUEST_TIMEOUT": "soon"}),
+	)
+	if err == nil {
+		t.Fatal("Expected validation errors")
+	}
+
+	for _, want := range []string{"REQUEST_TIMEOUT", "storage.postgres.host", "storage.postgres.name", "sslmode", "reviewers.strategy", "reviewers.expertise_half_life"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %s, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoad_UnknownFileField(t *testing.T) {
+	path := writeFile(t, "config.yaml", "server:\n  listen_adr: \":8080\"\n")
+
+	if _, err := config.Load([]string{"-config", path}, envFrom(nil)); err == nil {
+		t.Fatal("Expected an error for a misspelled field")
+	}
+}
+
+func TestLoad_JWTRequiresIssuerAndAudience(t *testing.T) {
+	_, err := config.Load(
+		[]string{"-storage", "memory", "-jwt-jwks-file", "jwks.json", "-jwt-jwks-url", "ftp://keys"},
+		envFrom(nil),
+	)
+	if err == nil {
+		t.Fatal("Expected validation errors")
+	}
+
+	for _, want := range []string{"mutually exclusive", "jwks_url", "auth.jwt.issuer", "auth.jwt.audience"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %s, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoad_RateLimitRoutes(t *testing.T) {
+	cfg, err := config.Load(
+		[]string{"-storage", "memory", "-rate-limit-routes", "/pullRequest/create=0.5:3, /team/add=2:4"},
+		envFrom(nil),
+	)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	want := map[string]config.RateLimitRule{
+		"/pullRequest/create": {RPS: 0.5, Burst: 3},
+		"/team/add":           {RPS: 2, Burst: 4},
+	}
+	if len(cfg.RateLimit.Routes) != len(want) {
+		t.Fatalf("Expected routes %v, got %v", want, cfg.RateLimit.Routes)
+	}
+	for route, rule := range want {
+		if cfg.RateLimit.Routes[route] != rule {
+			t.Errorf("Expected %s to be %+v, got %+v", route, rule, cfg.RateLimit.Routes[route])
+		}
+	}
+
+	_, err = config.Load([]string{"-storage", "memory", "-rate-limit-routes", "pullRequest/create=1:0"}, envFrom(nil))
+	if err == nil || !strings.Contains(err.Error(), "must be a route path") || !strings.Contains(err.Error(), "burst must be at least 1") {
+		t.Errorf("Expected route and burst errors, got %v", err)
+	}
+}
+
+func TestLoad_WebhookBackoff(t *testing.T) {
+	_, err := config.Load(
+		[]string{"-storage", "memory", "-webhooks-initial-backoff", "1m", "-webhooks-max-backoff", "30s"},
+		envFrom(map[string]string{"WEBHOOKS_MAX_ATTEMPTS": "0"}),
+	)
+	if err == nil {
+		t.Fatal("Expected validation errors")
+	}
+	for _, want := range []string{"webhooks.max_attempts", "webhooks.max_backoff"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %s, got:\n%v", want, err)
+		}
+	}
+
+	if _, err := config.Load([]string{"-storage", "memory", "-webhooks-enabled=false", "-webhooks-max-attempts", "0"}, envFrom(nil)); err != nil {
+		t.Errorf("Expected worker settings to be ignored when disabled, got %v", err)
+	}
+}
+
+func TestLoad_OutboxSinks(t *testing.T) {
+	cfg, err := config.Load([]string{"-storage", "memory"}, envFrom(map[string]string{"OUTBOX_SINKS": " log, file ", "OUTBOX_FILE": "out.jsonl"}))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !slices.Equal(cfg.Outbox.Sinks, []string{"log", "file"}) || cfg.Outbox.File != "out.jsonl" {
+		t.Errorf("Expected the log and file sinks, got %v and %q", cfg.Outbox.Sinks, cfg.Outbox.File)
+	}
+
+	_, err = config.Load([]string{"-storage", "memory", "-outbox-sinks", "kafka,file", "-outbox-file", ""}, envFrom(nil))
+	if err == nil {
+		t.Fatal("Expected validation errors")
+	}
+	for _, want := range []string{`outbox.sinks entry "kafka"`, "outbox.file"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %s, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoad_GitHubAPI(t *testing.T) {
+	cfg, err := config.Load([]string{"-storage", "memory"}, envFrom(map[string]string{"GITHUB_TOKEN": "ghp_test"}))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Integrations.GitHub.APIURL != "https://api.github.com" || cfg.Integrations.GitHub.APITimeout <= 0 {
+		t.Errorf("Expected the public API by default, got %q with timeout %s", cfg.Integrations.GitHub.APIURL, cfg.Integrations.GitHub.APITimeout)
+	}
+
+	_, err = config.Load([]string{"-storage", "memory", "-github-api-url", "ghe.example.com/api/v3"}, envFrom(map[string]string{"GITHUB_TOKEN": "ghp_test"}))
+	if err == nil || !strings.Contains(err.Error(), "integrations.github.api_url") {
+		t.Errorf("Expected the API URL to be rejected, got %v", err)
+	}
+}
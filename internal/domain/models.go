@@ -1,6 +1,10 @@
 package domain
 
-import "time"
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
 
 type User struct {
 	UserID   string `json:"user_id"`
@@ -19,6 +23,8 @@ type PullRequestStatus string
 const (
 	StatusOpen   PullRequestStatus = "OPEN"
 	StatusMerged PullRequestStatus = "MERGED"
+	// StatusClosed is a PR closed without merging; it can be reopened.
+	StatusClosed PullRequestStatus = "CLOSED"
 )
 
 type PullRequest struct {
@@ -29,6 +35,37 @@ type PullRequest struct {
 	AssignedReviewers []string          `json:"assigned_reviewers"`
 	CreatedAt         *time.Time        `json:"createdAt,omitempty"`
 	MergedAt          *time.Time        `json:"mergedAt,omitempty"`
+	Version           int               `json:"version"`
+	// ChangedPaths lists the files the PR touches, relative to the repository
+	// root; reviewers are preferably picked among their owners.
+	ChangedPaths []string `json:"changed_paths,omitempty"`
+	// Labels are free-form tags such as the labels of the code host.
+	Labels []string `json:"labels,omitempty"`
+	// CodeHostRef locates the PR on the code host it was opened from, as
+	// <provider>-<repository id>-<number>. Only code host webhooks set it.
+	CodeHostRef string `json:"code_host_ref,omitempty"`
+}
+
+// ExpertiseScore measures how well a user knows an area of the code, learnt
+// from the merged or closed PRs touching it that they were assigned to review. Area is a label
+// as "label:<name>" or a directory as "<path>/".
+type ExpertiseScore struct {
+	UserID string `json:"user_id"`
+	Area   string `json:"area"`
+	// Score sums the reviews, each weighted down as it ages.
+	Score   float64 `json:"score"`
+	Reviews int     `json:"reviews"`
+}
+
+// AnyVersion disables the optimistic concurrency check for an update.
+const AnyVersion = 0
+
+// CodeOwners holds the CODEOWNERS-style rules of a team, which decide the
+// preferred reviewers of the PRs its members author.
+type CodeOwners struct {
+	TeamName  string    `json:"team_name"`
+	Rules     string    `json:"rules"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 type PullRequestShort struct {
@@ -38,6 +75,337 @@ type PullRequestShort struct {
 	Status          PullRequestStatus `json:"status"`
 }
 
+// ReviewStats is a snapshot of review workload used for monitoring.
+type ReviewStats struct {
+	OpenPRs                 int
+	MergedPRs               int
+	OpenPRsWithoutReviewers int
+	// OpenReviewsByUser counts open PRs each user is assigned to review.
+	OpenReviewsByUser map[string]int
+}
+
+// IdempotencyRecord is the stored outcome of a request made with an
+// Idempotency-Key. StatusCode is zero while the first request is in flight.
+type IdempotencyRecord struct {
+	Key         string
+	RequestHash string
+	StatusCode  int
+	Headers     map[string][]string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+type Role string
+
+const (
+	RoleAdmin    Role = "admin"
+	RoleTeamLead Role = "team-lead"
+	RoleMember   Role = "member"
+	RoleBot      Role = "bot"
+)
+
+var Roles = []Role{RoleAdmin, RoleTeamLead, RoleMember, RoleBot}
+
+// Identity is the authenticated caller of a request.
+type Identity struct {
+	// Subject identifies the credential, e.g. "token:<id>".
+	Subject string
+	// UserID is the team member acting, empty for bots and service accounts.
+	UserID string
+	Roles  []Role
+}
+
+// HasAnyRole reports whether the identity has one of roles; an empty list matches any identity.
+func (i Identity) HasAnyRole(roles ...Role) bool {
+	if len(roles) == 0 {
+		return true
+	}
+	for _, want := range roles {
+		for _, have := range i.Roles {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// APIToken is a bearer token; only the SHA-256 hash of its secret is stored.
+type APIToken struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	TokenHash string     `json:"-"`
+	UserID    string     `json:"user_id,omitempty"`
+	Roles     []Role     `json:"roles"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+const (
+	AuditTeamUpsert    = "team.upsert"
+	AuditUserSetActive = "user.set_active"
+	AuditPRCreate      = "pull_request.create"
+	AuditPRMerge       = "pull_request.merge"
+	AuditPRReassign    = "pull_request.reassign"
+	AuditPRClose       = "pull_request.close"
+	AuditPRReopen      = "pull_request.reopen"
+	AuditCodeOwnersSet = "team.codeowners_set"
+)
+
+// AuditEntry records one successful mutation. Entries are append-only.
+type AuditEntry struct {
+	ID int64 `json:"id"`
+	// Actor is the acting user id, the credential subject for callers that are
+	// not users, or "anonymous" when authentication is disabled.
+	Actor  string `json:"actor"`
+	Action string `json:"action"`
+	// TargetIDs lists the teams, users and pull requests the action touched.
+	TargetIDs []string        `json:"target_ids"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	RequestID string          `json:"request_id,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// PullRequestAudit completes entry for the change of a pull request from before
+// to after; before is nil for a new pull request. The targets are the PR and
+// its author and reviewers when it is created, otherwise the PR and the
+// reviewers that were replaced.
+func PullRequestAudit(entry AuditEntry, before *PullRequest, after PullRequest) AuditEntry {
+	if before == nil {
+		entry.TargetIDs = append([]string{after.PullRequestID, after.AuthorID}, after.AssignedReviewers...)
+		entry.After = auditSnapshot(after)
+		return entry
+	}
+	entry.TargetIDs = []string{after.PullRequestID}
+	removed, added := reviewerChanges(*before, after)
+	for i := range max(len(removed), len(added)) {
+		if i < len(removed) {
+			entry.TargetIDs = append(entry.TargetIDs, removed[i])
+		}
+		if i < len(added) {
+			entry.TargetIDs = append(entry.TargetIDs, added[i])
+		}
+	}
+	entry.Before, entry.After = auditSnapshot(*before), auditSnapshot(after)
+	return entry
+}
+
+// TeamAudit completes entry for the upsert of a team; before is nil for a new
+// team. The targets are the team and its members before and after.
+func TeamAudit(entry AuditEntry, before *Team, after Team) AuditEntry {
+	entry.TargetIDs = []string{after.TeamName}
+	var previous []User
+	if before != nil {
+		previous = before.Members
+		entry.Before = auditSnapshot(*before)
+	}
+	for _, members := range [][]User{previous, after.Members} {
+		for _, member := range members {
+			if !slices.Contains(entry.TargetIDs, member.UserID) {
+				entry.TargetIDs = append(entry.TargetIDs, member.UserID)
+			}
+		}
+	}
+	entry.After = auditSnapshot(after)
+	return entry
+}
+
+// UserAudit completes entry for a change of a user.
+func UserAudit(entry AuditEntry, before, after User) AuditEntry {
+	entry.TargetIDs = []string{after.UserID}
+	entry.Before, entry.After = auditSnapshot(before), auditSnapshot(after)
+	return entry
+}
+
+// CodeOwnersAudit completes entry for an upload of CODEOWNERS rules; before is
+// nil when the team had none.
+func CodeOwnersAudit(entry AuditEntry, before *CodeOwners, after CodeOwners) AuditEntry {
+	entry.TargetIDs = []string{after.TeamName}
+	if before != nil {
+		entry.Before = auditSnapshot(*before)
+	}
+	entry.After = auditSnapshot(after)
+	return entry
+}
+
+func auditSnapshot(v any) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+const (
+	EventReviewersAssigned   = "pull_request.reviewers_assigned"
+	EventReviewerReassigned  = "pull_request.reviewer_reassigned"
+	EventPRMerged            = "pull_request.merged"
+	EventTeamUpdated         = "team.updated"
+	EventUserActivityChanged = "user.activity_changed"
+)
+
+// EventTypes lists the events a webhook subscription may filter on.
+var EventTypes = []string{EventReviewersAssigned, EventReviewerReassigned, EventPRMerged, EventTeamUpdated, EventUserActivityChanged}
+
+// Event describes a change that external systems are notified about. Exactly
+// one of PullRequest, Team and User is set.
+type Event struct {
+	ID            string       `json:"id"`
+	Type          string       `json:"type"`
+	OccurredAt    time.Time    `json:"occurred_at"`
+	PullRequest   *PullRequest `json:"pull_request,omitempty"`
+	OldReviewerID string       `json:"old_reviewer_id,omitempty"`
+	NewReviewerID string       `json:"new_reviewer_id,omitempty"`
+	Team          *Team        `json:"team,omitempty"`
+	User          *User        `json:"user,omitempty"`
+}
+
+// AggregateID names the object whose events must be published in the order
+// they occurred: the pull request, or the team for team and user events.
+func (e Event) AggregateID() string {
+	switch {
+	case e.PullRequest != nil:
+		return "pull_request:" + e.PullRequest.PullRequestID
+	case e.Team != nil:
+		return "team:" + e.Team.TeamName
+	case e.User != nil:
+		return "team:" + e.User.TeamName
+	default:
+		return ""
+	}
+}
+
+// PullRequestEvents returns the events describing the change of a pull
+// request from before to after; before is nil for a new pull request.
+func PullRequestEvents(before *PullRequest, after PullRequest, at time.Time) []Event {
+	snapshot := func() *PullRequest {
+		pr := after
+		pr.AssignedReviewers = append([]string{}, after.AssignedReviewers...)
+		return &pr
+	}
+	if before == nil {
+		return []Event{{Type: EventReviewersAssigned, OccurredAt: at, PullRequest: snapshot()}}
+	}
+
+	var events []Event
+	removed, added := reviewerChanges(*before, after)
+	for i := 0; i < len(removed) && i < len(added); i++ {
+		events = append(events, Event{Type: EventReviewerReassigned, OccurredAt: at, PullRequest: snapshot(),
+			OldReviewerID: removed[i], NewReviewerID: added[i]})
+	}
+	if before.Status != StatusMerged && after.Status == StatusMerged {
+		events = append(events, Event{Type: EventPRMerged, OccurredAt: at, PullRequest: snapshot()})
+	}
+	return events
+}
+
+// reviewerChanges returns the reviewers of before that after no longer has and
+// the ones after added.
+func reviewerChanges(before, after PullRequest) (removed, added []string) {
+	for _, id := range before.AssignedReviewers {
+		if !slices.Contains(after.AssignedReviewers, id) {
+			removed = append(removed, id)
+		}
+	}
+	for _, id := range after.AssignedReviewers {
+		if !slices.Contains(before.AssignedReviewers, id) {
+			added = append(added, id)
+		}
+	}
+	return removed, added
+}
+
+// OutboxEvent is an event recorded in the same transaction as the change it
+// describes, together with the state of its publication.
+type OutboxEvent struct {
+	Event
+	// Seq orders the events; the event ID is its decimal form.
+	Seq           int64
+	AggregateID   string
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	// PublishedSinks names the sinks that already accepted the event, so that
+	// a retry only goes to the ones that failed.
+	PublishedSinks []string
+	PublishedAt    *time.Time
+}
+
+// WebhookSubscription receives the events listed in Events, or all events when
+// Events contains "*". Secret signs the payloads and is only returned on creation.
+type WebhookSubscription struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Matches reports whether the subscription wants events of type eventType.
+func (s WebhookSubscription) Matches(eventType string) bool {
+	for _, e := range s.Events {
+		if e == "*" || e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	// DeliveryDead marks a delivery that ran out of attempts.
+	DeliveryDead = "dead"
+)
+
+// WebhookDelivery is one event sent to one subscription.
+type WebhookDelivery struct {
+	ID             string `json:"id"`
+	SubscriptionID string `json:"subscription_id"`
+	// URL and Secret are filled from the subscription when deliveries are claimed or listed.
+	URL           string          `json:"url"`
+	Secret        string          `json:"-"`
+	EventID       string          `json:"event_id"`
+	EventType     string          `json:"event_type"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LastError     string          `json:"last_error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// AuditFilter selects audit entries; zero fields match everything. From is
+// inclusive and To exclusive.
+type AuditFilter struct {
+	Actor  string
+	Target string
+	From   *time.Time
+	To     *time.Time
+	Limit  int
+}
+
+const (
+	ProviderGitHub = "github"
+	ProviderGitLab = "gitlab"
+)
+
+// Providers lists the code hosts whose webhooks are accepted.
+var Providers = []string{ProviderGitHub, ProviderGitLab}
+
+// UserMapping links an account of a code host to a user. Logins are stored
+// in lower case, as code hosts compare them case-insensitively.
+type UserMapping struct {
+	Provider  string    `json:"provider"`
+	Login     string    `json:"login"`
+	UserID    string    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type ErrorCode string
 
 const (
@@ -45,26 +413,61 @@ const (
 	ErrPRExists    ErrorCode = "PR_EXISTS"
 	ErrNotFound    ErrorCode = "NOT_FOUND"
 	ErrPRMerged    ErrorCode = "PR_MERGED"
+	ErrPRClosed    ErrorCode = "PR_CLOSED"
 	ErrNotAssigned ErrorCode = "NOT_ASSIGNED"
 	ErrNoCandidate ErrorCode = "NO_CANDIDATE"
+
+	ErrVersionConflict ErrorCode = "VERSION_CONFLICT"
+
+	ErrIdempotencyMismatch   ErrorCode = "IDEMPOTENCY_KEY_MISMATCH"
+	ErrIdempotencyInProgress ErrorCode = "IDEMPOTENCY_KEY_IN_PROGRESS"
+
+	ErrUnauthorized ErrorCode = "UNAUTHORIZED"
+	ErrForbidden    ErrorCode = "FORBIDDEN"
+	ErrTokenExists  ErrorCode = "TOKEN_EXISTS"
+	ErrRateLimited  ErrorCode = "RATE_LIMITED"
+
+	ErrWebhookExists ErrorCode = "WEBHOOK_EXISTS"
+
+	ErrInvalidRequest   ErrorCode = "INVALID_REQUEST"
+	ErrValidationFailed ErrorCode = "VALIDATION_FAILED"
+	ErrTimeout          ErrorCode = "TIMEOUT"
+	ErrInternal         ErrorCode = "INTERNAL"
 )
 
+// FieldError describes why one request field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
 type BusinessError struct {
 	Code    ErrorCode
 	Message string
+	// Details lists the offending fields of a VALIDATION_FAILED error.
+	Details []FieldError
 }
 
 func (e *BusinessError) Error() string {
 	return e.Message
 }
 
+// ErrorResponse is the envelope of every error returned by the API.
 type ErrorResponse struct {
-	Error struct {
-		Code    ErrorCode `json:"code"`
-		Message string    `json:"message"`
-	} `json:"error"`
+	Error ErrorBody `json:"error"`
+}
+
+type ErrorBody struct {
+	Code      ErrorCode    `json:"code"`
+	Message   string       `json:"message"`
+	Details   []FieldError `json:"details,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
 }
 
 func NewBusinessError(code ErrorCode, message string) *BusinessError {
 	return &BusinessError{Code: code, Message: message}
 }
+
+func NewValidationError(details ...FieldError) *BusinessError {
+	return &BusinessError{Code: ErrValidationFailed, Message: "Request validation failed", Details: details}
+}
@@ -0,0 +1,182 @@
+// Package metrics exposes service metrics in the Prometheus text format.
+package metrics
+
+import (
+	"Backend/internal/domain"
+	"Backend/internal/repository"
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "pr_reviewer"
+
+type Metrics struct {
+	registry       *prometheus.Registry
+	httpRequests   *prometheus.CounterVec
+	httpDuration   *prometheus.HistogramVec
+	businessErrors *prometheus.CounterVec
+	rateLimits     *prometheus.CounterVec
+	webhooks       *prometheus.CounterVec
+	outbox         *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route template and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method and route template.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		businessErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "business_errors_total",
+			Help:      "Business errors returned to clients by error code.",
+		}, []string{"code"}),
+		rateLimits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rate_limit_decisions_total",
+			Help:      "Rate limiter decisions by route template and result (allowed or limited).",
+		}, []string{"route", "result"}),
+		webhooks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "webhook_delivery_attempts_total",
+			Help:      "Webhook delivery attempts by result (delivered, retry or dead).",
+		}, []string{"result"}),
+		outbox: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "outbox_publish_attempts_total",
+			Help:      "Outbox event publication attempts by result (published or retry).",
+		}, []string{"result"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.businessErrors,
+		m.rateLimits,
+		m.webhooks,
+		m.outbox,
+	)
+	return m
+}
+
+// Registerer lets other components register their own collectors.
+func (m *Metrics) Registerer() prometheus.Registerer {
+	return m.registry
+}
+
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+func (m *Metrics) ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	m.httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.httpDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+func (m *Metrics) IncBusinessError(code domain.ErrorCode) {
+	m.businessErrors.WithLabelValues(string(code)).Inc()
+}
+
+func (m *Metrics) ObserveRateLimit(route string, allowed bool) {
+	result := "allowed"
+	if !allowed {
+		result = "limited"
+	}
+	m.rateLimits.WithLabelValues(route, result).Inc()
+}
+
+func (m *Metrics) ObserveWebhookDelivery(result string) {
+	m.webhooks.WithLabelValues(result).Inc()
+}
+
+func (m *Metrics) ObserveOutboxPublish(result string) {
+	m.outbox.WithLabelValues(result).Inc()
+}
+
+// RegisterRateLimitBuckets exports the number of callers tracked by the rate limiter.
+func (m *Metrics) RegisterRateLimitBuckets(count func() int) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_buckets",
+		Help:      "Token buckets currently kept by the rate limiter.",
+	}, func() float64 { return float64(count()) }))
+}
+
+// RegisterDB exports connection pool statistics from sql.DB.Stats.
+func (m *Metrics) RegisterDB(db *sql.DB, dbName string) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, dbName))
+}
+
+// RegisterReviewStats exports review workload gauges, queried on every scrape.
+func (m *Metrics) RegisterReviewStats(repo repository.StatsRepository, timeout time.Duration) {
+	m.registry.MustRegister(&reviewStatsCollector{repo: repo, timeout: timeout})
+}
+
+var (
+	openPRsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "open_pull_requests"),
+		"Pull requests in OPEN status.", nil, nil)
+	mergedPRsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "merged_pull_requests"),
+		"Pull requests in MERGED status.", nil, nil)
+	unassignedPRsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "open_pull_requests_without_reviewers"),
+		"Open pull requests that have no assigned reviewers.", nil, nil)
+	openReviewsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "open_reviews"),
+		"Open pull requests assigned to a user for review.", []string{"user_id"}, nil)
+	statsUpDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "review_stats_up"),
+		"Whether the last review statistics query succeeded.", nil, nil)
+)
+
+type reviewStatsCollector struct {
+	repo    repository.StatsRepository
+	timeout time.Duration
+}
+
+func (c *reviewStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- openPRsDesc
+	ch <- mergedPRsDesc
+	ch <- unassignedPRsDesc
+	ch <- openReviewsDesc
+	ch <- statsUpDesc
+}
+
+func (c *reviewStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	stats, err := c.repo.GetReviewStats(ctx)
+	if err != nil {
+		slog.Error("failed to collect review stats", "error", err)
+		ch <- prometheus.MustNewConstMetric(statsUpDesc, prometheus.GaugeValue, 0)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(statsUpDesc, prometheus.GaugeValue, 1)
+	ch <- prometheus.MustNewConstMetric(openPRsDesc, prometheus.GaugeValue, float64(stats.OpenPRs))
+	ch <- prometheus.MustNewConstMetric(mergedPRsDesc, prometheus.GaugeValue, float64(stats.MergedPRs))
+	ch <- prometheus.MustNewConstMetric(unassignedPRsDesc, prometheus.GaugeValue, float64(stats.OpenPRsWithoutReviewers))
+	for userID, count := range stats.OpenReviewsByUser {
+		ch <- prometheus.MustNewConstMetric(openReviewsDesc, prometheus.GaugeValue, float64(count), userID)
+	}
+}
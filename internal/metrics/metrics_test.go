@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"Backend/internal/domain"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type stubStats struct {
+	stats domain.ReviewStats
+	err   error
+}
+
+func (s stubStats) GetReviewStats(ctx context.Context) (domain.ReviewStats, error) {
+	return s.stats, s.err
+}
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from /metrics, got %d", rec.Code)
+	}
+	body, _ := io.ReadAll(rec.Body)
+	return string(body)
+}
+
+func TestMetrics_ExposesRequestsErrorsAndReviewStats(t *testing.T) {
+	m := New()
+	m.ObserveHTTPRequest("POST", "/pullRequest/create", http.StatusCreated, 20*time.Millisecond)
+	m.IncBusinessError(domain.ErrNotFound)
+	m.RegisterReviewStats(stubStats{stats: domain.ReviewStats{
+		OpenPRs:                 3,
+		MergedPRs:               1,
+		OpenPRsWithoutReviewers: 1,
+		OpenReviewsByUser:       map[string]int{"u2": 2},
+	}}, time.Second)
+
+	out := scrape(t, m)
+	for _, want := range []string{
+		`pr_reviewer_http_requests_total{method="POST",route="/pullRequest/create",status="201"} 1`,
+		`pr_reviewer_http_request_duration_seconds_count{method="POST",route="/pullRequest/create"} 1`,
+		`pr_reviewer_business_errors_total{code="NOT_FOUND"} 1`,
+		`pr_reviewer_open_pull_requests 3`,
+		`pr_reviewer_merged_pull_requests 1`,
+		`pr_reviewer_open_pull_requests_without_reviewers 1`,
+		`pr_reviewer_open_reviews{user_id="u2"} 2`,
+		`pr_reviewer_review_stats_up 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected metrics output to contain %q", want)
+		}
+	}
+}
+
+func TestMetrics_ReviewStatsFailure(t *testing.T) {
+	m := New()
+	m.RegisterReviewStats(stubStats{err: errors.New("db down")}, time.Second)
+
+	out := scrape(t, m)
+	if !strings.Contains(out, "pr_reviewer_review_stats_up 0") {
+		t.Errorf("Expected review_stats_up 0 when the query fails")
+	}
+	if strings.Contains(out, "pr_reviewer_open_pull_requests ") {
+		t.Errorf("Expected no workload gauges when the query fails")
+	}
+}
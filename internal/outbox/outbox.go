@@ -0,0 +1,167 @@
+// Package outbox publishes the events recorded by the repositories to sinks.
+//
+// Events are written in the same transaction as the change they describe and
+// published afterwards, so an event is never lost but may be published more
+// than once; consumers deduplicate by event id. A sink that accepted an event
+// is not given it again when another sink fails. The events of one pull request
+// or team are published in the order they occurred.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"time"
+
+	"Backend/internal/domain"
+	"Backend/internal/metrics"
+	"Backend/internal/poll"
+	"Backend/internal/repository"
+	"Backend/internal/tracing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Sink receives published events. Publish must be safe to repeat for the same event.
+type Sink interface {
+	Publish(ctx context.Context, event domain.Event) error
+}
+
+// lease is how long claimed events are reserved for a relay; it only matters
+// when a relay dies while holding them.
+const lease = time.Minute
+
+var tracer = otel.Tracer("Backend/internal/outbox")
+
+type Options struct {
+	PollInterval time.Duration
+	BatchSize    int
+	// InitialBackoff is the delay before the first retry of an event; it
+	// doubles with every further attempt up to MaxBackoff. Events are retried
+	// until they are published, holding back the later events of their aggregate.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Retention is how long published events are kept; zero keeps them forever.
+	Retention time.Duration
+	Metrics   *metrics.Metrics
+}
+
+type Relay struct {
+	repo  repository.OutboxRepository
+	sinks map[string]Sink
+	names []string
+	opts  Options
+	now   func() time.Time
+}
+
+// NewRelay returns a relay publishing every event to all sinks, which are
+// named for logs and metrics.
+func NewRelay(repo repository.OutboxRepository, sinks map[string]Sink, opts Options) *Relay {
+	names := make([]string, 0, len(sinks))
+	for name := range sinks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return &Relay{
+		repo:  repo,
+		sinks: sinks,
+		names: names,
+		opts:  opts,
+		now:   func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Run publishes events until ctx is done.
+func (r *Relay) Run(ctx context.Context) {
+	poll.Loop(ctx, "outbox events", r.opts.PollInterval, r.opts.BatchSize, r.processBatch, r.cleanup)
+}
+
+func (r *Relay) cleanup(ctx context.Context) {
+	if r.opts.Retention <= 0 {
+		return
+	}
+	n, err := r.repo.DeletePublishedOutboxEvents(ctx, r.now().Add(-r.opts.Retention))
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to delete published outbox events", "error", err)
+		return
+	}
+	slog.DebugContext(ctx, "published outbox events deleted", "count", n)
+}
+
+// processBatch claims due events and publishes them in order. Once an event
+// fails, the later events of its aggregate in the batch are released
+// unpublished, so that they follow it when it is retried.
+func (r *Relay) processBatch(ctx context.Context) (int, error) {
+	events, err := r.repo.ClaimOutboxEvents(ctx, r.now(), lease, r.opts.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	failed := make(map[string]bool)
+	for _, e := range events {
+		if failed[e.AggregateID] {
+			e.NextAttemptAt = r.now()
+			r.record(ctx, e)
+			continue
+		}
+
+		err := r.publish(ctx, &e)
+		now := r.now()
+		e.Attempts++
+		result := "published"
+		if err == nil {
+			e.PublishedAt, e.LastError = &now, ""
+		} else {
+			result = "retry"
+			failed[e.AggregateID] = true
+			e.LastError, e.NextAttemptAt = err.Error(), now.Add(r.backoff(e.Attempts))
+			slog.WarnContext(ctx, "failed to publish event, retrying", "event_id", e.ID, "event_type", e.Type,
+				"aggregate_id", e.AggregateID, "attempts", e.Attempts, "next_attempt_at", e.NextAttemptAt, "error", err)
+		}
+		if r.opts.Metrics != nil {
+			r.opts.Metrics.ObserveOutboxPublish(result)
+		}
+		r.record(ctx, e)
+	}
+	return len(events), nil
+}
+
+// publish gives e to the sinks that have not accepted it yet and adds the ones
+// that do to e.PublishedSinks.
+func (r *Relay) publish(ctx context.Context, e *domain.OutboxEvent) (err error) {
+	ctx, span := tracer.Start(ctx, "Outbox.Publish", trace.WithAttributes(
+		attribute.String("event.id", e.ID), attribute.String("event.type", e.Type),
+		attribute.String("outbox.aggregate_id", e.AggregateID), attribute.Int("outbox.attempt", e.Attempts+1)))
+	defer func() { tracing.End(span, err) }()
+
+	var errs []error
+	for _, name := range r.names {
+		if slices.Contains(e.PublishedSinks, name) {
+			continue
+		}
+		if err := r.sinks[name].Publish(ctx, e.Event); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		e.PublishedSinks = append(e.PublishedSinks, name)
+	}
+	return errors.Join(errs...)
+}
+
+// record stores the outcome of an attempt even when shutting down, otherwise
+// a published event would be published again.
+func (r *Relay) record(ctx context.Context, e domain.OutboxEvent) {
+	if err := r.repo.UpdateOutboxEvent(context.WithoutCancel(ctx), e); err != nil {
+		slog.ErrorContext(ctx, "failed to record outbox event", "event_id", e.ID, "error", err)
+	}
+}
+
+// backoff returns the delay after the given number of failed attempts.
+func (r *Relay) backoff(attempts int) time.Duration {
+	return poll.Backoff(r.opts.InitialBackoff, r.opts.MaxBackoff, attempts)
+}
@@ -0,0 +1,193 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"Backend/internal/domain"
+	"Backend/internal/repository/memory"
+)
+
+type recordingSink struct {
+	events []domain.Event
+	fail   map[string]bool
+}
+
+func (s *recordingSink) Publish(_ context.Context, event domain.Event) error {
+	if s.fail[event.AggregateID()] {
+		return errors.New("unavailable")
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+func seed(t *testing.T, repo *memory.MemoryRepository) {
+	t.Helper()
+	ctx := context.Background()
+	_, err := repo.CreateOrUpdateTeam(ctx, domain.Team{TeamName: "backend", Members: []domain.User{
+		{UserID: "u1", Username: "Alice", TeamName: "backend", IsActive: true},
+		{UserID: "u2", Username: "Bob", TeamName: "backend", IsActive: true},
+	}})
+	if err != nil {
+		t.Fatalf("CreateOrUpdateTeam failed: %v", err)
+	}
+	for _, id := range []string{"pr-1", "pr-2"} {
+		_, err := repo.CreatePullRequest(ctx, domain.PullRequest{
+			PullRequestID: id, PullRequestName: id, AuthorID: "u1", Status: domain.StatusOpen, AssignedReviewers: []string{"u2"},
+		})
+		if err != nil {
+			t.Fatalf("CreatePullRequest failed: %v", err)
+		}
+	}
+	pr, _ := repo.GetPullRequestByID(ctx, "pr-1")
+	pr.Status = domain.StatusMerged
+	if _, err := repo.UpdatePullRequest(ctx, pr); err != nil {
+		t.Fatalf("UpdatePullRequest failed: %v", err)
+	}
+}
+
+func newTestRelay(repo *memory.MemoryRepository, sink Sink) (*Relay, *time.Time) {
+	now := time.Now().UTC()
+	r := NewRelay(repo, map[string]Sink{"test": sink}, Options{
+		PollInterval: time.Second, BatchSize: 10, InitialBackoff: time.Second, MaxBackoff: time.Minute,
+	})
+	r.now = func() time.Time { return now }
+	return r, &now
+}
+
+func types(events []domain.Event) []string {
+	var out []string
+	for _, e := range events {
+		out = append(out, e.AggregateID()+" "+e.Type)
+	}
+	return out
+}
+
+func TestRelay_PublishesInOrderOnce(t *testing.T) {
+	repo := memory.NewMemoryRepository()
+	seed(t, repo)
+	sink := &recordingSink{}
+	r, _ := newTestRelay(repo, sink)
+
+	if n, err := r.processBatch(context.Background()); err != nil || n != 4 {
+		t.Fatalf("Expected 4 events to be processed, got %d, %v", n, err)
+	}
+	want := []string{
+		"team:backend " + domain.EventTeamUpdated,
+		"pull_request:pr-1 " + domain.EventReviewersAssigned,
+		"pull_request:pr-2 " + domain.EventReviewersAssigned,
+		"pull_request:pr-1 " + domain.EventPRMerged,
+	}
+	if got := types(sink.events); !slices.Equal(got, want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+
+	if n, _ := r.processBatch(context.Background()); n != 0 {
+		t.Errorf("Expected published events not to be claimed again, got %d", n)
+	}
+}
+
+func TestRelay_FailureHoldsBackItsAggregateOnly(t *testing.T) {
+	repo := memory.NewMemoryRepository()
+	seed(t, repo)
+	sink := &recordingSink{fail: map[string]bool{"pull_request:pr-1": true}}
+	r, now := newTestRelay(repo, sink)
+	ctx := context.Background()
+
+	r.processBatch(ctx)
+	if got := types(sink.events); len(got) != 2 || got[1] != "pull_request:pr-2 "+domain.EventReviewersAssigned {
+		t.Fatalf("Expected the team and pr-2 events only, got %v", got)
+	}
+
+	*now = now.Add(999 * time.Millisecond)
+	if n, _ := r.processBatch(ctx); n != 0 {
+		t.Fatalf("Expected pr-1 to wait for its backoff, got %d events", n)
+	}
+
+	delete(sink.fail, "pull_request:pr-1")
+	*now = now.Add(time.Millisecond)
+	if n, _ := r.processBatch(ctx); n != 2 {
+		t.Fatalf("Expected both pr-1 events to be retried, got %d", n)
+	}
+	got := types(sink.events)
+	if len(got) != 4 || got[2] != "pull_request:pr-1 "+domain.EventReviewersAssigned || got[3] != "pull_request:pr-1 "+domain.EventPRMerged {
+		t.Errorf("Expected pr-1 events in order after the retry, got %v", got)
+	}
+}
+
+func TestRelay_RetriesOnlyFailedSinks(t *testing.T) {
+	repo := memory.NewMemoryRepository()
+	seed(t, repo)
+	ok, flaky := &recordingSink{}, &recordingSink{fail: map[string]bool{"pull_request:pr-1": true}}
+	r := NewRelay(repo, map[string]Sink{"ok": ok, "flaky": flaky}, Options{
+		PollInterval: time.Second, BatchSize: 10, InitialBackoff: time.Second, MaxBackoff: time.Minute,
+	})
+	now := time.Now().UTC()
+	r.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	r.processBatch(ctx)
+	delete(flaky.fail, "pull_request:pr-1")
+	now = now.Add(time.Second)
+	if n, _ := r.processBatch(ctx); n != 2 {
+		t.Fatalf("Expected both pr-1 events to be retried, got %d", n)
+	}
+
+	// ok accepted the first pr-1 event before flaky failed it, so only flaky
+	// gets it again; both get the held back merge once.
+	if got := types(ok.events); len(got) != 4 {
+		t.Errorf("Expected every event once in the sink that never failed, got %v", got)
+	}
+	if got := types(flaky.events); len(got) != 4 || got[2] != "pull_request:pr-1 "+domain.EventReviewersAssigned {
+		t.Errorf("Expected the failed event to be retried in the failing sink, got %v", got)
+	}
+}
+
+func TestRelay_Backoff(t *testing.T) {
+	r := NewRelay(nil, nil, Options{InitialBackoff: time.Second, MaxBackoff: 10 * time.Second})
+	for attempts, want := range map[int]time.Duration{1: time.Second, 3: 4 * time.Second, 5: 10 * time.Second} {
+		if got := r.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+func TestFileSink_AppendsJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	sink, err := NewFileSink(path)
+	if err != nil {
+		t.Fatalf("NewFileSink failed: %v", err)
+	}
+	for _, id := range []string{"1", "2"} {
+		if err := sink.Publish(context.Background(), domain.Event{ID: id, Type: domain.EventPRMerged}); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer f.Close()
+	var ids []string
+	for scanner := bufio.NewScanner(f); scanner.Scan(); {
+		var e domain.Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("Line %q is not JSON: %v", scanner.Text(), err)
+		}
+		ids = append(ids, e.ID)
+	}
+	if len(ids) != 2 || ids[0] != "1" || ids[1] != "2" {
+		t.Errorf("Expected events 1 and 2, got %v", ids)
+	}
+}
@@ -0,0 +1,60 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+
+	"Backend/internal/domain"
+)
+
+const (
+	SinkWebhook = "webhook"
+	SinkLog     = "log"
+	SinkFile    = "file"
+)
+
+// LogSink writes every event to the application log.
+type LogSink struct{}
+
+func (LogSink) Publish(ctx context.Context, event domain.Event) error {
+	slog.InfoContext(ctx, "event published", "event_id", event.ID, "event_type", event.Type, "aggregate_id", event.AggregateID())
+	return nil
+}
+
+// FileSink appends every event to a file as a line of JSON.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open event file: %w", err)
+	}
+	return &FileSink{file: f}, nil
+}
+
+// Publish returns once the event is on disk, so that it is not marked as
+// published only to be lost in a crash.
+func (s *FileSink) Publish(ctx context.Context, event domain.Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode event %s: %w", event.ID, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write event %s: %w", event.ID, err)
+	}
+	return s.file.Sync()
+}
+
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,49 @@
+// Package poll runs the claim-and-process loops of the background workers.
+package poll
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Loop calls process every interval until ctx is done, and cleanup every hour.
+// A batch of batchSize items suggests a backlog, so process is then called
+// again right away. what names the claimed items in logs.
+func Loop(ctx context.Context, what string, interval time.Duration, batchSize int,
+	process func(context.Context) (int, error), cleanup func(context.Context)) {
+	poll := time.NewTicker(interval)
+	defer poll.Stop()
+	hourly := time.NewTicker(time.Hour)
+	defer hourly.Stop()
+
+	for {
+		for {
+			n, err := process(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to claim "+what, "error", err)
+			}
+			if err != nil || n < batchSize || ctx.Err() != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+		case <-hourly.C:
+			cleanup(ctx)
+		}
+	}
+}
+
+// Backoff returns the delay after the given number of failed attempts: initial
+// after the first, doubled with every further attempt up to limit.
+func Backoff(initial, limit time.Duration, attempts int) time.Duration {
+	delay := initial
+	for i := 1; i < attempts && delay < limit; i++ {
+		delay *= 2
+	}
+	return min(delay, limit)
+}
@@ -0,0 +1,832 @@
+package memory
+
+import (
+	"Backend/internal/domain"
+	"Backend/internal/repository"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemoryRepository keeps all data in process memory. It mirrors the behaviour
+// of the PostgreSQL repository and is intended for local development and tests.
+type MemoryRepository struct {
+	mu           sync.RWMutex
+	teams        map[string]struct{}
+	users        map[string]domain.User
+	pullRequests map[string]domain.PullRequest
+	idempotency  map[string]domain.IdempotencyRecord
+	apiTokens    map[string]domain.APIToken
+	audit        []domain.AuditEntry
+	webhooks     map[string]domain.WebhookSubscription
+	deliveries   map[string]domain.WebhookDelivery
+	outbox       []domain.OutboxEvent
+	outboxSeq    int64
+	mappings     map[mappingKey]domain.UserMapping
+	codeOwners   map[string]domain.CodeOwners
+}
+
+type mappingKey struct{ provider, login string }
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		teams:        make(map[string]struct{}),
+		users:        make(map[string]domain.User),
+		pullRequests: make(map[string]domain.PullRequest),
+		idempotency:  make(map[string]domain.IdempotencyRecord),
+		apiTokens:    make(map[string]domain.APIToken),
+		webhooks:     make(map[string]domain.WebhookSubscription),
+		deliveries:   make(map[string]domain.WebhookDelivery),
+		mappings:     make(map[mappingKey]domain.UserMapping),
+		codeOwners:   make(map[string]domain.CodeOwners),
+	}
+}
+
+// Ping and CheckMigrations always succeed: there is no connection or schema to lose.
+func (r *MemoryRepository) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (r *MemoryRepository) CheckMigrations(ctx context.Context) error {
+	return nil
+}
+
+func (r *MemoryRepository) Close() error {
+	return nil
+}
+
+func (r *MemoryRepository) CreateOrUpdateTeam(ctx context.Context, team domain.Team) (domain.Team, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, teamExisted := r.teams[team.TeamName]
+	var before *domain.Team
+	if teamExisted {
+		t := r.team(team.TeamName)
+		before = &t
+	}
+	r.teams[team.TeamName] = struct{}{}
+
+	for _, member := range team.Members {
+		if _, ok := r.teams[member.TeamName]; !ok {
+			if !teamExisted {
+				delete(r.teams, team.TeamName)
+			}
+			return domain.Team{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("Team %s not found for user %s", member.TeamName, member.UserID))
+		}
+	}
+
+	for _, member := range team.Members {
+		r.users[member.UserID] = member
+	}
+
+	r.recordEvents(domain.Event{Type: domain.EventTeamUpdated, OccurredAt: time.Now().UTC(), Team: &team})
+	r.recordAudit(ctx, func(entry domain.AuditEntry) domain.AuditEntry { return domain.TeamAudit(entry, before, team) })
+	return team, nil
+}
+
+func (r *MemoryRepository) GetTeamByName(ctx context.Context, teamName string) (domain.Team, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.teams[teamName]; !ok {
+		return domain.Team{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("Team %s not found", teamName))
+	}
+	return r.team(teamName), nil
+}
+
+// team returns an existing team with its members; the caller holds the lock.
+func (r *MemoryRepository) team(teamName string) domain.Team {
+	team := domain.Team{TeamName: teamName}
+	for _, user := range r.users {
+		if user.TeamName == teamName {
+			team.Members = append(team.Members, user)
+		}
+	}
+	sort.Slice(team.Members, func(i, j int) bool { return team.Members[i].UserID < team.Members[j].UserID })
+	return team
+}
+
+func (r *MemoryRepository) GetUserByID(ctx context.Context, userID string) (domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	u, ok := r.users[userID]
+	if !ok {
+		return domain.User{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("User %s not found", userID))
+	}
+	return u, nil
+}
+
+func (r *MemoryRepository) SetUserIsActive(ctx context.Context, userID string, isActive bool) (domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[userID]
+	if !ok {
+		return domain.User{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("User %s not found for update", userID))
+	}
+	before := u
+	u.IsActive = isActive
+	r.users[userID] = u
+
+	if before.IsActive != isActive {
+		r.recordEvents(domain.Event{Type: domain.EventUserActivityChanged, OccurredAt: time.Now().UTC(), User: &u})
+		r.recordAudit(ctx, func(entry domain.AuditEntry) domain.AuditEntry { return domain.UserAudit(entry, before, u) })
+	}
+	return u, nil
+}
+
+func (r *MemoryRepository) CreatePullRequest(ctx context.Context, pr domain.PullRequest) (domain.PullRequest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.pullRequests[pr.PullRequestID]; ok {
+		return domain.PullRequest{}, domain.NewBusinessError(domain.ErrPRExists, fmt.Sprintf("Pull Request with ID %s already exists", pr.PullRequestID))
+	}
+	if _, ok := r.users[pr.AuthorID]; !ok {
+		return domain.PullRequest{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("Author %s not found", pr.AuthorID))
+	}
+
+	pr.Version = 1
+	r.pullRequests[pr.PullRequestID] = clonePullRequest(pr)
+
+	r.recordEvents(domain.PullRequestEvents(nil, pr, time.Now().UTC())...)
+	r.recordAudit(ctx, func(entry domain.AuditEntry) domain.AuditEntry { return domain.PullRequestAudit(entry, nil, pr) })
+	return pr, nil
+}
+
+func (r *MemoryRepository) GetPullRequestByID(ctx context.Context, prID string) (domain.PullRequest, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	pr, ok := r.pullRequests[prID]
+	if !ok {
+		return domain.PullRequest{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("Pull Request %s not found", prID))
+	}
+	return clonePullRequest(pr), nil
+}
+
+func (r *MemoryRepository) UpdatePullRequest(ctx context.Context, pr domain.PullRequest) (domain.PullRequest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, ok := r.pullRequests[pr.PullRequestID]
+	if !ok {
+		return domain.PullRequest{}, domain.NewBusinessError(domain.ErrNotFound, "Pull Request not found for update")
+	}
+	if current.Version != pr.Version {
+		slog.WarnContext(ctx, "pull request version conflict", "pr_id", pr.PullRequestID, "version", pr.Version)
+		return domain.PullRequest{}, domain.NewBusinessError(domain.ErrVersionConflict,
+			fmt.Sprintf("Pull Request %s was modified concurrently", pr.PullRequestID))
+	}
+	if _, ok := r.users[pr.AuthorID]; !ok {
+		return domain.PullRequest{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("Author %s not found", pr.AuthorID))
+	}
+
+	pr.CreatedAt = current.CreatedAt
+	pr.ChangedPaths = current.ChangedPaths
+	pr.Labels = current.Labels
+	pr.Version = current.Version + 1
+	r.pullRequests[pr.PullRequestID] = clonePullRequest(pr)
+
+	r.recordEvents(domain.PullRequestEvents(&current, pr, time.Now().UTC())...)
+	r.recordAudit(ctx, func(entry domain.AuditEntry) domain.AuditEntry { return domain.PullRequestAudit(entry, &current, pr) })
+	return pr, nil
+}
+
+func (r *MemoryRepository) GetPRsByReviewerID(ctx context.Context, userID string) ([]domain.PullRequestShort, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []domain.PullRequest
+	for _, pr := range r.pullRequests {
+		for _, reviewerID := range pr.AssignedReviewers {
+			if reviewerID == userID {
+				matched = append(matched, pr)
+				break
+			}
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return createdAt(matched[i]).After(createdAt(matched[j]))
+	})
+
+	var prs []domain.PullRequestShort
+	for _, pr := range matched {
+		prs = append(prs, domain.PullRequestShort{
+			PullRequestID:   pr.PullRequestID,
+			PullRequestName: pr.PullRequestName,
+			AuthorID:        pr.AuthorID,
+			Status:          pr.Status,
+		})
+	}
+
+	return prs, nil
+}
+
+func (r *MemoryRepository) GetReviewStats(ctx context.Context) (domain.ReviewStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := domain.ReviewStats{OpenReviewsByUser: make(map[string]int)}
+	for _, pr := range r.pullRequests {
+		switch pr.Status {
+		case domain.StatusOpen:
+			stats.OpenPRs++
+			if len(pr.AssignedReviewers) == 0 {
+				stats.OpenPRsWithoutReviewers++
+			}
+			for _, reviewerID := range pr.AssignedReviewers {
+				stats.OpenReviewsByUser[reviewerID]++
+			}
+		case domain.StatusMerged:
+			stats.MergedPRs++
+		}
+	}
+
+	return stats, nil
+}
+
+func (r *MemoryRepository) ListReviewedPullRequests(ctx context.Context, since time.Time) ([]domain.PullRequest, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var prs []domain.PullRequest
+	for _, pr := range r.pullRequests {
+		if len(pr.AssignedReviewers) > 0 && !createdAt(pr).Before(since) {
+			prs = append(prs, clonePullRequest(pr))
+		}
+	}
+	sort.Slice(prs, func(i, j int) bool {
+		if !createdAt(prs[i]).Equal(createdAt(prs[j])) {
+			return createdAt(prs[i]).Before(createdAt(prs[j]))
+		}
+		return prs[i].PullRequestID < prs[j].PullRequestID
+	})
+	return prs, nil
+}
+
+func (r *MemoryRepository) ReserveIdempotencyKey(ctx context.Context, rec domain.IdempotencyRecord) (domain.IdempotencyRecord, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if stored, ok := r.idempotency[rec.Key]; ok && stored.ExpiresAt.After(rec.CreatedAt) {
+		return stored, false, nil
+	}
+
+	rec.StatusCode = 0
+	rec.Headers = nil
+	rec.Body = nil
+	r.idempotency[rec.Key] = rec
+
+	return rec, true, nil
+}
+
+func (r *MemoryRepository) CompleteIdempotencyKey(ctx context.Context, rec domain.IdempotencyRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.idempotency[rec.Key]
+	if !ok || stored.RequestHash != rec.RequestHash {
+		return nil
+	}
+	stored.StatusCode = rec.StatusCode
+	stored.Headers = rec.Headers
+	stored.Body = append([]byte(nil), rec.Body...)
+	r.idempotency[rec.Key] = stored
+
+	return nil
+}
+
+func (r *MemoryRepository) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if stored, ok := r.idempotency[key]; ok && stored.StatusCode == 0 {
+		delete(r.idempotency, key)
+	}
+	return nil
+}
+
+func (r *MemoryRepository) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for key, rec := range r.idempotency {
+		if !rec.ExpiresAt.After(now) {
+			delete(r.idempotency, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (r *MemoryRepository) CreateAPIToken(ctx context.Context, token domain.APIToken) (domain.APIToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.apiTokens {
+		if existing.ID == token.ID || existing.TokenHash == token.TokenHash {
+			return domain.APIToken{}, domain.NewBusinessError(domain.ErrTokenExists, "API token already exists")
+		}
+	}
+	token.RevokedAt = nil
+	r.apiTokens[token.ID] = cloneAPIToken(token)
+	return cloneAPIToken(token), nil
+}
+
+func (r *MemoryRepository) GetAPITokenByHash(ctx context.Context, tokenHash string) (domain.APIToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, token := range r.apiTokens {
+		if token.TokenHash == tokenHash {
+			return cloneAPIToken(token), nil
+		}
+	}
+	return domain.APIToken{}, domain.NewBusinessError(domain.ErrNotFound, "API token not found")
+}
+
+func (r *MemoryRepository) ListAPITokens(ctx context.Context) ([]domain.APIToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tokens := make([]domain.APIToken, 0, len(r.apiTokens))
+	for _, token := range r.apiTokens {
+		tokens = append(tokens, cloneAPIToken(token))
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		if !tokens[i].CreatedAt.Equal(tokens[j].CreatedAt) {
+			return tokens[i].CreatedAt.Before(tokens[j].CreatedAt)
+		}
+		return tokens[i].ID < tokens[j].ID
+	})
+	return tokens, nil
+}
+
+func (r *MemoryRepository) RevokeAPIToken(ctx context.Context, id string, at time.Time) (domain.APIToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.apiTokens[id]
+	if !ok {
+		return domain.APIToken{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("API token %s not found", id))
+	}
+	if token.RevokedAt == nil {
+		token.RevokedAt = &at
+		r.apiTokens[id] = token
+	}
+	return cloneAPIToken(token), nil
+}
+
+func cloneAPIToken(token domain.APIToken) domain.APIToken {
+	token.Roles = append([]domain.Role{}, token.Roles...)
+	if token.ExpiresAt != nil {
+		t := *token.ExpiresAt
+		token.ExpiresAt = &t
+	}
+	if token.RevokedAt != nil {
+		t := *token.RevokedAt
+		token.RevokedAt = &t
+	}
+	return token
+}
+
+func (r *MemoryRepository) AppendAuditEntry(ctx context.Context, entry domain.AuditEntry) (domain.AuditEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return cloneAuditEntry(r.appendAudit(entry)), nil
+}
+
+// recordAudit appends the entry requested with repository.WithAudit, completed
+// by complete; the caller holds the write lock.
+func (r *MemoryRepository) recordAudit(ctx context.Context, complete func(domain.AuditEntry) domain.AuditEntry) {
+	if entry, ok := repository.AuditFromContext(ctx); ok {
+		r.appendAudit(complete(entry))
+	}
+}
+
+func (r *MemoryRepository) appendAudit(entry domain.AuditEntry) domain.AuditEntry {
+	entry.ID = int64(len(r.audit) + 1)
+	r.audit = append(r.audit, cloneAuditEntry(entry))
+	return entry
+}
+
+func (r *MemoryRepository) ListAuditEntries(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := []domain.AuditEntry{}
+	for i := len(r.audit) - 1; i >= 0 && (filter.Limit <= 0 || len(entries) < filter.Limit); i-- {
+		entry := r.audit[i]
+		if filter.Actor != "" && entry.Actor != filter.Actor {
+			continue
+		}
+		if filter.Target != "" && !slices.Contains(entry.TargetIDs, filter.Target) {
+			continue
+		}
+		if filter.From != nil && entry.CreatedAt.Before(*filter.From) {
+			continue
+		}
+		if filter.To != nil && !entry.CreatedAt.Before(*filter.To) {
+			continue
+		}
+		entries = append(entries, cloneAuditEntry(entry))
+	}
+	return entries, nil
+}
+
+func cloneAuditEntry(entry domain.AuditEntry) domain.AuditEntry {
+	entry.TargetIDs = append([]string{}, entry.TargetIDs...)
+	entry.Before = append(json.RawMessage(nil), entry.Before...)
+	entry.After = append(json.RawMessage(nil), entry.After...)
+	return entry
+}
+
+func (r *MemoryRepository) CreateWebhookSubscription(ctx context.Context, sub domain.WebhookSubscription) (domain.WebhookSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.webhooks[sub.ID]; exists {
+		return domain.WebhookSubscription{}, domain.NewBusinessError(domain.ErrWebhookExists, "Webhook subscription already exists")
+	}
+	sub.Events = slices.Clone(sub.Events)
+	r.webhooks[sub.ID] = sub
+	sub.Events = slices.Clone(sub.Events)
+	return sub, nil
+}
+
+func (r *MemoryRepository) ListWebhookSubscriptions(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	subs := make([]domain.WebhookSubscription, 0, len(r.webhooks))
+	for _, sub := range r.webhooks {
+		sub.Events = slices.Clone(sub.Events)
+		subs = append(subs, sub)
+	}
+	sort.Slice(subs, func(i, j int) bool {
+		if !subs[i].CreatedAt.Equal(subs[j].CreatedAt) {
+			return subs[i].CreatedAt.Before(subs[j].CreatedAt)
+		}
+		return subs[i].ID < subs[j].ID
+	})
+	return subs, nil
+}
+
+func (r *MemoryRepository) DeleteWebhookSubscription(ctx context.Context, id string) (domain.WebhookSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sub, ok := r.webhooks[id]
+	if !ok {
+		return domain.WebhookSubscription{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("Webhook subscription %s not found", id))
+	}
+	delete(r.webhooks, id)
+	for deliveryID, d := range r.deliveries {
+		if d.SubscriptionID == id {
+			delete(r.deliveries, deliveryID)
+		}
+	}
+	return sub, nil
+}
+
+func (r *MemoryRepository) CreateWebhookDeliveries(ctx context.Context, deliveries []domain.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, d := range deliveries {
+		if _, ok := r.webhooks[d.SubscriptionID]; !ok {
+			return fmt.Errorf("webhook subscription %s does not exist", d.SubscriptionID)
+		}
+	}
+	for _, d := range deliveries {
+		d.URL, d.Secret = "", ""
+		d.Payload = slices.Clone(d.Payload)
+		r.deliveries[d.ID] = d
+	}
+	return nil
+}
+
+func (r *MemoryRepository) ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []domain.WebhookDelivery
+	for _, d := range r.deliveries {
+		if d.Status == domain.DeliveryPending && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	sortDeliveries(due, false)
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	for i, d := range due {
+		d.NextAttemptAt = now.Add(lease)
+		r.deliveries[d.ID] = d
+		due[i] = r.withSubscription(d)
+	}
+	return due, nil
+}
+
+func (r *MemoryRepository) UpdateWebhookDelivery(ctx context.Context, delivery domain.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d, ok := r.deliveries[delivery.ID]
+	if !ok {
+		return domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("Webhook delivery %s not found", delivery.ID))
+	}
+	d.Status = delivery.Status
+	d.Attempts = delivery.Attempts
+	d.NextAttemptAt = delivery.NextAttemptAt
+	d.LastError = delivery.LastError
+	d.DeliveredAt = delivery.DeliveredAt
+	r.deliveries[d.ID] = d
+	return nil
+}
+
+func (r *MemoryRepository) ListWebhookDeliveries(ctx context.Context, status string, limit int) ([]domain.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	deliveries := []domain.WebhookDelivery{}
+	for _, d := range r.deliveries {
+		if d.Status == status {
+			deliveries = append(deliveries, r.withSubscription(d))
+		}
+	}
+	sortDeliveries(deliveries, true)
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+func (r *MemoryRepository) RequeueWebhookDelivery(ctx context.Context, id string, now time.Time) (domain.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d, ok := r.deliveries[id]
+	if !ok || d.Status != domain.DeliveryDead {
+		return domain.WebhookDelivery{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("Dead webhook delivery %s not found", id))
+	}
+	d.Status = domain.DeliveryPending
+	d.Attempts = 0
+	d.NextAttemptAt = now
+	r.deliveries[id] = d
+	return r.withSubscription(d), nil
+}
+
+func (r *MemoryRepository) DeleteDeliveredWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, d := range r.deliveries {
+		if d.Status == domain.DeliveryDelivered && d.DeliveredAt != nil && d.DeliveredAt.Before(before) {
+			delete(r.deliveries, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (r *MemoryRepository) withSubscription(d domain.WebhookDelivery) domain.WebhookDelivery {
+	sub := r.webhooks[d.SubscriptionID]
+	d.URL, d.Secret = sub.URL, sub.Secret
+	d.Payload = slices.Clone(d.Payload)
+	if d.DeliveredAt != nil {
+		t := *d.DeliveredAt
+		d.DeliveredAt = &t
+	}
+	return d
+}
+
+func sortDeliveries(deliveries []domain.WebhookDelivery, newestFirst bool) {
+	sort.Slice(deliveries, func(i, j int) bool {
+		a, b := deliveries[i], deliveries[j]
+		if newestFirst {
+			a, b = b, a
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
+}
+
+// recordEvents appends events to the outbox; the caller holds the write lock,
+// which makes the append atomic with the change the events describe.
+func (r *MemoryRepository) recordEvents(events ...domain.Event) {
+	for _, event := range events {
+		r.outboxSeq++
+		event.ID = strconv.FormatInt(r.outboxSeq, 10)
+		r.outbox = append(r.outbox, cloneOutboxEvent(domain.OutboxEvent{
+			Event:         event,
+			Seq:           r.outboxSeq,
+			AggregateID:   event.AggregateID(),
+			NextAttemptAt: event.OccurredAt,
+		}))
+	}
+}
+
+func (r *MemoryRepository) ClaimOutboxEvents(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.OutboxEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// The outbox is ordered by Seq, so the first unpublished event of an
+	// aggregate is its head.
+	heads := make(map[string]domain.OutboxEvent)
+	for _, e := range r.outbox {
+		if _, ok := heads[e.AggregateID]; !ok && e.PublishedAt == nil {
+			heads[e.AggregateID] = e
+		}
+	}
+
+	claimed := []domain.OutboxEvent{}
+	for i := range r.outbox {
+		if len(claimed) == limit {
+			break
+		}
+		e := &r.outbox[i]
+		if e.PublishedAt != nil || heads[e.AggregateID].NextAttemptAt.After(now) {
+			continue
+		}
+		e.NextAttemptAt = now.Add(lease)
+		claimed = append(claimed, cloneOutboxEvent(*e))
+	}
+	return claimed, nil
+}
+
+func (r *MemoryRepository) UpdateOutboxEvent(ctx context.Context, event domain.OutboxEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, ok := slices.BinarySearchFunc(r.outbox, event.Seq, func(e domain.OutboxEvent, seq int64) int {
+		return cmp.Compare(e.Seq, seq)
+	})
+	if !ok {
+		return domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("Outbox event %d not found", event.Seq))
+	}
+	stored := &r.outbox[i]
+	stored.Attempts = event.Attempts
+	stored.NextAttemptAt = event.NextAttemptAt
+	stored.LastError = event.LastError
+	stored.PublishedSinks = slices.Clone(event.PublishedSinks)
+	stored.PublishedAt = cloneTime(event.PublishedAt)
+	return nil
+}
+
+func (r *MemoryRepository) DeletePublishedOutboxEvents(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := len(r.outbox)
+	r.outbox = slices.DeleteFunc(r.outbox, func(e domain.OutboxEvent) bool {
+		return e.PublishedAt != nil && e.PublishedAt.Before(before)
+	})
+	return int64(n - len(r.outbox)), nil
+}
+
+func (r *MemoryRepository) SetUserMapping(ctx context.Context, m domain.UserMapping) (domain.UserMapping, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := mappingKey{m.Provider, m.Login}
+	if existing, ok := r.mappings[key]; ok {
+		m.CreatedAt = existing.CreatedAt
+	}
+	r.mappings[key] = m
+	return m, nil
+}
+
+func (r *MemoryRepository) GetUserMapping(ctx context.Context, provider, login string) (domain.UserMapping, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	m, ok := r.mappings[mappingKey{provider, login}]
+	if !ok {
+		return domain.UserMapping{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("No user is mapped to %s login %s", provider, login))
+	}
+	return m, nil
+}
+
+func (r *MemoryRepository) ListUserMappings(ctx context.Context, provider string) ([]domain.UserMapping, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	mappings := []domain.UserMapping{}
+	for _, m := range r.mappings {
+		if provider == "" || m.Provider == provider {
+			mappings = append(mappings, m)
+		}
+	}
+	sort.Slice(mappings, func(i, j int) bool {
+		if mappings[i].Provider != mappings[j].Provider {
+			return mappings[i].Provider < mappings[j].Provider
+		}
+		return mappings[i].Login < mappings[j].Login
+	})
+	return mappings, nil
+}
+
+func (r *MemoryRepository) DeleteUserMapping(ctx context.Context, provider, login string) (domain.UserMapping, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := mappingKey{provider, login}
+	m, ok := r.mappings[key]
+	if !ok {
+		return domain.UserMapping{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("No user is mapped to %s login %s", provider, login))
+	}
+	delete(r.mappings, key)
+	return m, nil
+}
+
+func (r *MemoryRepository) SetCodeOwners(ctx context.Context, owners domain.CodeOwners) (domain.CodeOwners, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.teams[owners.TeamName]; !ok {
+		return domain.CodeOwners{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("Team %s not found", owners.TeamName))
+	}
+	var before *domain.CodeOwners
+	if current, ok := r.codeOwners[owners.TeamName]; ok {
+		before = &current
+	}
+	r.codeOwners[owners.TeamName] = owners
+	r.recordAudit(ctx, func(entry domain.AuditEntry) domain.AuditEntry { return domain.CodeOwnersAudit(entry, before, owners) })
+	return owners, nil
+}
+
+func (r *MemoryRepository) GetCodeOwners(ctx context.Context, teamName string) (domain.CodeOwners, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	owners, ok := r.codeOwners[teamName]
+	if !ok {
+		return domain.CodeOwners{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("Team %s has no CODEOWNERS rules", teamName))
+	}
+	return owners, nil
+}
+
+func cloneOutboxEvent(e domain.OutboxEvent) domain.OutboxEvent {
+	if e.PullRequest != nil {
+		pr := clonePullRequest(*e.PullRequest)
+		e.PullRequest = &pr
+	}
+	if e.Team != nil {
+		team := *e.Team
+		team.Members = append([]domain.User(nil), team.Members...)
+		e.Team = &team
+	}
+	if e.User != nil {
+		u := *e.User
+		e.User = &u
+	}
+	e.PublishedSinks = slices.Clone(e.PublishedSinks)
+	e.PublishedAt = cloneTime(e.PublishedAt)
+	return e
+}
+
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
+
+func clonePullRequest(pr domain.PullRequest) domain.PullRequest {
+	pr.AssignedReviewers = append([]string{}, pr.AssignedReviewers...)
+	pr.ChangedPaths = slices.Clone(pr.ChangedPaths)
+	pr.Labels = slices.Clone(pr.Labels)
+	if pr.CreatedAt != nil {
+		t := *pr.CreatedAt
+		pr.CreatedAt = &t
+	}
+	if pr.MergedAt != nil {
+		t := *pr.MergedAt
+		pr.MergedAt = &t
+	}
+	return pr
+}
+
+func createdAt(pr domain.PullRequest) time.Time {
+	if pr.CreatedAt == nil {
+		return time.Time{}
+	}
+	return *pr.CreatedAt
+}
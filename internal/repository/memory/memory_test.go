@@ -0,0 +1,29 @@
+package memory_test
+
+import (
+	"testing"
+
+	"Backend/internal/repository"
+	"Backend/internal/repository/memory"
+	"Backend/internal/repository/repotest"
+)
+
+var _ repository.TeamRepository = (*memory.MemoryRepository)(nil)
+var _ repository.PullRequestRepository = (*memory.MemoryRepository)(nil)
+var _ repository.IdempotencyRepository = (*memory.MemoryRepository)(nil)
+var _ repository.StatsRepository = (*memory.MemoryRepository)(nil)
+var _ repository.HealthRepository = (*memory.MemoryRepository)(nil)
+var _ repository.TokenRepository = (*memory.MemoryRepository)(nil)
+var _ repository.AuditRepository = (*memory.MemoryRepository)(nil)
+var _ repository.WebhookRepository = (*memory.MemoryRepository)(nil)
+var _ repository.OutboxRepository = (*memory.MemoryRepository)(nil)
+var _ repository.UserMappingRepository = (*memory.MemoryRepository)(nil)
+var _ repository.CodeOwnersRepository = (*memory.MemoryRepository)(nil)
+var _ repository.ReviewHistoryRepository = (*memory.MemoryRepository)(nil)
+
+func TestMemoryRepository(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repositories {
+		repo := memory.NewMemoryRepository()
+		return repotest.Repositories{Teams: repo, PRs: repo, Idempotency: repo, Stats: repo, Health: repo, Tokens: repo, Audit: repo, Webhooks: repo, Outbox: repo, Mappings: repo, CodeOwners: repo, History: repo}
+	})
+}
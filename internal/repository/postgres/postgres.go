@@ -2,23 +2,51 @@ package postgres
 
 import (
 	"Backend/internal/domain"
+	"Backend/internal/repository"
+	"Backend/internal/tracing"
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
 	"time"
 
 	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type PostgresRepository struct {
 	db *sql.DB
 }
 
+// schemaVersion is recorded by Init and must be bumped whenever Init changes the schema.
+const schemaVersion = 10
+
+var tracer = otel.Tracer("Backend/internal/repository/postgres")
+
+// startSpan starts a client span for one repository operation; the queries it
+// issues run inside that span.
+func startSpan(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "postgres."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", "postgresql"),
+			attribute.String("db.operation.name", operation),
+		))
+}
+
 func NewPostgresRepository(db *sql.DB) *PostgresRepository {
 	return &PostgresRepository{db: db}
 }
 
-func (r *PostgresRepository) Init(ctx context.Context) error {
+func (r *PostgresRepository) Init(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, "Init")
+	defer func() { tracing.End(span, err) }()
+
 	const createSchemas = `
 	CREATE TABLE IF NOT EXISTS teams (
 		team_name TEXT PRIMARY KEY
@@ -41,28 +69,177 @@ func (r *PostgresRepository) Init(ctx context.Context) error {
 		merged_at TIMESTAMPTZ
 	);
 	CREATE INDEX IF NOT EXISTS idx_pr_reviewer ON pull_requests USING GIN (assigned_reviewers);
+
+	ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+	ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS changed_paths TEXT[] NOT NULL DEFAULT '{}';
+	ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS labels TEXT[] NOT NULL DEFAULT '{}';
+	ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS code_host_ref TEXT NOT NULL DEFAULT '';
+	CREATE INDEX IF NOT EXISTS idx_pr_created ON pull_requests (created_at);
+
+	CREATE TABLE IF NOT EXISTS idempotency_keys (
+		key TEXT PRIMARY KEY,
+		request_hash TEXT NOT NULL,
+		status_code INTEGER NOT NULL DEFAULT 0,
+		headers JSONB,
+		body BYTEA,
+		created_at TIMESTAMPTZ NOT NULL,
+		expires_at TIMESTAMPTZ NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_idempotency_expires ON idempotency_keys (expires_at);
+
+	CREATE TABLE IF NOT EXISTS api_tokens (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		user_id TEXT,
+		roles TEXT[] NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL,
+		expires_at TIMESTAMPTZ,
+		revoked_at TIMESTAMPTZ
+	);
+
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id BIGSERIAL PRIMARY KEY,
+		actor TEXT NOT NULL,
+		action TEXT NOT NULL,
+		target_ids TEXT[] NOT NULL,
+		before_state JSONB,
+		after_state JSONB,
+		request_id TEXT,
+		created_at TIMESTAMPTZ NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_audit_actor ON audit_log (actor, id);
+	CREATE INDEX IF NOT EXISTS idx_audit_targets ON audit_log USING GIN (target_ids);
+	CREATE INDEX IF NOT EXISTS idx_audit_created ON audit_log (created_at);
+
+	CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+		id TEXT PRIMARY KEY,
+		url TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		events TEXT[] NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id TEXT PRIMARY KEY,
+		subscription_id TEXT NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+		event_id TEXT NOT NULL,
+		event_type TEXT NOT NULL,
+		payload JSONB NOT NULL,
+		status TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMPTZ NOT NULL,
+		last_error TEXT,
+		created_at TIMESTAMPTZ NOT NULL,
+		delivered_at TIMESTAMPTZ
+	);
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription ON webhook_deliveries (subscription_id);
+
+	CREATE TABLE IF NOT EXISTS outbox (
+		id BIGSERIAL PRIMARY KEY,
+		aggregate_id TEXT NOT NULL,
+		event_type TEXT NOT NULL,
+		payload JSONB NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMPTZ NOT NULL,
+		last_error TEXT,
+		created_at TIMESTAMPTZ NOT NULL,
+		published_at TIMESTAMPTZ
+	);
+	CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox (aggregate_id, id) WHERE published_at IS NULL;
+	CREATE INDEX IF NOT EXISTS idx_outbox_published ON outbox (published_at);
+	ALTER TABLE outbox ADD COLUMN IF NOT EXISTS published_sinks TEXT[] NOT NULL DEFAULT '{}';
+
+	CREATE TABLE IF NOT EXISTS user_mappings (
+		provider TEXT NOT NULL,
+		login TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (provider, login)
+	);
+
+	CREATE TABLE IF NOT EXISTS team_codeowners (
+		team_name TEXT PRIMARY KEY REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE CASCADE,
+		rules TEXT NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS schema_version (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		version INTEGER NOT NULL
+	);
 	`
 
-	_, err := r.db.ExecContext(ctx, createSchemas)
+	_, err = r.db.ExecContext(ctx, createSchemas)
 	if err != nil {
 		return fmt.Errorf("failed to initialize database schema: %w", err)
 	}
+
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO schema_version (id, version) VALUES (1, $1)
+		 ON CONFLICT (id) DO UPDATE SET version = GREATEST(schema_version.version, EXCLUDED.version)`, schemaVersion)
+	if err != nil {
+		return fmt.Errorf("failed to record schema version: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresRepository) Ping(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, "Ping")
+	defer func() { tracing.End(span, err) }()
+
+	return r.db.PingContext(ctx)
+}
+
+func (r *PostgresRepository) CheckMigrations(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, "CheckMigrations")
+	defer func() { tracing.End(span, err) }()
+
+	var version int
+	err = r.db.QueryRowContext(ctx, "SELECT version FROM schema_version WHERE id = 1").Scan(&version)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("schema version is not recorded")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if version < schemaVersion {
+		return fmt.Errorf("schema is at version %d, expected %d", version, schemaVersion)
+	}
 	return nil
 }
 
-func (r *PostgresRepository) CreateOrUpdateTeam(ctx context.Context, team domain.Team) (domain.Team, error) {
+func (r *PostgresRepository) Close() error {
+	return r.db.Close()
+}
+
+func (r *PostgresRepository) CreateOrUpdateTeam(ctx context.Context, team domain.Team) (_ domain.Team, err error) {
+	ctx, span := startSpan(ctx, "CreateOrUpdateTeam")
+	defer func() { tracing.End(span, err) }()
+
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return domain.Team{}, err
 	}
 	defer tx.Rollback()
 
-	_, err = tx.ExecContext(ctx,
-		"INSERT INTO teams (team_name) VALUES ($1) ON CONFLICT (team_name) DO NOTHING",
-		team.TeamName)
-	if err != nil {
+	err = tx.QueryRowContext(ctx,
+		"INSERT INTO teams (team_name) VALUES ($1) ON CONFLICT (team_name) DO NOTHING RETURNING team_name",
+		team.TeamName).Scan(new(string))
+	created := err == nil
+	if err != nil && err != sql.ErrNoRows {
 		return domain.Team{}, fmt.Errorf("failed to upsert team: %w", err)
 	}
+	if err = lockTeam(ctx, tx, team.TeamName); err != nil {
+		return domain.Team{}, err
+	}
+	var before *domain.Team
+	if !created {
+		if before, err = queryTeam(ctx, tx, team.TeamName); err != nil {
+			return domain.Team{}, err
+		}
+	}
 
 	for _, member := range team.Members {
 		_, err := tx.ExecContext(ctx,
@@ -81,6 +258,16 @@ func (r *PostgresRepository) CreateOrUpdateTeam(ctx context.Context, team domain
 		}
 	}
 
+	event := domain.Event{Type: domain.EventTeamUpdated, OccurredAt: time.Now().UTC(), Team: &team}
+	if err = insertOutboxEvents(ctx, tx, event); err != nil {
+		return domain.Team{}, err
+	}
+	if err = insertRequestedAudit(ctx, tx, func(entry domain.AuditEntry) domain.AuditEntry {
+		return domain.TeamAudit(entry, before, team)
+	}); err != nil {
+		return domain.Team{}, err
+	}
+
 	if err = tx.Commit(); err != nil {
 		return domain.Team{}, fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -88,20 +275,35 @@ func (r *PostgresRepository) CreateOrUpdateTeam(ctx context.Context, team domain
 	return team, nil
 }
 
-func (r *PostgresRepository) GetTeamByName(ctx context.Context, teamName string) (domain.Team, error) {
+func (r *PostgresRepository) GetTeamByName(ctx context.Context, teamName string) (_ domain.Team, err error) {
+	ctx, span := startSpan(ctx, "GetTeamByName")
+	defer func() { tracing.End(span, err) }()
+
+	team, err := queryTeam(ctx, r.db, teamName)
+	if err != nil {
+		return domain.Team{}, err
+	}
+	if team == nil {
+		return domain.Team{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("Team %s not found", teamName))
+	}
+	return *team, nil
+}
+
+// queryTeam returns the team with its members, or nil if it does not exist.
+func queryTeam(ctx context.Context, q querier, teamName string) (*domain.Team, error) {
 	var tName string
-	err := r.db.QueryRowContext(ctx, "SELECT team_name FROM teams WHERE team_name = $1", teamName).Scan(&tName)
+	err := q.QueryRowContext(ctx, "SELECT team_name FROM teams WHERE team_name = $1", teamName).Scan(&tName)
 	if err == sql.ErrNoRows {
-		return domain.Team{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("Team %s not found", teamName))
+		return nil, nil
 	}
 	if err != nil {
-		return domain.Team{}, fmt.Errorf("error querying team: %w", err)
+		return nil, fmt.Errorf("error querying team: %w", err)
 	}
 
-	rows, err := r.db.QueryContext(ctx,
+	rows, err := q.QueryContext(ctx,
 		"SELECT user_id, username, team_name, is_active FROM users WHERE team_name = $1", teamName)
 	if err != nil {
-		return domain.Team{}, fmt.Errorf("error querying team members: %w", err)
+		return nil, fmt.Errorf("error querying team members: %w", err)
 	}
 	defer rows.Close()
 
@@ -109,24 +311,27 @@ func (r *PostgresRepository) GetTeamByName(ctx context.Context, teamName string)
 	for rows.Next() {
 		var member domain.User
 		if err := rows.Scan(&member.UserID, &member.Username, &member.TeamName, &member.IsActive); err != nil {
-			return domain.Team{}, fmt.Errorf("error scanning team member: %w", err)
+			return nil, fmt.Errorf("error scanning team member: %w", err)
 		}
 		team.Members = append(team.Members, member)
 	}
 
 	if err := rows.Err(); err != nil {
-		return domain.Team{}, fmt.Errorf("error iterating team members: %w", err)
+		return nil, fmt.Errorf("error iterating team members: %w", err)
 	}
 
-	return team, nil
+	return &team, nil
 }
 
-func (r *PostgresRepository) GetUserByID(ctx context.Context, userID string) (domain.User, error) {
+func (r *PostgresRepository) GetUserByID(ctx context.Context, userID string) (_ domain.User, err error) {
+	ctx, span := startSpan(ctx, "GetUserByID")
+	defer func() { tracing.End(span, err) }()
+
 	var u domain.User
 	row := r.db.QueryRowContext(ctx,
 		"SELECT user_id, username, team_name, is_active FROM users WHERE user_id = $1", userID)
 
-	err := row.Scan(&u.UserID, &u.Username, &u.TeamName, &u.IsActive)
+	err = row.Scan(&u.UserID, &u.Username, &u.TeamName, &u.IsActive)
 
 	if err == sql.ErrNoRows {
 		return domain.User{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("User %s not found", userID))
@@ -137,31 +342,90 @@ func (r *PostgresRepository) GetUserByID(ctx context.Context, userID string) (do
 	return u, nil
 }
 
-func (r *PostgresRepository) SetUserIsActive(ctx context.Context, userID string, isActive bool) (domain.User, error) {
-	result, err := r.db.ExecContext(ctx,
-		"UPDATE users SET is_active = $2 WHERE user_id = $1", userID, isActive)
+func (r *PostgresRepository) SetUserIsActive(ctx context.Context, userID string, isActive bool) (_ domain.User, err error) {
+	ctx, span := startSpan(ctx, "SetUserIsActive")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return domain.User{}, fmt.Errorf("error updating user activity: %w", err)
+		return domain.User{}, err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	var teamName string
+	err = tx.QueryRowContext(ctx, "SELECT team_name FROM users WHERE user_id = $1", userID).Scan(&teamName)
+	if err == sql.ErrNoRows {
+		return domain.User{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("User %s not found for update", userID))
+	}
 	if err != nil {
-		return domain.User{}, fmt.Errorf("error getting rows affected: %w", err)
+		return domain.User{}, fmt.Errorf("error getting user from DB: %w", err)
+	}
+	if err = lockTeam(ctx, tx, teamName); err != nil {
+		return domain.User{}, err
 	}
-	if rowsAffected == 0 {
+
+	var u domain.User
+	var wasActive bool
+	err = tx.QueryRowContext(ctx,
+		`UPDATE users u SET is_active = $2
+		 FROM users old
+		 WHERE u.user_id = $1 AND old.user_id = u.user_id
+		 RETURNING u.user_id, u.username, u.team_name, u.is_active, old.is_active`, userID, isActive).
+		Scan(&u.UserID, &u.Username, &u.TeamName, &u.IsActive, &wasActive)
+	if err == sql.ErrNoRows {
 		return domain.User{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("User %s not found for update", userID))
 	}
+	if err != nil {
+		return domain.User{}, fmt.Errorf("error updating user activity: %w", err)
+	}
 
-	return r.GetUserByID(ctx, userID)
+	if wasActive != isActive {
+		event := domain.Event{Type: domain.EventUserActivityChanged, OccurredAt: time.Now().UTC(), User: &u}
+		if err = insertOutboxEvents(ctx, tx, event); err != nil {
+			return domain.User{}, err
+		}
+		before := u
+		before.IsActive = wasActive
+		if err = insertRequestedAudit(ctx, tx, func(entry domain.AuditEntry) domain.AuditEntry {
+			return domain.UserAudit(entry, before, u)
+		}); err != nil {
+			return domain.User{}, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return domain.User{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return u, nil
 }
 
-func (r *PostgresRepository) CreatePullRequest(ctx context.Context, pr domain.PullRequest) (domain.PullRequest, error) {
+// lockTeam serializes the transactions that record events of a team, so that
+// the events' ids follow the order in which the transactions commit.
+func lockTeam(ctx context.Context, tx *sql.Tx, teamName string) error {
+	if _, err := tx.ExecContext(ctx, "SELECT 1 FROM teams WHERE team_name = $1 FOR UPDATE", teamName); err != nil {
+		return fmt.Errorf("failed to lock team %s: %w", teamName, err)
+	}
+	return nil
+}
+
+func (r *PostgresRepository) CreatePullRequest(ctx context.Context, pr domain.PullRequest) (_ domain.PullRequest, err error) {
+	ctx, span := startSpan(ctx, "CreatePullRequest")
+	defer func() { tracing.End(span, err) }()
+
 	assignedReviewers := pq.Array(pr.AssignedReviewers)
+	pr.Version = 1
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.PullRequest{}, err
+	}
+	defer tx.Rollback()
 
-	_, err := r.db.ExecContext(ctx,
-		`INSERT INTO pull_requests (pr_id, pr_name, author_id, status, assigned_reviewers, created_at, merged_at) 
-		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
-		pr.PullRequestID, pr.PullRequestName, pr.AuthorID, pr.Status, assignedReviewers, pr.CreatedAt, pr.MergedAt)
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO pull_requests (pr_id, pr_name, author_id, status, assigned_reviewers, created_at, merged_at, version, changed_paths, labels, code_host_ref) 
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		pr.PullRequestID, pr.PullRequestName, pr.AuthorID, pr.Status, assignedReviewers, pr.CreatedAt, pr.MergedAt, pr.Version,
+		pq.Array(append([]string{}, pr.ChangedPaths...)), pq.Array(append([]string{}, pr.Labels...)), pr.CodeHostRef)
 
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
@@ -173,38 +437,89 @@ func (r *PostgresRepository) CreatePullRequest(ctx context.Context, pr domain.Pu
 		return domain.PullRequest{}, fmt.Errorf("failed to create PR: %w", err)
 	}
 
+	if err = insertOutboxEvents(ctx, tx, domain.PullRequestEvents(nil, pr, time.Now().UTC())...); err != nil {
+		return domain.PullRequest{}, err
+	}
+	if err = insertRequestedAudit(ctx, tx, func(entry domain.AuditEntry) domain.AuditEntry {
+		return domain.PullRequestAudit(entry, nil, pr)
+	}); err != nil {
+		return domain.PullRequest{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return domain.PullRequest{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return pr, nil
 }
 
-func (r *PostgresRepository) GetPullRequestByID(ctx context.Context, prID string) (domain.PullRequest, error) {
-	var pr domain.PullRequest
-	var assignedReviewers pq.StringArray
-	row := r.db.QueryRowContext(ctx,
-		`SELECT pr_id, pr_name, author_id, status, assigned_reviewers, created_at, merged_at 
-		 FROM pull_requests 
-		 WHERE pr_id = $1`, prID)
-
-	err := row.Scan(
-		&pr.PullRequestID,
-		&pr.PullRequestName,
-		&pr.AuthorID,
-		&pr.Status,
-		&assignedReviewers,
-		&pr.CreatedAt,
-		&pr.MergedAt)
+func (r *PostgresRepository) GetPullRequestByID(ctx context.Context, prID string) (_ domain.PullRequest, err error) {
+	ctx, span := startSpan(ctx, "GetPullRequestByID")
+	defer func() { tracing.End(span, err) }()
 
+	pr, err := scanPullRequest(r.db.QueryRowContext(ctx,
+		"SELECT "+prColumns+" FROM pull_requests WHERE pr_id = $1", prID))
 	if err == sql.ErrNoRows {
 		return domain.PullRequest{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("Pull Request %s not found", prID))
 	}
 	if err != nil {
 		return domain.PullRequest{}, fmt.Errorf("error getting PR from DB: %w", err)
 	}
+	return pr, nil
+}
+
+func (r *PostgresRepository) ListReviewedPullRequests(ctx context.Context, since time.Time) (_ []domain.PullRequest, err error) {
+	ctx, span := startSpan(ctx, "ListReviewedPullRequests")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+prColumns+` FROM pull_requests
+		 WHERE created_at >= $1 AND cardinality(assigned_reviewers) > 0
+		 ORDER BY created_at, pr_id`, since)
+	if err != nil {
+		return nil, fmt.Errorf("error querying PRs: %w", err)
+	}
+	defer rows.Close()
+
+	var prs []domain.PullRequest
+	for rows.Next() {
+		pr, err := scanPullRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning PR: %w", err)
+		}
+		prs = append(prs, pr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating PRs: %w", err)
+	}
+	return prs, nil
+}
+
+const prColumns = "pr_id, pr_name, author_id, status, assigned_reviewers, created_at, merged_at, version, changed_paths, labels, code_host_ref"
+
+func scanPullRequest(row rowScanner) (domain.PullRequest, error) {
+	var pr domain.PullRequest
+	var assignedReviewers, changedPaths, labels pq.StringArray
+	err := row.Scan(&pr.PullRequestID, &pr.PullRequestName, &pr.AuthorID, &pr.Status, &assignedReviewers,
+		&pr.CreatedAt, &pr.MergedAt, &pr.Version, &changedPaths, &labels, &pr.CodeHostRef)
+	if err != nil {
+		return domain.PullRequest{}, err
+	}
 
 	pr.AssignedReviewers = []string(assignedReviewers)
+	if len(changedPaths) > 0 {
+		pr.ChangedPaths = []string(changedPaths)
+	}
+	if len(labels) > 0 {
+		pr.Labels = []string(labels)
+	}
 	return pr, nil
 }
 
-func (r *PostgresRepository) UpdatePullRequest(ctx context.Context, pr domain.PullRequest) (domain.PullRequest, error) {
+func (r *PostgresRepository) UpdatePullRequest(ctx context.Context, pr domain.PullRequest) (_ domain.PullRequest, err error) {
+	ctx, span := startSpan(ctx, "UpdatePullRequest")
+	defer func() { tracing.End(span, err) }()
+
 	var mergedAt *time.Time
 	if pr.MergedAt != nil {
 		mergedAt = pr.MergedAt
@@ -212,28 +527,59 @@ func (r *PostgresRepository) UpdatePullRequest(ctx context.Context, pr domain.Pu
 
 	assignedReviewers := pq.Array(pr.AssignedReviewers)
 
-	result, err := r.db.ExecContext(ctx,
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.PullRequest{}, err
+	}
+	defer tx.Rollback()
+
+	// The row lock also orders concurrent updates, and thereby their events.
+	before, err := scanPullRequest(tx.QueryRowContext(ctx,
+		"SELECT "+prColumns+" FROM pull_requests WHERE pr_id = $1 FOR UPDATE", pr.PullRequestID))
+	if err == sql.ErrNoRows {
+		return domain.PullRequest{}, domain.NewBusinessError(domain.ErrNotFound, "Pull Request not found for update")
+	}
+	if err != nil {
+		return domain.PullRequest{}, fmt.Errorf("error getting PR from DB: %w", err)
+	}
+
+	var newVersion int
+	err = tx.QueryRowContext(ctx,
 		`UPDATE pull_requests 
-		 SET pr_name = $2, author_id = $3, status = $4, assigned_reviewers = $5, merged_at = $6
-		 WHERE pr_id = $1`,
-		pr.PullRequestID, pr.PullRequestName, pr.AuthorID, pr.Status, assignedReviewers, mergedAt)
+		 SET pr_name = $2, author_id = $3, status = $4, assigned_reviewers = $5, merged_at = $6, version = version + 1
+		 WHERE pr_id = $1 AND version = $7
+		 RETURNING version`,
+		pr.PullRequestID, pr.PullRequestName, pr.AuthorID, pr.Status, assignedReviewers, mergedAt, pr.Version).Scan(&newVersion)
 
+	if err == sql.ErrNoRows {
+		slog.WarnContext(ctx, "pull request version conflict", "pr_id", pr.PullRequestID, "version", pr.Version)
+		return domain.PullRequest{}, domain.NewBusinessError(domain.ErrVersionConflict,
+			fmt.Sprintf("Pull Request %s was modified concurrently", pr.PullRequestID))
+	}
 	if err != nil {
 		return domain.PullRequest{}, fmt.Errorf("error updating PR: %w", err)
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return domain.PullRequest{}, fmt.Errorf("error getting rows affected: %w", err)
+	pr.Version = newVersion
+	if err = insertOutboxEvents(ctx, tx, domain.PullRequestEvents(&before, pr, time.Now().UTC())...); err != nil {
+		return domain.PullRequest{}, err
 	}
-	if rowsAffected == 0 {
-		return domain.PullRequest{}, domain.NewBusinessError(domain.ErrNotFound, "Pull Request not found for update")
+	if err = insertRequestedAudit(ctx, tx, func(entry domain.AuditEntry) domain.AuditEntry {
+		return domain.PullRequestAudit(entry, &before, pr)
+	}); err != nil {
+		return domain.PullRequest{}, err
 	}
 
+	if err = tx.Commit(); err != nil {
+		return domain.PullRequest{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return pr, nil
 }
 
-func (r *PostgresRepository) GetPRsByReviewerID(ctx context.Context, userID string) ([]domain.PullRequestShort, error) {
+func (r *PostgresRepository) GetPRsByReviewerID(ctx context.Context, userID string) (_ []domain.PullRequestShort, err error) {
+	ctx, span := startSpan(ctx, "GetPRsByReviewerID")
+	defer func() { tracing.End(span, err) }()
+
 	rows, err := r.db.QueryContext(ctx,
 		`SELECT pr_id, pr_name, author_id, status 
 		 FROM pull_requests 
@@ -260,3 +606,834 @@ func (r *PostgresRepository) GetPRsByReviewerID(ctx context.Context, userID stri
 
 	return prs, nil
 }
+
+func (r *PostgresRepository) ReserveIdempotencyKey(ctx context.Context, rec domain.IdempotencyRecord) (_ domain.IdempotencyRecord, _ bool, err error) {
+	ctx, span := startSpan(ctx, "ReserveIdempotencyKey")
+	defer func() { tracing.End(span, err) }()
+
+	// An expired record is overwritten in place, so a reused key behaves like a new one.
+	var key string
+	err = r.db.QueryRowContext(ctx,
+		`INSERT INTO idempotency_keys (key, request_hash, status_code, headers, body, created_at, expires_at)
+		 VALUES ($1, $2, 0, NULL, NULL, $3, $4)
+		 ON CONFLICT (key) DO UPDATE
+		 SET request_hash = EXCLUDED.request_hash,
+		     status_code = 0,
+		     headers = NULL,
+		     body = NULL,
+		     created_at = EXCLUDED.created_at,
+		     expires_at = EXCLUDED.expires_at
+		 WHERE idempotency_keys.expires_at <= EXCLUDED.created_at
+		 RETURNING key`,
+		rec.Key, rec.RequestHash, rec.CreatedAt, rec.ExpiresAt).Scan(&key)
+	if err == nil {
+		rec.StatusCode = 0
+		return rec, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return domain.IdempotencyRecord{}, false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	var stored domain.IdempotencyRecord
+	var headers []byte
+	err = r.db.QueryRowContext(ctx,
+		`SELECT key, request_hash, status_code, headers, body, created_at, expires_at
+		 FROM idempotency_keys
+		 WHERE key = $1`, rec.Key).
+		Scan(&stored.Key, &stored.RequestHash, &stored.StatusCode, &headers, &stored.Body, &stored.CreatedAt, &stored.ExpiresAt)
+	if err == sql.ErrNoRows {
+		// The conflicting record was purged between the two statements; try again.
+		return r.ReserveIdempotencyKey(ctx, rec)
+	}
+	if err != nil {
+		return domain.IdempotencyRecord{}, false, fmt.Errorf("error getting idempotency key: %w", err)
+	}
+	if len(headers) > 0 {
+		if err := json.Unmarshal(headers, &stored.Headers); err != nil {
+			return domain.IdempotencyRecord{}, false, fmt.Errorf("error decoding stored headers: %w", err)
+		}
+	}
+
+	return stored, false, nil
+}
+
+func (r *PostgresRepository) CompleteIdempotencyKey(ctx context.Context, rec domain.IdempotencyRecord) (err error) {
+	ctx, span := startSpan(ctx, "CompleteIdempotencyKey")
+	defer func() { tracing.End(span, err) }()
+
+	headers, err := json.Marshal(rec.Headers)
+	if err != nil {
+		return fmt.Errorf("error encoding headers: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		`UPDATE idempotency_keys SET status_code = $2, headers = $3, body = $4
+		 WHERE key = $1 AND request_hash = $5`,
+		rec.Key, rec.StatusCode, headers, rec.Body, rec.RequestHash)
+	if err != nil {
+		return fmt.Errorf("error completing idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ReleaseIdempotencyKey(ctx context.Context, key string) (err error) {
+	ctx, span := startSpan(ctx, "ReleaseIdempotencyKey")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = r.db.ExecContext(ctx,
+		"DELETE FROM idempotency_keys WHERE key = $1 AND status_code = 0", key)
+	if err != nil {
+		return fmt.Errorf("error releasing idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresRepository) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "DeleteExpiredIdempotencyKeys")
+	defer func() { tracing.End(span, err) }()
+
+	result, err := r.db.ExecContext(ctx,
+		"DELETE FROM idempotency_keys WHERE expires_at <= $1", now)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting expired idempotency keys: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (r *PostgresRepository) GetReviewStats(ctx context.Context) (_ domain.ReviewStats, err error) {
+	ctx, span := startSpan(ctx, "GetReviewStats")
+	defer func() { tracing.End(span, err) }()
+
+	stats := domain.ReviewStats{OpenReviewsByUser: make(map[string]int)}
+
+	err = r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FILTER (WHERE status = $1),
+		        COUNT(*) FILTER (WHERE status = $2),
+		        COUNT(*) FILTER (WHERE status = $1 AND cardinality(assigned_reviewers) = 0)
+		 FROM pull_requests`, domain.StatusOpen, domain.StatusMerged).
+		Scan(&stats.OpenPRs, &stats.MergedPRs, &stats.OpenPRsWithoutReviewers)
+	if err != nil {
+		return domain.ReviewStats{}, fmt.Errorf("error counting PRs: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT reviewer, COUNT(*)
+		 FROM pull_requests, unnest(assigned_reviewers) AS reviewer
+		 WHERE status = $1
+		 GROUP BY reviewer`, domain.StatusOpen)
+	if err != nil {
+		return domain.ReviewStats{}, fmt.Errorf("error counting open reviews: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID string
+		var count int
+		if err := rows.Scan(&userID, &count); err != nil {
+			return domain.ReviewStats{}, fmt.Errorf("error scanning open reviews: %w", err)
+		}
+		stats.OpenReviewsByUser[userID] = count
+	}
+	if err := rows.Err(); err != nil {
+		return domain.ReviewStats{}, fmt.Errorf("error iterating open reviews: %w", err)
+	}
+
+	return stats, nil
+}
+
+const apiTokenColumns = "id, name, token_hash, user_id, roles, created_at, expires_at, revoked_at"
+
+func (r *PostgresRepository) CreateAPIToken(ctx context.Context, token domain.APIToken) (_ domain.APIToken, err error) {
+	ctx, span := startSpan(ctx, "CreateAPIToken")
+	defer func() { tracing.End(span, err) }()
+
+	var userID sql.NullString
+	if token.UserID != "" {
+		userID = sql.NullString{String: token.UserID, Valid: true}
+	}
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO api_tokens (`+apiTokenColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, NULL)`,
+		token.ID, token.Name, token.TokenHash, userID, pq.Array(rolesToStrings(token.Roles)), token.CreatedAt, token.ExpiresAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+			return domain.APIToken{}, domain.NewBusinessError(domain.ErrTokenExists, "API token already exists")
+		}
+		return domain.APIToken{}, fmt.Errorf("failed to create API token: %w", err)
+	}
+	token.RevokedAt = nil
+	return token, nil
+}
+
+func (r *PostgresRepository) GetAPITokenByHash(ctx context.Context, tokenHash string) (_ domain.APIToken, err error) {
+	ctx, span := startSpan(ctx, "GetAPITokenByHash")
+	defer func() { tracing.End(span, err) }()
+
+	row := r.db.QueryRowContext(ctx, `SELECT `+apiTokenColumns+` FROM api_tokens WHERE token_hash = $1`, tokenHash)
+	token, err := scanAPIToken(row)
+	if err == sql.ErrNoRows {
+		return domain.APIToken{}, domain.NewBusinessError(domain.ErrNotFound, "API token not found")
+	}
+	if err != nil {
+		return domain.APIToken{}, fmt.Errorf("error getting API token: %w", err)
+	}
+	return token, nil
+}
+
+func (r *PostgresRepository) ListAPITokens(ctx context.Context) (_ []domain.APIToken, err error) {
+	ctx, span := startSpan(ctx, "ListAPITokens")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+apiTokenColumns+` FROM api_tokens ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("error listing API tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []domain.APIToken{}
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning API token: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating API tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+func (r *PostgresRepository) RevokeAPIToken(ctx context.Context, id string, at time.Time) (_ domain.APIToken, err error) {
+	ctx, span := startSpan(ctx, "RevokeAPIToken")
+	defer func() { tracing.End(span, err) }()
+
+	row := r.db.QueryRowContext(ctx,
+		`UPDATE api_tokens SET revoked_at = COALESCE(revoked_at, $2)
+		 WHERE id = $1
+		 RETURNING `+apiTokenColumns, id, at)
+	token, err := scanAPIToken(row)
+	if err == sql.ErrNoRows {
+		return domain.APIToken{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("API token %s not found", id))
+	}
+	if err != nil {
+		return domain.APIToken{}, fmt.Errorf("error revoking API token: %w", err)
+	}
+	return token, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func scanAPIToken(row rowScanner) (domain.APIToken, error) {
+	var token domain.APIToken
+	var userID sql.NullString
+	var roles pq.StringArray
+	if err := row.Scan(&token.ID, &token.Name, &token.TokenHash, &userID, &roles,
+		&token.CreatedAt, &token.ExpiresAt, &token.RevokedAt); err != nil {
+		return domain.APIToken{}, err
+	}
+	token.UserID = userID.String
+	for _, role := range roles {
+		token.Roles = append(token.Roles, domain.Role(role))
+	}
+	return token, nil
+}
+
+func rolesToStrings(roles []domain.Role) []string {
+	out := make([]string, len(roles))
+	for i, role := range roles {
+		out[i] = string(role)
+	}
+	return out
+}
+
+const auditColumns = "id, actor, action, target_ids, before_state, after_state, request_id, created_at"
+
+func (r *PostgresRepository) AppendAuditEntry(ctx context.Context, entry domain.AuditEntry) (_ domain.AuditEntry, err error) {
+	ctx, span := startSpan(ctx, "AppendAuditEntry")
+	defer func() { tracing.End(span, err) }()
+
+	return insertAuditEntry(ctx, r.db, entry)
+}
+
+// insertRequestedAudit appends the entry requested with repository.WithAudit,
+// completed by complete, in tx, i.e. atomically with the change it describes.
+func insertRequestedAudit(ctx context.Context, tx *sql.Tx, complete func(domain.AuditEntry) domain.AuditEntry) error {
+	entry, ok := repository.AuditFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	_, err := insertAuditEntry(ctx, tx, complete(entry))
+	return err
+}
+
+func insertAuditEntry(ctx context.Context, q querier, entry domain.AuditEntry) (domain.AuditEntry, error) {
+	if entry.TargetIDs == nil {
+		entry.TargetIDs = []string{}
+	}
+	err := q.QueryRowContext(ctx,
+		`INSERT INTO audit_log (actor, action, target_ids, before_state, after_state, request_id, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		entry.Actor, entry.Action, pq.Array(entry.TargetIDs), nullJSON(entry.Before), nullJSON(entry.After),
+		entry.RequestID, entry.CreatedAt).Scan(&entry.ID)
+	if err != nil {
+		return domain.AuditEntry{}, fmt.Errorf("failed to append audit entry: %w", err)
+	}
+	return entry, nil
+}
+
+func (r *PostgresRepository) ListAuditEntries(ctx context.Context, filter domain.AuditFilter) (_ []domain.AuditEntry, err error) {
+	ctx, span := startSpan(ctx, "ListAuditEntries")
+	defer func() { tracing.End(span, err) }()
+
+	query := `SELECT ` + auditColumns + ` FROM audit_log WHERE TRUE`
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if filter.Actor != "" {
+		query += ` AND actor = ` + arg(filter.Actor)
+	}
+	if filter.Target != "" {
+		query += ` AND target_ids @> ARRAY[` + arg(filter.Target) + `]::TEXT[]`
+	}
+	if filter.From != nil {
+		query += ` AND created_at >= ` + arg(*filter.From)
+	}
+	if filter.To != nil {
+		query += ` AND created_at < ` + arg(*filter.To)
+	}
+	query += ` ORDER BY id DESC`
+	if filter.Limit > 0 {
+		query += ` LIMIT ` + arg(filter.Limit)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []domain.AuditEntry{}
+	for rows.Next() {
+		var entry domain.AuditEntry
+		var targets pq.StringArray
+		var before, after []byte
+		var requestID sql.NullString
+		if err := rows.Scan(&entry.ID, &entry.Actor, &entry.Action, &targets, &before, &after, &requestID, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning audit entry: %w", err)
+		}
+		entry.TargetIDs = []string(targets)
+		if before != nil {
+			entry.Before = json.RawMessage(before)
+		}
+		if after != nil {
+			entry.After = json.RawMessage(after)
+		}
+		entry.RequestID = requestID.String
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit entries: %w", err)
+	}
+	return entries, nil
+}
+
+func nullJSON(raw json.RawMessage) sql.NullString {
+	if len(raw) == 0 {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: string(raw), Valid: true}
+}
+
+const webhookSubscriptionColumns = "id, url, secret, events, created_at"
+
+func (r *PostgresRepository) CreateWebhookSubscription(ctx context.Context, sub domain.WebhookSubscription) (_ domain.WebhookSubscription, err error) {
+	ctx, span := startSpan(ctx, "CreateWebhookSubscription")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO webhook_subscriptions (`+webhookSubscriptionColumns+`) VALUES ($1, $2, $3, $4, $5)`,
+		sub.ID, sub.URL, sub.Secret, pq.Array(sub.Events), sub.CreatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+			return domain.WebhookSubscription{}, domain.NewBusinessError(domain.ErrWebhookExists, "Webhook subscription already exists")
+		}
+		return domain.WebhookSubscription{}, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	return sub, nil
+}
+
+func (r *PostgresRepository) ListWebhookSubscriptions(ctx context.Context) (_ []domain.WebhookSubscription, err error) {
+	ctx, span := startSpan(ctx, "ListWebhookSubscriptions")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+webhookSubscriptionColumns+` FROM webhook_subscriptions ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("error listing webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []domain.WebhookSubscription{}
+	for rows.Next() {
+		sub, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning webhook subscription: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+func (r *PostgresRepository) DeleteWebhookSubscription(ctx context.Context, id string) (_ domain.WebhookSubscription, err error) {
+	ctx, span := startSpan(ctx, "DeleteWebhookSubscription")
+	defer func() { tracing.End(span, err) }()
+
+	row := r.db.QueryRowContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1 RETURNING `+webhookSubscriptionColumns, id)
+	sub, err := scanWebhookSubscription(row)
+	if err == sql.ErrNoRows {
+		return domain.WebhookSubscription{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("Webhook subscription %s not found", id))
+	}
+	if err != nil {
+		return domain.WebhookSubscription{}, fmt.Errorf("error deleting webhook subscription: %w", err)
+	}
+	return sub, nil
+}
+
+func scanWebhookSubscription(row rowScanner) (domain.WebhookSubscription, error) {
+	var sub domain.WebhookSubscription
+	var events pq.StringArray
+	if err := row.Scan(&sub.ID, &sub.URL, &sub.Secret, &events, &sub.CreatedAt); err != nil {
+		return domain.WebhookSubscription{}, err
+	}
+	sub.Events = []string(events)
+	return sub, nil
+}
+
+func (r *PostgresRepository) CreateWebhookDeliveries(ctx context.Context, deliveries []domain.WebhookDelivery) (err error) {
+	ctx, span := startSpan(ctx, "CreateWebhookDeliveries")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, d := range deliveries {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO webhook_deliveries (id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at, created_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+			d.ID, d.SubscriptionID, d.EventID, d.EventType, string(d.Payload), d.Status, d.Attempts, d.NextAttemptAt, d.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to create webhook delivery: %w", err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+const webhookDeliveryColumns = `d.id, d.subscription_id, s.url, s.secret, d.event_id, d.event_type, d.payload, d.status,
+	d.attempts, d.next_attempt_at, d.last_error, d.created_at, d.delivered_at`
+
+func (r *PostgresRepository) ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) (_ []domain.WebhookDelivery, err error) {
+	ctx, span := startSpan(ctx, "ClaimWebhookDeliveries")
+	defer func() { tracing.End(span, err) }()
+
+	// SKIP LOCKED lets several workers claim disjoint batches concurrently.
+	deliveries, err := queryWebhookDeliveries(ctx, r.db, `
+		WITH due AS (
+			SELECT id FROM webhook_deliveries
+			WHERE status = $1 AND next_attempt_at <= $2
+			ORDER BY created_at, id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d SET next_attempt_at = $4
+		FROM due, webhook_subscriptions s
+		WHERE d.id = due.id AND s.id = d.subscription_id
+		RETURNING `+webhookDeliveryColumns, domain.DeliveryPending, now, limit, now.Add(lease))
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		if !deliveries[i].CreatedAt.Equal(deliveries[j].CreatedAt) {
+			return deliveries[i].CreatedAt.Before(deliveries[j].CreatedAt)
+		}
+		return deliveries[i].ID < deliveries[j].ID
+	})
+	return deliveries, nil
+}
+
+func (r *PostgresRepository) UpdateWebhookDelivery(ctx context.Context, d domain.WebhookDelivery) (err error) {
+	ctx, span := startSpan(ctx, "UpdateWebhookDelivery")
+	defer func() { tracing.End(span, err) }()
+
+	var lastError sql.NullString
+	if d.LastError != "" {
+		lastError = sql.NullString{String: d.LastError, Valid: true}
+	}
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE webhook_deliveries SET status = $2, attempts = $3, next_attempt_at = $4, last_error = $5, delivered_at = $6
+		 WHERE id = $1`,
+		d.ID, d.Status, d.Attempts, d.NextAttemptAt, lastError, d.DeliveredAt)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("Webhook delivery %s not found", d.ID))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) ListWebhookDeliveries(ctx context.Context, status string, limit int) (_ []domain.WebhookDelivery, err error) {
+	ctx, span := startSpan(ctx, "ListWebhookDeliveries")
+	defer func() { tracing.End(span, err) }()
+
+	return queryWebhookDeliveries(ctx, r.db, `SELECT `+webhookDeliveryColumns+`
+		FROM webhook_deliveries d JOIN webhook_subscriptions s ON s.id = d.subscription_id
+		WHERE d.status = $1 ORDER BY d.created_at DESC, d.id DESC LIMIT $2`, status, limit)
+}
+
+func (r *PostgresRepository) RequeueWebhookDelivery(ctx context.Context, id string, now time.Time) (_ domain.WebhookDelivery, err error) {
+	ctx, span := startSpan(ctx, "RequeueWebhookDelivery")
+	defer func() { tracing.End(span, err) }()
+
+	deliveries, err := queryWebhookDeliveries(ctx, r.db, `
+		UPDATE webhook_deliveries d SET status = $2, attempts = 0, next_attempt_at = $3
+		FROM webhook_subscriptions s
+		WHERE d.id = $1 AND d.status = $4 AND s.id = d.subscription_id
+		RETURNING `+webhookDeliveryColumns, id, domain.DeliveryPending, now, domain.DeliveryDead)
+	if err != nil {
+		return domain.WebhookDelivery{}, err
+	}
+	if len(deliveries) == 0 {
+		return domain.WebhookDelivery{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("Dead webhook delivery %s not found", id))
+	}
+	return deliveries[0], nil
+}
+
+func (r *PostgresRepository) DeleteDeliveredWebhookDeliveries(ctx context.Context, before time.Time) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "DeleteDeliveredWebhookDeliveries")
+	defer func() { tracing.End(span, err) }()
+
+	res, err := r.db.ExecContext(ctx,
+		"DELETE FROM webhook_deliveries WHERE status = $1 AND delivered_at < $2", domain.DeliveryDelivered, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete delivered webhook deliveries: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+func queryWebhookDeliveries(ctx context.Context, db *sql.DB, query string, args ...any) ([]domain.WebhookDelivery, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []domain.WebhookDelivery{}
+	for rows.Next() {
+		var d domain.WebhookDelivery
+		var payload []byte
+		var lastError sql.NullString
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.URL, &d.Secret, &d.EventID, &d.EventType, &payload, &d.Status,
+			&d.Attempts, &d.NextAttemptAt, &lastError, &d.CreatedAt, &d.DeliveredAt); err != nil {
+			return nil, fmt.Errorf("error scanning webhook delivery: %w", err)
+		}
+		d.Payload = json.RawMessage(payload)
+		d.LastError = lastError.String
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// insertOutboxEvents records events in tx, i.e. atomically with the change they describe.
+func insertOutboxEvents(ctx context.Context, tx *sql.Tx, events ...domain.Event) error {
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s event: %w", event.Type, err)
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO outbox (aggregate_id, event_type, payload, next_attempt_at, created_at) VALUES ($1, $2, $3, $4, $4)`,
+			event.AggregateID(), event.Type, string(payload), event.OccurredAt)
+		if err != nil {
+			return fmt.Errorf("failed to record %s event: %w", event.Type, err)
+		}
+	}
+	return nil
+}
+
+// outboxClaimLock is the advisory lock key that serializes outbox claims.
+const outboxClaimLock = 0x6f7574626f78
+
+func (r *PostgresRepository) ClaimOutboxEvents(ctx context.Context, now time.Time, lease time.Duration, limit int) (_ []domain.OutboxEvent, err error) {
+	ctx, span := startSpan(ctx, "ClaimOutboxEvents")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Claims are serialized rather than using SKIP LOCKED: a relay skipping the
+	// locked head of an aggregate could otherwise claim the events after it.
+	if _, err = tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", outboxClaimLock); err != nil {
+		return nil, fmt.Errorf("failed to lock outbox: %w", err)
+	}
+	rows, err := tx.QueryContext(ctx, `
+		WITH heads AS (
+			SELECT DISTINCT ON (aggregate_id) aggregate_id, next_attempt_at
+			FROM outbox WHERE published_at IS NULL
+			ORDER BY aggregate_id, id
+		), due AS (
+			SELECT o.id FROM outbox o JOIN heads h ON h.aggregate_id = o.aggregate_id
+			WHERE o.published_at IS NULL AND h.next_attempt_at <= $1
+			ORDER BY o.id LIMIT $2
+		)
+		UPDATE outbox o SET next_attempt_at = $3
+		FROM due WHERE o.id = due.id
+		RETURNING o.id, o.aggregate_id, o.payload, o.attempts, o.next_attempt_at, o.last_error, o.published_sinks, o.published_at`,
+		now, limit, now.Add(lease))
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim outbox events: %w", err)
+	}
+	events, err := scanOutboxEvents(rows)
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Seq < events[j].Seq })
+	return events, nil
+}
+
+func (r *PostgresRepository) UpdateOutboxEvent(ctx context.Context, event domain.OutboxEvent) (err error) {
+	ctx, span := startSpan(ctx, "UpdateOutboxEvent")
+	defer func() { tracing.End(span, err) }()
+
+	var lastError sql.NullString
+	if event.LastError != "" {
+		lastError = sql.NullString{String: event.LastError, Valid: true}
+	}
+	res, err := r.db.ExecContext(ctx,
+		"UPDATE outbox SET attempts = $2, next_attempt_at = $3, last_error = $4, published_sinks = $5, published_at = $6 WHERE id = $1",
+		event.Seq, event.Attempts, event.NextAttemptAt, lastError, pq.Array(append([]string{}, event.PublishedSinks...)), event.PublishedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update outbox event: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("Outbox event %d not found", event.Seq))
+	}
+	return nil
+}
+
+func (r *PostgresRepository) DeletePublishedOutboxEvents(ctx context.Context, before time.Time) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "DeletePublishedOutboxEvents")
+	defer func() { tracing.End(span, err) }()
+
+	res, err := r.db.ExecContext(ctx, "DELETE FROM outbox WHERE published_at < $1", before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete published outbox events: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+func scanOutboxEvents(rows *sql.Rows) ([]domain.OutboxEvent, error) {
+	defer rows.Close()
+
+	events := []domain.OutboxEvent{}
+	for rows.Next() {
+		var e domain.OutboxEvent
+		var payload []byte
+		var lastError sql.NullString
+		var sinks pq.StringArray
+		if err := rows.Scan(&e.Seq, &e.AggregateID, &payload, &e.Attempts, &e.NextAttemptAt, &lastError, &sinks, &e.PublishedAt); err != nil {
+			return nil, fmt.Errorf("error scanning outbox event: %w", err)
+		}
+		if err := json.Unmarshal(payload, &e.Event); err != nil {
+			return nil, fmt.Errorf("error decoding outbox event %d: %w", e.Seq, err)
+		}
+		e.ID = strconv.FormatInt(e.Seq, 10)
+		e.LastError = lastError.String
+		if len(sinks) > 0 {
+			e.PublishedSinks = []string(sinks)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating outbox events: %w", err)
+	}
+	return events, nil
+}
+
+const userMappingColumns = "provider, login, user_id, created_at"
+
+func (r *PostgresRepository) SetUserMapping(ctx context.Context, m domain.UserMapping) (_ domain.UserMapping, err error) {
+	ctx, span := startSpan(ctx, "SetUserMapping")
+	defer func() { tracing.End(span, err) }()
+
+	row := r.db.QueryRowContext(ctx,
+		`INSERT INTO user_mappings (`+userMappingColumns+`) VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (provider, login) DO UPDATE SET user_id = EXCLUDED.user_id
+		 RETURNING `+userMappingColumns,
+		m.Provider, m.Login, m.UserID, m.CreatedAt)
+	saved, err := scanUserMapping(row)
+	if err != nil {
+		return domain.UserMapping{}, fmt.Errorf("failed to set user mapping: %w", err)
+	}
+	return saved, nil
+}
+
+func (r *PostgresRepository) GetUserMapping(ctx context.Context, provider, login string) (_ domain.UserMapping, err error) {
+	ctx, span := startSpan(ctx, "GetUserMapping")
+	defer func() { tracing.End(span, err) }()
+
+	row := r.db.QueryRowContext(ctx, `SELECT `+userMappingColumns+` FROM user_mappings WHERE provider = $1 AND login = $2`, provider, login)
+	m, err := scanUserMapping(row)
+	if err == sql.ErrNoRows {
+		return domain.UserMapping{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("No user is mapped to %s login %s", provider, login))
+	}
+	if err != nil {
+		return domain.UserMapping{}, fmt.Errorf("error getting user mapping: %w", err)
+	}
+	return m, nil
+}
+
+func (r *PostgresRepository) ListUserMappings(ctx context.Context, provider string) (_ []domain.UserMapping, err error) {
+	ctx, span := startSpan(ctx, "ListUserMappings")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+userMappingColumns+` FROM user_mappings WHERE $1 = '' OR provider = $1 ORDER BY provider, login`, provider)
+	if err != nil {
+		return nil, fmt.Errorf("error listing user mappings: %w", err)
+	}
+	defer rows.Close()
+
+	mappings := []domain.UserMapping{}
+	for rows.Next() {
+		m, err := scanUserMapping(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning user mapping: %w", err)
+		}
+		mappings = append(mappings, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating user mappings: %w", err)
+	}
+	return mappings, nil
+}
+
+func (r *PostgresRepository) DeleteUserMapping(ctx context.Context, provider, login string) (_ domain.UserMapping, err error) {
+	ctx, span := startSpan(ctx, "DeleteUserMapping")
+	defer func() { tracing.End(span, err) }()
+
+	row := r.db.QueryRowContext(ctx, `DELETE FROM user_mappings WHERE provider = $1 AND login = $2 RETURNING `+userMappingColumns, provider, login)
+	m, err := scanUserMapping(row)
+	if err == sql.ErrNoRows {
+		return domain.UserMapping{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("No user is mapped to %s login %s", provider, login))
+	}
+	if err != nil {
+		return domain.UserMapping{}, fmt.Errorf("error deleting user mapping: %w", err)
+	}
+	return m, nil
+}
+
+func scanUserMapping(row rowScanner) (domain.UserMapping, error) {
+	var m domain.UserMapping
+	if err := row.Scan(&m.Provider, &m.Login, &m.UserID, &m.CreatedAt); err != nil {
+		return domain.UserMapping{}, err
+	}
+	return m, nil
+}
+
+func (r *PostgresRepository) SetCodeOwners(ctx context.Context, owners domain.CodeOwners) (_ domain.CodeOwners, err error) {
+	ctx, span := startSpan(ctx, "SetCodeOwners")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.CodeOwners{}, err
+	}
+	defer tx.Rollback()
+
+	if err = lockTeam(ctx, tx, owners.TeamName); err != nil {
+		return domain.CodeOwners{}, err
+	}
+	before, err := queryCodeOwners(ctx, tx, owners.TeamName)
+	if err != nil {
+		return domain.CodeOwners{}, err
+	}
+
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO team_codeowners (team_name, rules, updated_at)
+		 SELECT team_name, $1, $2 FROM teams WHERE team_name = $3
+		 ON CONFLICT (team_name) DO UPDATE SET rules = EXCLUDED.rules, updated_at = EXCLUDED.updated_at
+		 RETURNING team_name, rules, updated_at`,
+		owners.Rules, owners.UpdatedAt, owners.TeamName).Scan(&owners.TeamName, &owners.Rules, &owners.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return domain.CodeOwners{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("Team %s not found", owners.TeamName))
+	}
+	if err != nil {
+		return domain.CodeOwners{}, fmt.Errorf("failed to set CODEOWNERS rules: %w", err)
+	}
+
+	if err = insertRequestedAudit(ctx, tx, func(entry domain.AuditEntry) domain.AuditEntry {
+		return domain.CodeOwnersAudit(entry, before, owners)
+	}); err != nil {
+		return domain.CodeOwners{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return domain.CodeOwners{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return owners, nil
+}
+
+func (r *PostgresRepository) GetCodeOwners(ctx context.Context, teamName string) (_ domain.CodeOwners, err error) {
+	ctx, span := startSpan(ctx, "GetCodeOwners")
+	defer func() { tracing.End(span, err) }()
+
+	owners, err := queryCodeOwners(ctx, r.db, teamName)
+	if err != nil {
+		return domain.CodeOwners{}, err
+	}
+	if owners == nil {
+		return domain.CodeOwners{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("Team %s has no CODEOWNERS rules", teamName))
+	}
+	return *owners, nil
+}
+
+// queryCodeOwners returns the rules of a team, or nil if it has none.
+func queryCodeOwners(ctx context.Context, q querier, teamName string) (*domain.CodeOwners, error) {
+	var owners domain.CodeOwners
+	err := q.QueryRowContext(ctx,
+		"SELECT team_name, rules, updated_at FROM team_codeowners WHERE team_name = $1", teamName).
+		Scan(&owners.TeamName, &owners.Rules, &owners.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting CODEOWNERS rules: %w", err)
+	}
+	return &owners, nil
+}
@@ -0,0 +1,54 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"Backend/internal/repository"
+	"Backend/internal/repository/postgres"
+	"Backend/internal/repository/repotest"
+
+	_ "github.com/lib/pq"
+)
+
+var _ repository.TeamRepository = (*postgres.PostgresRepository)(nil)
+var _ repository.PullRequestRepository = (*postgres.PostgresRepository)(nil)
+var _ repository.IdempotencyRepository = (*postgres.PostgresRepository)(nil)
+var _ repository.StatsRepository = (*postgres.PostgresRepository)(nil)
+var _ repository.HealthRepository = (*postgres.PostgresRepository)(nil)
+var _ repository.TokenRepository = (*postgres.PostgresRepository)(nil)
+var _ repository.AuditRepository = (*postgres.PostgresRepository)(nil)
+var _ repository.WebhookRepository = (*postgres.PostgresRepository)(nil)
+var _ repository.OutboxRepository = (*postgres.PostgresRepository)(nil)
+var _ repository.UserMappingRepository = (*postgres.PostgresRepository)(nil)
+var _ repository.CodeOwnersRepository = (*postgres.PostgresRepository)(nil)
+var _ repository.ReviewHistoryRepository = (*postgres.PostgresRepository)(nil)
+
+// TestPostgresRepository runs against the database in POSTGRES_TEST_DSN.
+// All tables in that database are truncated between tests.
+func TestPostgresRepository(t *testing.T) {
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_TEST_DSN is not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("Failed to open DB connection: %v", err)
+	}
+	defer db.Close()
+
+	repo := postgres.NewPostgresRepository(db)
+	if err := repo.Init(context.Background()); err != nil {
+		t.Fatalf("Failed to initialize schema: %v", err)
+	}
+
+	repotest.Run(t, func(t *testing.T) repotest.Repositories {
+		if _, err := db.Exec("TRUNCATE pull_requests, users, teams, idempotency_keys, api_tokens, audit_log, webhook_subscriptions, webhook_deliveries, outbox, user_mappings, team_codeowners RESTART IDENTITY"); err != nil {
+			t.Fatalf("Failed to truncate tables: %v", err)
+		}
+		return repotest.Repositories{Teams: repo, PRs: repo, Idempotency: repo, Stats: repo, Health: repo, Tokens: repo, Audit: repo, Webhooks: repo, Outbox: repo, Mappings: repo, CodeOwners: repo, History: repo}
+	})
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"Backend/internal/domain"
 	"context"
+	"time"
 )
 
 type TeamRepository interface {
@@ -18,3 +19,110 @@ type PullRequestRepository interface {
 	UpdatePullRequest(ctx context.Context, pr domain.PullRequest) (domain.PullRequest, error)
 	GetPRsByReviewerID(ctx context.Context, userID string) ([]domain.PullRequestShort, error)
 }
+
+type IdempotencyRepository interface {
+	// ReserveIdempotencyKey stores rec unless a live record with the same key exists,
+	// in which case that record is returned with reserved=false.
+	ReserveIdempotencyKey(ctx context.Context, rec domain.IdempotencyRecord) (stored domain.IdempotencyRecord, reserved bool, err error)
+	CompleteIdempotencyKey(ctx context.Context, rec domain.IdempotencyRecord) error
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error)
+}
+
+type StatsRepository interface {
+	GetReviewStats(ctx context.Context) (domain.ReviewStats, error)
+}
+
+// ReviewHistoryRepository exposes past assignments, from which reviewers'
+// expertise is learnt.
+type ReviewHistoryRepository interface {
+	// ListReviewedPullRequests returns the PRs created at or after since that
+	// have reviewers assigned, oldest first.
+	ListReviewedPullRequests(ctx context.Context, since time.Time) ([]domain.PullRequest, error)
+}
+
+// HealthRepository reports whether the storage is able to serve requests.
+type HealthRepository interface {
+	Ping(ctx context.Context) error
+	// CheckMigrations returns an error unless the schema is at the version this build expects.
+	CheckMigrations(ctx context.Context) error
+}
+
+type TokenRepository interface {
+	CreateAPIToken(ctx context.Context, token domain.APIToken) (domain.APIToken, error)
+	GetAPITokenByHash(ctx context.Context, tokenHash string) (domain.APIToken, error)
+	ListAPITokens(ctx context.Context) ([]domain.APIToken, error)
+	// RevokeAPIToken sets revoked_at unless the token is already revoked.
+	RevokeAPIToken(ctx context.Context, id string, at time.Time) (domain.APIToken, error)
+}
+
+type AuditRepository interface {
+	AppendAuditEntry(ctx context.Context, entry domain.AuditEntry) (domain.AuditEntry, error)
+	// ListAuditEntries returns matching entries newest first, at most filter.Limit of them.
+	ListAuditEntries(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error)
+}
+
+type auditKey struct{}
+
+// WithAudit asks the mutating methods of TeamRepository, PullRequestRepository
+// and CodeOwnersRepository to append entry, completed with the targets and the
+// state before and after the change, in the same transaction as the change.
+// Nothing is appended when nothing changes.
+func WithAudit(ctx context.Context, entry domain.AuditEntry) context.Context {
+	return context.WithValue(ctx, auditKey{}, entry)
+}
+
+// AuditFromContext returns the entry requested with WithAudit.
+func AuditFromContext(ctx context.Context) (domain.AuditEntry, bool) {
+	entry, ok := ctx.Value(auditKey{}).(domain.AuditEntry)
+	return entry, ok
+}
+
+type WebhookRepository interface {
+	CreateWebhookSubscription(ctx context.Context, sub domain.WebhookSubscription) (domain.WebhookSubscription, error)
+	ListWebhookSubscriptions(ctx context.Context) ([]domain.WebhookSubscription, error)
+	// DeleteWebhookSubscription also deletes the subscription's deliveries.
+	DeleteWebhookSubscription(ctx context.Context, id string) (domain.WebhookSubscription, error)
+
+	CreateWebhookDeliveries(ctx context.Context, deliveries []domain.WebhookDelivery) error
+	// ClaimWebhookDeliveries returns up to limit pending deliveries due at now, oldest
+	// first, and postpones them by lease so that concurrent workers skip them.
+	ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.WebhookDelivery, error)
+	// UpdateWebhookDelivery stores the outcome of an attempt.
+	UpdateWebhookDelivery(ctx context.Context, delivery domain.WebhookDelivery) error
+	// ListWebhookDeliveries returns deliveries in status, newest first.
+	ListWebhookDeliveries(ctx context.Context, status string, limit int) ([]domain.WebhookDelivery, error)
+	// RequeueWebhookDelivery makes a dead delivery pending again with a fresh attempt budget.
+	RequeueWebhookDelivery(ctx context.Context, id string, now time.Time) (domain.WebhookDelivery, error)
+	DeleteDeliveredWebhookDeliveries(ctx context.Context, before time.Time) (int64, error)
+}
+
+// OutboxRepository exposes the events that the mutating methods of
+// TeamRepository and PullRequestRepository record in the same transaction as
+// the change, so that none is lost if the process dies after the commit.
+type OutboxRepository interface {
+	// ClaimOutboxEvents leases up to limit unpublished events, oldest first,
+	// until now+lease. Only aggregates whose oldest unpublished event is due
+	// are considered, so an event is never handed out while an earlier event of
+	// the same aggregate is leased or waiting for a retry.
+	ClaimOutboxEvents(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.OutboxEvent, error)
+	// UpdateOutboxEvent records the outcome of a publication attempt.
+	UpdateOutboxEvent(ctx context.Context, event domain.OutboxEvent) error
+	DeletePublishedOutboxEvents(ctx context.Context, before time.Time) (int64, error)
+}
+
+type UserMappingRepository interface {
+	// SetUserMapping creates the mapping of a login or replaces its user.
+	SetUserMapping(ctx context.Context, m domain.UserMapping) (domain.UserMapping, error)
+	GetUserMapping(ctx context.Context, provider, login string) (domain.UserMapping, error)
+	// ListUserMappings returns the mappings of provider, or of every provider
+	// when it is empty, ordered by provider and login.
+	ListUserMappings(ctx context.Context, provider string) ([]domain.UserMapping, error)
+	DeleteUserMapping(ctx context.Context, provider, login string) (domain.UserMapping, error)
+}
+
+type CodeOwnersRepository interface {
+	// SetCodeOwners creates or replaces the rules of an existing team.
+	SetCodeOwners(ctx context.Context, owners domain.CodeOwners) (domain.CodeOwners, error)
+	GetCodeOwners(ctx context.Context, teamName string) (domain.CodeOwners, error)
+}
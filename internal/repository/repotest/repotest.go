@@ -0,0 +1,1216 @@
+// Package repotest provides a conformance suite that every repository
+// backend runs against itself, so that all storages behave the same way.
+package repotest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"Backend/internal/domain"
+	"Backend/internal/repository"
+)
+
+type Repositories struct {
+	Teams repository.TeamRepository
+	PRs   repository.PullRequestRepository
+	// Optional repositories; their tests are skipped when nil.
+	Idempotency repository.IdempotencyRepository
+	Stats       repository.StatsRepository
+	Health      repository.HealthRepository
+	Tokens      repository.TokenRepository
+	Audit       repository.AuditRepository
+	Webhooks    repository.WebhookRepository
+	Outbox      repository.OutboxRepository
+	Mappings    repository.UserMappingRepository
+	CodeOwners  repository.CodeOwnersRepository
+	History     repository.ReviewHistoryRepository
+}
+
+// Factory returns repositories backed by an empty storage.
+type Factory func(t *testing.T) Repositories
+
+func Run(t *testing.T, newRepos Factory) {
+	t.Run("TeamRepository", func(t *testing.T) { runTeamTests(t, newRepos) })
+	t.Run("PullRequestRepository", func(t *testing.T) { runPullRequestTests(t, newRepos) })
+	t.Run("IdempotencyRepository", func(t *testing.T) { runIdempotencyTests(t, newRepos) })
+	t.Run("StatsRepository", func(t *testing.T) { runStatsTests(t, newRepos) })
+	t.Run("HealthRepository", func(t *testing.T) { runHealthTests(t, newRepos) })
+	t.Run("TokenRepository", func(t *testing.T) { runTokenTests(t, newRepos) })
+	t.Run("AuditRepository", func(t *testing.T) { runAuditTests(t, newRepos) })
+	t.Run("WebhookRepository", func(t *testing.T) { runWebhookTests(t, newRepos) })
+	t.Run("OutboxRepository", func(t *testing.T) { runOutboxTests(t, newRepos) })
+	t.Run("UserMappingRepository", func(t *testing.T) { runUserMappingTests(t, newRepos) })
+	t.Run("CodeOwnersRepository", func(t *testing.T) { runCodeOwnersTests(t, newRepos) })
+	t.Run("ReviewHistoryRepository", func(t *testing.T) { runReviewHistoryTests(t, newRepos) })
+}
+
+func runTeamTests(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	t.Run("CreateAndGetTeam", func(t *testing.T) {
+		repos := newRepos(t)
+		seedTeam(t, repos, "backend", "u1", "u2")
+
+		team, err := repos.Teams.GetTeamByName(ctx, "backend")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if team.TeamName != "backend" || len(team.Members) != 2 {
+			t.Fatalf("Expected team backend with 2 members, got %+v", team)
+		}
+		for _, member := range team.Members {
+			if member.TeamName != "backend" || !member.IsActive || member.Username != "name-"+member.UserID {
+				t.Errorf("Unexpected member %+v", member)
+			}
+		}
+	})
+
+	t.Run("TeamWithoutMembers", func(t *testing.T) {
+		repos := newRepos(t)
+		if _, err := repos.Teams.CreateOrUpdateTeam(ctx, domain.Team{TeamName: "empty"}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		team, err := repos.Teams.GetTeamByName(ctx, "empty")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(team.Members) != 0 {
+			t.Errorf("Expected no members, got %+v", team.Members)
+		}
+	})
+
+	t.Run("UpsertUpdatesAndMovesMembers", func(t *testing.T) {
+		repos := newRepos(t)
+		seedTeam(t, repos, "backend", "u1", "u2")
+		seedTeam(t, repos, "frontend", "u3")
+
+		_, err := repos.Teams.CreateOrUpdateTeam(ctx, domain.Team{
+			TeamName: "frontend",
+			Members: []domain.User{
+				{UserID: "u3", Username: "renamed", TeamName: "frontend", IsActive: false},
+				{UserID: "u2", Username: "name-u2", TeamName: "frontend", IsActive: true},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		u3, err := repos.Teams.GetUserByID(ctx, "u3")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if u3.Username != "renamed" || u3.IsActive {
+			t.Errorf("Expected u3 to be renamed and inactive, got %+v", u3)
+		}
+
+		backend, err := repos.Teams.GetTeamByName(ctx, "backend")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(backend.Members) != 1 || backend.Members[0].UserID != "u1" {
+			t.Errorf("Expected only u1 to remain in backend, got %+v", backend.Members)
+		}
+
+		frontend, err := repos.Teams.GetTeamByName(ctx, "frontend")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(frontend.Members) != 2 {
+			t.Errorf("Expected 2 members in frontend, got %+v", frontend.Members)
+		}
+	})
+
+	t.Run("MemberOfUnknownTeamRollsBack", func(t *testing.T) {
+		repos := newRepos(t)
+		_, err := repos.Teams.CreateOrUpdateTeam(ctx, domain.Team{
+			TeamName: "backend",
+			Members:  []domain.User{{UserID: "u1", Username: "name-u1", TeamName: "ghost", IsActive: true}},
+		})
+		assertCode(t, err, domain.ErrNotFound)
+
+		_, err = repos.Teams.GetTeamByName(ctx, "backend")
+		assertCode(t, err, domain.ErrNotFound)
+		_, err = repos.Teams.GetUserByID(ctx, "u1")
+		assertCode(t, err, domain.ErrNotFound)
+	})
+
+	t.Run("GetTeamByNameNotFound", func(t *testing.T) {
+		repos := newRepos(t)
+		_, err := repos.Teams.GetTeamByName(ctx, "missing")
+		assertCode(t, err, domain.ErrNotFound)
+	})
+
+	t.Run("GetUserByID", func(t *testing.T) {
+		repos := newRepos(t)
+		seedTeam(t, repos, "backend", "u1")
+
+		u, err := repos.Teams.GetUserByID(ctx, "u1")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		want := domain.User{UserID: "u1", Username: "name-u1", TeamName: "backend", IsActive: true}
+		if u != want {
+			t.Errorf("Expected %+v, got %+v", want, u)
+		}
+
+		_, err = repos.Teams.GetUserByID(ctx, "missing")
+		assertCode(t, err, domain.ErrNotFound)
+	})
+
+	t.Run("SetUserIsActive", func(t *testing.T) {
+		repos := newRepos(t)
+		seedTeam(t, repos, "backend", "u1")
+
+		u, err := repos.Teams.SetUserIsActive(ctx, "u1", false)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if u.IsActive || u.UserID != "u1" || u.TeamName != "backend" {
+			t.Errorf("Expected inactive u1, got %+v", u)
+		}
+
+		stored, err := repos.Teams.GetUserByID(ctx, "u1")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if stored.IsActive {
+			t.Errorf("Expected change to be persisted")
+		}
+
+		_, err = repos.Teams.SetUserIsActive(ctx, "missing", true)
+		assertCode(t, err, domain.ErrNotFound)
+	})
+}
+
+func runPullRequestTests(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	t.Run("CreateAndGet", func(t *testing.T) {
+		repos := newRepos(t)
+		seedTeam(t, repos, "backend", "u1", "u2", "u3")
+
+		created, err := repos.PRs.CreatePullRequest(ctx, newPR("pr-1", "u1", at(0), "u2", "u3"))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if created.Version != 1 {
+			t.Errorf("Expected version 1, got %d", created.Version)
+		}
+
+		got, err := repos.PRs.GetPullRequestByID(ctx, "pr-1")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if got.PullRequestID != "pr-1" || got.PullRequestName != "name-pr-1" || got.AuthorID != "u1" ||
+			got.Status != domain.StatusOpen || got.Version != 1 {
+			t.Errorf("Unexpected PR %+v", got)
+		}
+		if !equalStrings(got.AssignedReviewers, []string{"u2", "u3"}) {
+			t.Errorf("Expected reviewers [u2 u3] in order, got %v", got.AssignedReviewers)
+		}
+		if got.CreatedAt == nil || !got.CreatedAt.Equal(at(0)) {
+			t.Errorf("Expected createdAt %v, got %v", at(0), got.CreatedAt)
+		}
+		if got.MergedAt != nil {
+			t.Errorf("Expected no mergedAt, got %v", got.MergedAt)
+		}
+	})
+
+	t.Run("ChangedPathsAndLabelsArePersisted", func(t *testing.T) {
+		repos := newRepos(t)
+		seedTeam(t, repos, "backend", "u1", "u2")
+
+		pr := newPR("pr-1", "u1", at(0), "u2")
+		pr.ChangedPaths = []string{"internal/api/router.go", "README.md"}
+		pr.Labels = []string{"bug", "api"}
+		created, err := repos.PRs.CreatePullRequest(ctx, pr)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		created.Status = domain.StatusMerged
+		if _, err := repos.PRs.UpdatePullRequest(ctx, created); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		got, err := repos.PRs.GetPullRequestByID(ctx, "pr-1")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !equalStrings(got.ChangedPaths, pr.ChangedPaths) || !equalStrings(got.Labels, pr.Labels) {
+			t.Errorf("Expected changed paths %v and labels %v, got %v and %v", pr.ChangedPaths, pr.Labels, got.ChangedPaths, got.Labels)
+		}
+	})
+
+	t.Run("CodeHostRefIsPersisted", func(t *testing.T) {
+		repos := newRepos(t)
+		seedTeam(t, repos, "backend", "u1", "u2")
+
+		pr := newPR("github-1-2", "u1", at(0), "u2")
+		pr.CodeHostRef = "github-1-2"
+		if _, err := repos.PRs.CreatePullRequest(ctx, pr); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if _, err := repos.PRs.CreatePullRequest(ctx, newPR("github-1-3", "u1", at(1), "u2")); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		for id, want := range map[string]string{"github-1-2": "github-1-2", "github-1-3": ""} {
+			got, err := repos.PRs.GetPullRequestByID(ctx, id)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got.CodeHostRef != want {
+				t.Errorf("Expected %s to have code host ref %q, got %q", id, want, got.CodeHostRef)
+			}
+		}
+	})
+
+	t.Run("CreateWithoutReviewers", func(t *testing.T) {
+		repos := newRepos(t)
+		seedTeam(t, repos, "backend", "u1")
+
+		if _, err := repos.PRs.CreatePullRequest(ctx, newPR("pr-1", "u1", at(0))); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		got, err := repos.PRs.GetPullRequestByID(ctx, "pr-1")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(got.AssignedReviewers) != 0 {
+			t.Errorf("Expected no reviewers, got %v", got.AssignedReviewers)
+		}
+	})
+
+	t.Run("CreateDuplicate", func(t *testing.T) {
+		repos := newRepos(t)
+		seedTeam(t, repos, "backend", "u1", "u2")
+
+		if _, err := repos.PRs.CreatePullRequest(ctx, newPR("pr-1", "u1", at(0), "u2")); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		_, err := repos.PRs.CreatePullRequest(ctx, newPR("pr-1", "u2", at(1)))
+		assertCode(t, err, domain.ErrPRExists)
+	})
+
+	t.Run("CreateWithUnknownAuthor", func(t *testing.T) {
+		repos := newRepos(t)
+		_, err := repos.PRs.CreatePullRequest(ctx, newPR("pr-1", "ghost", at(0)))
+		assertCode(t, err, domain.ErrNotFound)
+	})
+
+	t.Run("GetNotFound", func(t *testing.T) {
+		repos := newRepos(t)
+		_, err := repos.PRs.GetPullRequestByID(ctx, "missing")
+		assertCode(t, err, domain.ErrNotFound)
+	})
+
+	t.Run("UpdateBumpsVersion", func(t *testing.T) {
+		repos := newRepos(t)
+		seedTeam(t, repos, "backend", "u1", "u2", "u3")
+
+		pr, err := repos.PRs.CreatePullRequest(ctx, newPR("pr-1", "u1", at(0), "u2"))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		mergedAt := at(5)
+		pr.Status = domain.StatusMerged
+		pr.MergedAt = &mergedAt
+		pr.AssignedReviewers = []string{"u3"}
+
+		updated, err := repos.PRs.UpdatePullRequest(ctx, pr)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if updated.Version != 2 {
+			t.Errorf("Expected version 2, got %d", updated.Version)
+		}
+
+		got, err := repos.PRs.GetPullRequestByID(ctx, "pr-1")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if got.Status != domain.StatusMerged || got.Version != 2 || !equalStrings(got.AssignedReviewers, []string{"u3"}) {
+			t.Errorf("Update was not persisted, got %+v", got)
+		}
+		if got.MergedAt == nil || !got.MergedAt.Equal(mergedAt) {
+			t.Errorf("Expected mergedAt %v, got %v", mergedAt, got.MergedAt)
+		}
+	})
+
+	t.Run("UpdateStaleVersion", func(t *testing.T) {
+		repos := newRepos(t)
+		seedTeam(t, repos, "backend", "u1", "u2")
+
+		pr, err := repos.PRs.CreatePullRequest(ctx, newPR("pr-1", "u1", at(0), "u2"))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if _, err := repos.PRs.UpdatePullRequest(ctx, pr); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		pr.Status = domain.StatusMerged
+		_, err = repos.PRs.UpdatePullRequest(ctx, pr)
+		assertCode(t, err, domain.ErrVersionConflict)
+
+		got, err := repos.PRs.GetPullRequestByID(ctx, "pr-1")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if got.Status != domain.StatusOpen {
+			t.Errorf("Stale update must not be applied, got status %s", got.Status)
+		}
+	})
+
+	t.Run("UpdateNotFound", func(t *testing.T) {
+		repos := newRepos(t)
+		seedTeam(t, repos, "backend", "u1")
+
+		pr := newPR("missing", "u1", at(0))
+		pr.Version = 1
+		_, err := repos.PRs.UpdatePullRequest(ctx, pr)
+		assertCode(t, err, domain.ErrNotFound)
+	})
+
+	t.Run("GetPRsByReviewerID", func(t *testing.T) {
+		repos := newRepos(t)
+		seedTeam(t, repos, "backend", "u1", "u2", "u3")
+
+		for _, pr := range []domain.PullRequest{
+			newPR("pr-old", "u1", at(0), "u2"),
+			newPR("pr-new", "u1", at(2), "u3", "u2"),
+			newPR("pr-mid", "u3", at(1), "u2"),
+			newPR("pr-other", "u1", at(3), "u3"),
+		} {
+			if _, err := repos.PRs.CreatePullRequest(ctx, pr); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+		}
+
+		prs, err := repos.PRs.GetPRsByReviewerID(ctx, "u2")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		var ids []string
+		for _, pr := range prs {
+			ids = append(ids, pr.PullRequestID)
+		}
+		if !equalStrings(ids, []string{"pr-new", "pr-mid", "pr-old"}) {
+			t.Errorf("Expected PRs newest first [pr-new pr-mid pr-old], got %v", ids)
+		}
+		if prs[1].AuthorID != "u3" || prs[1].PullRequestName != "name-pr-mid" || prs[1].Status != domain.StatusOpen {
+			t.Errorf("Unexpected short PR %+v", prs[1])
+		}
+
+		none, err := repos.PRs.GetPRsByReviewerID(ctx, "u1")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(none) != 0 {
+			t.Errorf("Expected no PRs for the author, got %v", none)
+		}
+	})
+}
+
+func runIdempotencyTests(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	repos := newRepos(t)
+	if repos.Idempotency == nil {
+		t.Skip("idempotency repository not provided")
+	}
+
+	now := at(0)
+	rec := domain.IdempotencyRecord{Key: "key-1", RequestHash: "hash-1", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+
+	t.Run("ReserveCompleteReplay", func(t *testing.T) {
+		if _, reserved, err := repos.Idempotency.ReserveIdempotencyKey(ctx, rec); err != nil || !reserved {
+			t.Fatalf("Expected key to be reserved, got reserved=%v err=%v", reserved, err)
+		}
+
+		stored, reserved, err := repos.Idempotency.ReserveIdempotencyKey(ctx, rec)
+		if err != nil || reserved {
+			t.Fatalf("Expected key to be taken, got reserved=%v err=%v", reserved, err)
+		}
+		if stored.StatusCode != 0 {
+			t.Errorf("Expected in-flight record, got status %d", stored.StatusCode)
+		}
+
+		done := rec
+		done.StatusCode = 201
+		done.Headers = map[string][]string{"Content-Type": {"application/json"}}
+		done.Body = []byte(`{"ok":true}`)
+		if err := repos.Idempotency.CompleteIdempotencyKey(ctx, done); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		stored, _, err = repos.Idempotency.ReserveIdempotencyKey(ctx, rec)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if stored.StatusCode != 201 || string(stored.Body) != `{"ok":true}` || stored.RequestHash != "hash-1" {
+			t.Errorf("Unexpected stored record %+v", stored)
+		}
+		if got := stored.Headers["Content-Type"]; len(got) != 1 || got[0] != "application/json" {
+			t.Errorf("Expected stored headers, got %v", stored.Headers)
+		}
+	})
+
+	t.Run("ReleaseInFlight", func(t *testing.T) {
+		pending := domain.IdempotencyRecord{Key: "key-2", RequestHash: "hash-2", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+		if _, reserved, err := repos.Idempotency.ReserveIdempotencyKey(ctx, pending); err != nil || !reserved {
+			t.Fatalf("Expected key to be reserved, got reserved=%v err=%v", reserved, err)
+		}
+		if err := repos.Idempotency.ReleaseIdempotencyKey(ctx, "key-2"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if _, reserved, err := repos.Idempotency.ReserveIdempotencyKey(ctx, pending); err != nil || !reserved {
+			t.Errorf("Expected released key to be reservable, got reserved=%v err=%v", reserved, err)
+		}
+	})
+
+	t.Run("ExpiredKeys", func(t *testing.T) {
+		later := rec
+		later.RequestHash = "hash-later"
+		later.CreatedAt = now.Add(2 * time.Hour)
+		later.ExpiresAt = later.CreatedAt.Add(time.Hour)
+		if _, reserved, err := repos.Idempotency.ReserveIdempotencyKey(ctx, later); err != nil || !reserved {
+			t.Fatalf("Expected expired key to be reusable, got reserved=%v err=%v", reserved, err)
+		}
+
+		deleted, err := repos.Idempotency.DeleteExpiredIdempotencyKeys(ctx, now.Add(10*time.Hour))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if deleted != 2 {
+			t.Errorf("Expected 2 expired keys to be deleted, got %d", deleted)
+		}
+	})
+}
+
+func runHealthTests(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	repos := newRepos(t)
+	if repos.Health == nil {
+		t.Skip("health repository not provided")
+	}
+	if err := repos.Health.Ping(ctx); err != nil {
+		t.Errorf("Ping failed: %v", err)
+	}
+	if err := repos.Health.CheckMigrations(ctx); err != nil {
+		t.Errorf("Expected migrations to be applied, got %v", err)
+	}
+}
+
+func runTokenTests(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	newToken := func(id, hash string, createdAt time.Time, roles ...domain.Role) domain.APIToken {
+		return domain.APIToken{ID: id, Name: "token " + id, TokenHash: hash, Roles: roles, CreatedAt: createdAt}
+	}
+
+	t.Run("CreateAndGetByHash", func(t *testing.T) {
+		repos := newRepos(t)
+		if repos.Tokens == nil {
+			t.Skip("token repository not provided")
+		}
+		expires := at(60)
+		token := newToken("t1", "hash-1", at(0), domain.RoleTeamLead, domain.RoleMember)
+		token.UserID = "u1"
+		token.ExpiresAt = &expires
+		if _, err := repos.Tokens.CreateAPIToken(ctx, token); err != nil {
+			t.Fatalf("CreateAPIToken failed: %v", err)
+		}
+
+		got, err := repos.Tokens.GetAPITokenByHash(ctx, "hash-1")
+		if err != nil {
+			t.Fatalf("GetAPITokenByHash failed: %v", err)
+		}
+		if got.ID != "t1" || got.UserID != "u1" || got.Name != "token t1" || got.RevokedAt != nil {
+			t.Errorf("Unexpected token %+v", got)
+		}
+		if len(got.Roles) != 2 || got.Roles[0] != domain.RoleTeamLead || got.Roles[1] != domain.RoleMember {
+			t.Errorf("Expected roles to round-trip, got %v", got.Roles)
+		}
+		if !got.CreatedAt.Equal(at(0)) || got.ExpiresAt == nil || !got.ExpiresAt.Equal(expires) {
+			t.Errorf("Expected timestamps to round-trip, got %v %v", got.CreatedAt, got.ExpiresAt)
+		}
+
+		_, err = repos.Tokens.GetAPITokenByHash(ctx, "missing")
+		assertCode(t, err, domain.ErrNotFound)
+	})
+
+	t.Run("DuplicateHash", func(t *testing.T) {
+		repos := newRepos(t)
+		if repos.Tokens == nil {
+			t.Skip("token repository not provided")
+		}
+		if _, err := repos.Tokens.CreateAPIToken(ctx, newToken("t1", "hash-1", at(0), domain.RoleAdmin)); err != nil {
+			t.Fatalf("CreateAPIToken failed: %v", err)
+		}
+		_, err := repos.Tokens.CreateAPIToken(ctx, newToken("t2", "hash-1", at(1), domain.RoleBot))
+		assertCode(t, err, domain.ErrTokenExists)
+	})
+
+	t.Run("ListAndRevoke", func(t *testing.T) {
+		repos := newRepos(t)
+		if repos.Tokens == nil {
+			t.Skip("token repository not provided")
+		}
+		for _, token := range []domain.APIToken{
+			newToken("t2", "hash-2", at(1), domain.RoleBot),
+			newToken("t1", "hash-1", at(0), domain.RoleAdmin),
+		} {
+			if _, err := repos.Tokens.CreateAPIToken(ctx, token); err != nil {
+				t.Fatalf("CreateAPIToken failed: %v", err)
+			}
+		}
+
+		revoked, err := repos.Tokens.RevokeAPIToken(ctx, "t2", at(5))
+		if err != nil {
+			t.Fatalf("RevokeAPIToken failed: %v", err)
+		}
+		if revoked.RevokedAt == nil || !revoked.RevokedAt.Equal(at(5)) {
+			t.Errorf("Expected revoked_at to be set, got %v", revoked.RevokedAt)
+		}
+		again, err := repos.Tokens.RevokeAPIToken(ctx, "t2", at(9))
+		if err != nil {
+			t.Fatalf("Repeated RevokeAPIToken failed: %v", err)
+		}
+		if !again.RevokedAt.Equal(at(5)) {
+			t.Errorf("Expected the first revocation time to be kept, got %v", again.RevokedAt)
+		}
+		_, err = repos.Tokens.RevokeAPIToken(ctx, "missing", at(5))
+		assertCode(t, err, domain.ErrNotFound)
+
+		tokens, err := repos.Tokens.ListAPITokens(ctx)
+		if err != nil {
+			t.Fatalf("ListAPITokens failed: %v", err)
+		}
+		if len(tokens) != 2 || tokens[0].ID != "t1" || tokens[1].ID != "t2" {
+			t.Fatalf("Expected tokens ordered by creation, got %+v", tokens)
+		}
+		if tokens[0].RevokedAt != nil || tokens[1].RevokedAt == nil {
+			t.Errorf("Unexpected revocation state in list %+v", tokens)
+		}
+	})
+}
+
+func runStatsTests(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	repos := newRepos(t)
+	if repos.Stats == nil {
+		t.Skip("stats repository not provided")
+	}
+	seedTeam(t, repos, "backend", "u1", "u2", "u3")
+
+	for _, pr := range []domain.PullRequest{
+		newPR("pr-1", "u1", at(0), "u2", "u3"),
+		newPR("pr-2", "u1", at(1), "u2"),
+		newPR("pr-3", "u2", at(2)),
+		newPR("pr-4", "u1", at(3), "u3"),
+	} {
+		if _, err := repos.PRs.CreatePullRequest(ctx, pr); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	merged, err := repos.PRs.GetPullRequestByID(ctx, "pr-4")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	merged.Status = domain.StatusMerged
+	if _, err := repos.PRs.UpdatePullRequest(ctx, merged); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	stats, err := repos.Stats.GetReviewStats(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if stats.OpenPRs != 3 || stats.MergedPRs != 1 || stats.OpenPRsWithoutReviewers != 1 {
+		t.Errorf("Unexpected PR counts %+v", stats)
+	}
+	if stats.OpenReviewsByUser["u2"] != 2 || stats.OpenReviewsByUser["u3"] != 1 || len(stats.OpenReviewsByUser) != 2 {
+		t.Errorf("Unexpected open reviews %v", stats.OpenReviewsByUser)
+	}
+}
+
+func seedTeam(t *testing.T, repos Repositories, teamName string, userIDs ...string) {
+	t.Helper()
+	team := domain.Team{TeamName: teamName}
+	for _, id := range userIDs {
+		team.Members = append(team.Members, domain.User{UserID: id, Username: "name-" + id, TeamName: teamName, IsActive: true})
+	}
+	if _, err := repos.Teams.CreateOrUpdateTeam(context.Background(), team); err != nil {
+		t.Fatalf("Failed to seed team %s: %v", teamName, err)
+	}
+}
+
+func newPR(id, authorID string, createdAt time.Time, reviewers ...string) domain.PullRequest {
+	if reviewers == nil {
+		reviewers = []string{}
+	}
+	return domain.PullRequest{
+		PullRequestID:     id,
+		PullRequestName:   "name-" + id,
+		AuthorID:          authorID,
+		Status:            domain.StatusOpen,
+		AssignedReviewers: reviewers,
+		CreatedAt:         &createdAt,
+	}
+}
+
+// at returns a fixed timestamp with a precision every backend can store.
+func at(minutes int) time.Time {
+	return time.Date(2025, 1, 1, 12, minutes, 0, 0, time.UTC)
+}
+
+func assertCode(t *testing.T, err error, code domain.ErrorCode) {
+	t.Helper()
+	var businessErr *domain.BusinessError
+	if !errors.As(err, &businessErr) || businessErr.Code != code {
+		t.Fatalf("Expected error code %s, got %v", code, err)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func runAuditTests(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	t.Run("AppendAndFilter", func(t *testing.T) {
+		repos := newRepos(t)
+		if repos.Audit == nil {
+			t.Skip("audit repository not provided")
+		}
+		entries := []domain.AuditEntry{
+			{Actor: "u1", Action: domain.AuditUserSetActive, TargetIDs: []string{"u2"},
+				Before: json.RawMessage(`{"is_active":true}`), After: json.RawMessage(`{"is_active":false}`),
+				RequestID: "req-1", CreatedAt: at(0)},
+			{Actor: "u1", Action: domain.AuditPRMerge, TargetIDs: []string{"pr-42"}, CreatedAt: at(10)},
+			{Actor: "token:tok_1", Action: domain.AuditPRReassign, TargetIDs: []string{"pr-42", "u2", "u3"}, CreatedAt: at(20)},
+		}
+		for i, entry := range entries {
+			appended, err := repos.Audit.AppendAuditEntry(ctx, entry)
+			if err != nil {
+				t.Fatalf("AppendAuditEntry failed: %v", err)
+			}
+			if appended.ID == 0 || (i > 0 && appended.ID <= entries[i-1].ID) {
+				t.Fatalf("Expected increasing ids, got %d", appended.ID)
+			}
+			entries[i].ID = appended.ID
+		}
+
+		ids := func(filter domain.AuditFilter) []int64 {
+			t.Helper()
+			got, err := repos.Audit.ListAuditEntries(ctx, filter)
+			if err != nil {
+				t.Fatalf("ListAuditEntries failed: %v", err)
+			}
+			var out []int64
+			for _, entry := range got {
+				out = append(out, entry.ID)
+			}
+			return out
+		}
+		e0, e1, e2 := entries[0].ID, entries[1].ID, entries[2].ID
+		from, to := at(10), at(20)
+		for name, tc := range map[string]struct {
+			filter domain.AuditFilter
+			want   []int64
+		}{
+			"all, newest first": {domain.AuditFilter{}, []int64{e2, e1, e0}},
+			"actor":             {domain.AuditFilter{Actor: "u1"}, []int64{e1, e0}},
+			"target":            {domain.AuditFilter{Target: "u2"}, []int64{e2, e0}},
+			"time range":        {domain.AuditFilter{From: &from, To: &to}, []int64{e1}},
+			"limit":             {domain.AuditFilter{Limit: 2}, []int64{e2, e1}},
+			"combined":          {domain.AuditFilter{Actor: "u1", Target: "pr-42"}, []int64{e1}},
+			"no match":          {domain.AuditFilter{Target: "pr-1"}, nil},
+		} {
+			if got := ids(tc.filter); !slices.Equal(got, tc.want) {
+				t.Errorf("%s: expected %v, got %v", name, tc.want, got)
+			}
+		}
+
+		got, err := repos.Audit.ListAuditEntries(ctx, domain.AuditFilter{Target: "u2", Limit: 1, To: &from})
+		if err != nil || len(got) != 1 {
+			t.Fatalf("Expected one entry, got %v, %v", got, err)
+		}
+		entry := got[0]
+		if entry.Actor != "u1" || entry.Action != domain.AuditUserSetActive || entry.RequestID != "req-1" || !entry.CreatedAt.Equal(at(0)) {
+			t.Errorf("Unexpected entry %+v", entry)
+		}
+		if !jsonEqual(t, entry.Before, `{"is_active":true}`) || !jsonEqual(t, entry.After, `{"is_active":false}`) {
+			t.Errorf("Expected snapshots to round-trip, got %s and %s", entry.Before, entry.After)
+		}
+	})
+
+	t.Run("RecordedWithChange", func(t *testing.T) {
+		repos := newRepos(t)
+		if repos.Audit == nil {
+			t.Skip("audit repository not provided")
+		}
+		seedTeam(t, repos, "backend", "u1", "u2", "u3")
+		audited := func(action string) context.Context {
+			return repository.WithAudit(ctx, domain.AuditEntry{Actor: "lead", Action: action, RequestID: "req-1", CreatedAt: at(0)})
+		}
+
+		if _, err := repos.PRs.CreatePullRequest(audited(domain.AuditPRCreate), newPR("pr-1", "u1", at(0), "u2")); err != nil {
+			t.Fatalf("CreatePullRequest failed: %v", err)
+		}
+		pr, err := repos.PRs.GetPullRequestByID(ctx, "pr-1")
+		if err != nil {
+			t.Fatalf("GetPullRequestByID failed: %v", err)
+		}
+		pr.AssignedReviewers = []string{"u3"}
+		if _, err := repos.PRs.UpdatePullRequest(audited(domain.AuditPRReassign), pr); err != nil {
+			t.Fatalf("UpdatePullRequest failed: %v", err)
+		}
+		// A stale version fails the update, and with it its entry.
+		if _, err := repos.PRs.UpdatePullRequest(audited(domain.AuditPRMerge), pr); err == nil {
+			t.Fatal("Expected a version conflict")
+		}
+		if _, err := repos.Teams.SetUserIsActive(audited(domain.AuditUserSetActive), "u2", true); err != nil {
+			t.Fatalf("SetUserIsActive failed: %v", err)
+		}
+
+		entries, err := repos.Audit.ListAuditEntries(ctx, domain.AuditFilter{})
+		if err != nil {
+			t.Fatalf("ListAuditEntries failed: %v", err)
+		}
+		if len(entries) != 2 || entries[0].Action != domain.AuditPRReassign || entries[1].Action != domain.AuditPRCreate {
+			t.Fatalf("Expected entries of the reassignment and the creation only, got %+v", entries)
+		}
+		reassign := entries[0]
+		if reassign.Actor != "lead" || reassign.RequestID != "req-1" || !equalStrings(reassign.TargetIDs, []string{"pr-1", "u2", "u3"}) {
+			t.Errorf("Unexpected entry %+v", reassign)
+		}
+		var before, after domain.PullRequest
+		if err := json.Unmarshal(reassign.Before, &before); err != nil || !equalStrings(before.AssignedReviewers, []string{"u2"}) {
+			t.Errorf("Expected the reviewers before the change, got %s (%v)", reassign.Before, err)
+		}
+		if err := json.Unmarshal(reassign.After, &after); err != nil || !equalStrings(after.AssignedReviewers, []string{"u3"}) {
+			t.Errorf("Expected the reviewers after the change, got %s (%v)", reassign.After, err)
+		}
+		if entries[1].Before != nil || !equalStrings(entries[1].TargetIDs, []string{"pr-1", "u1", "u2"}) {
+			t.Errorf("Unexpected creation entry %+v", entries[1])
+		}
+	})
+}
+
+func jsonEqual(t *testing.T, raw json.RawMessage, want string) bool {
+	t.Helper()
+	var a, b any
+	if err := json.Unmarshal(raw, &a); err != nil {
+		return false
+	}
+	if err := json.Unmarshal([]byte(want), &b); err != nil {
+		t.Fatalf("Invalid expected JSON: %v", err)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func runWebhookTests(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	newSub := func(t *testing.T, repos Repositories, id string) domain.WebhookSubscription {
+		t.Helper()
+		sub, err := repos.Webhooks.CreateWebhookSubscription(ctx, domain.WebhookSubscription{
+			ID: id, URL: "https://hooks.example.com/" + id, Secret: "secret-" + id,
+			Events: []string{domain.EventPRMerged, domain.EventReviewersAssigned}, CreatedAt: at(0),
+		})
+		if err != nil {
+			t.Fatalf("CreateWebhookSubscription failed: %v", err)
+		}
+		return sub
+	}
+	newDelivery := func(id, subID string, createdAt time.Time) domain.WebhookDelivery {
+		return domain.WebhookDelivery{
+			ID: id, SubscriptionID: subID, EventID: "evt-" + id, EventType: domain.EventPRMerged,
+			Payload: json.RawMessage(`{"id":"evt-` + id + `"}`), Status: domain.DeliveryPending,
+			NextAttemptAt: createdAt, CreatedAt: createdAt,
+		}
+	}
+
+	t.Run("Subscriptions", func(t *testing.T) {
+		repos := newRepos(t)
+		if repos.Webhooks == nil {
+			t.Skip("webhook repository not provided")
+		}
+		newSub(t, repos, "s2")
+		newSub(t, repos, "s1")
+		_, err := repos.Webhooks.CreateWebhookSubscription(ctx, domain.WebhookSubscription{ID: "s1", URL: "https://x", Secret: "x", Events: []string{"*"}, CreatedAt: at(1)})
+		assertCode(t, err, domain.ErrWebhookExists)
+
+		subs, err := repos.Webhooks.ListWebhookSubscriptions(ctx)
+		if err != nil {
+			t.Fatalf("ListWebhookSubscriptions failed: %v", err)
+		}
+		if len(subs) != 2 || subs[0].ID != "s1" || subs[1].ID != "s2" {
+			t.Fatalf("Expected s1 and s2, got %+v", subs)
+		}
+		if subs[0].Secret != "secret-s1" || subs[0].URL != "https://hooks.example.com/s1" ||
+			!slices.Equal(subs[0].Events, []string{domain.EventPRMerged, domain.EventReviewersAssigned}) || !subs[0].CreatedAt.Equal(at(0)) {
+			t.Errorf("Expected subscription to round-trip, got %+v", subs[0])
+		}
+
+		if err := repos.Webhooks.CreateWebhookDeliveries(ctx, []domain.WebhookDelivery{newDelivery("d1", "s1", at(0))}); err != nil {
+			t.Fatalf("CreateWebhookDeliveries failed: %v", err)
+		}
+		deleted, err := repos.Webhooks.DeleteWebhookSubscription(ctx, "s1")
+		if err != nil || deleted.ID != "s1" {
+			t.Fatalf("Expected s1 to be deleted, got %+v, %v", deleted, err)
+		}
+		_, err = repos.Webhooks.DeleteWebhookSubscription(ctx, "s1")
+		assertCode(t, err, domain.ErrNotFound)
+		claimed, err := repos.Webhooks.ClaimWebhookDeliveries(ctx, at(5), time.Minute, 10)
+		if err != nil || len(claimed) != 0 {
+			t.Errorf("Expected deliveries of a deleted subscription to be gone, got %+v, %v", claimed, err)
+		}
+	})
+
+	t.Run("DeliveryLifecycle", func(t *testing.T) {
+		repos := newRepos(t)
+		if repos.Webhooks == nil {
+			t.Skip("webhook repository not provided")
+		}
+		newSub(t, repos, "s1")
+		err := repos.Webhooks.CreateWebhookDeliveries(ctx, []domain.WebhookDelivery{
+			newDelivery("d2", "s1", at(1)), newDelivery("d1", "s1", at(0)), newDelivery("d3", "s1", at(10)),
+		})
+		if err != nil {
+			t.Fatalf("CreateWebhookDeliveries failed: %v", err)
+		}
+
+		claimed, err := repos.Webhooks.ClaimWebhookDeliveries(ctx, at(5), time.Minute, 10)
+		if err != nil {
+			t.Fatalf("ClaimWebhookDeliveries failed: %v", err)
+		}
+		if len(claimed) != 2 || claimed[0].ID != "d1" || claimed[1].ID != "d2" {
+			t.Fatalf("Expected due deliveries d1, d2 oldest first, got %+v", claimed)
+		}
+		d := claimed[0]
+		if d.URL != "https://hooks.example.com/s1" || d.Secret != "secret-s1" || d.EventID != "evt-d1" || !jsonEqual(t, d.Payload, `{"id":"evt-d1"}`) {
+			t.Errorf("Expected delivery with subscription details, got %+v", d)
+		}
+		if again, _ := repos.Webhooks.ClaimWebhookDeliveries(ctx, at(5), time.Minute, 10); len(again) != 0 {
+			t.Errorf("Expected claimed deliveries to be leased, got %+v", again)
+		}
+
+		delivered := at(6)
+		d.Status, d.Attempts, d.DeliveredAt = domain.DeliveryDelivered, 1, &delivered
+		if err := repos.Webhooks.UpdateWebhookDelivery(ctx, d); err != nil {
+			t.Fatalf("UpdateWebhookDelivery failed: %v", err)
+		}
+		dead := claimed[1]
+		dead.Status, dead.Attempts, dead.LastError = domain.DeliveryDead, 5, "unexpected status 500"
+		if err := repos.Webhooks.UpdateWebhookDelivery(ctx, dead); err != nil {
+			t.Fatalf("UpdateWebhookDelivery failed: %v", err)
+		}
+		err = repos.Webhooks.UpdateWebhookDelivery(ctx, domain.WebhookDelivery{ID: "missing", Status: domain.DeliveryDead, NextAttemptAt: at(0)})
+		assertCode(t, err, domain.ErrNotFound)
+
+		deadList, err := repos.Webhooks.ListWebhookDeliveries(ctx, domain.DeliveryDead, 10)
+		if err != nil || len(deadList) != 1 || deadList[0].ID != "d2" || deadList[0].Attempts != 5 || deadList[0].LastError != "unexpected status 500" {
+			t.Fatalf("Expected d2 in the dead-letter list, got %+v, %v", deadList, err)
+		}
+		if pending, _ := repos.Webhooks.ListWebhookDeliveries(ctx, domain.DeliveryPending, 10); len(pending) != 1 || pending[0].ID != "d3" {
+			t.Errorf("Expected d3 pending, got %+v", pending)
+		}
+
+		_, err = repos.Webhooks.RequeueWebhookDelivery(ctx, "d1", at(7))
+		assertCode(t, err, domain.ErrNotFound)
+		requeued, err := repos.Webhooks.RequeueWebhookDelivery(ctx, "d2", at(7))
+		if err != nil || requeued.Status != domain.DeliveryPending || requeued.Attempts != 0 || !requeued.NextAttemptAt.Equal(at(7)) {
+			t.Fatalf("Expected d2 to be pending again, got %+v, %v", requeued, err)
+		}
+		if claimed, _ := repos.Webhooks.ClaimWebhookDeliveries(ctx, at(8), time.Minute, 10); len(claimed) != 1 || claimed[0].ID != "d2" {
+			t.Errorf("Expected the requeued delivery to be claimable, got %+v", claimed)
+		}
+
+		if n, err := repos.Webhooks.DeleteDeliveredWebhookDeliveries(ctx, at(6)); err != nil || n != 0 {
+			t.Errorf("Expected nothing delivered before the cutoff, got %d, %v", n, err)
+		}
+		if n, err := repos.Webhooks.DeleteDeliveredWebhookDeliveries(ctx, at(7)); err != nil || n != 1 {
+			t.Errorf("Expected d1 to be deleted, got %d, %v", n, err)
+		}
+	})
+}
+
+func runOutboxTests(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	// Events are recorded at the current time, so claims look slightly ahead.
+	later := func() time.Time { return time.Now().UTC().Add(time.Minute) }
+
+	t.Run("RecordsEventsWithMutations", func(t *testing.T) {
+		repos := newRepos(t)
+		if repos.Outbox == nil {
+			t.Skip("outbox repository not provided")
+		}
+		seedTeam(t, repos, "backend", "u1", "u2", "u3")
+		pr, err := repos.PRs.CreatePullRequest(ctx, newPR("pr-1", "u1", at(0), "u2"))
+		if err != nil {
+			t.Fatalf("CreatePullRequest failed: %v", err)
+		}
+		pr.AssignedReviewers = []string{"u3"}
+		if pr, err = repos.PRs.UpdatePullRequest(ctx, pr); err != nil {
+			t.Fatalf("UpdatePullRequest failed: %v", err)
+		}
+		stale := pr
+		stale.Version--
+		if _, err := repos.PRs.UpdatePullRequest(ctx, stale); err == nil {
+			t.Fatal("Expected a version conflict")
+		}
+		merged := at(5)
+		pr.Status, pr.MergedAt = domain.StatusMerged, &merged
+		if pr, err = repos.PRs.UpdatePullRequest(ctx, pr); err != nil {
+			t.Fatalf("UpdatePullRequest failed: %v", err)
+		}
+		if _, err := repos.PRs.UpdatePullRequest(ctx, pr); err != nil {
+			t.Fatalf("UpdatePullRequest failed: %v", err)
+		}
+		for _, active := range []bool{false, false} {
+			if _, err := repos.Teams.SetUserIsActive(ctx, "u2", active); err != nil {
+				t.Fatalf("SetUserIsActive failed: %v", err)
+			}
+		}
+
+		events, err := repos.Outbox.ClaimOutboxEvents(ctx, later(), time.Minute, 100)
+		if err != nil {
+			t.Fatalf("ClaimOutboxEvents failed: %v", err)
+		}
+		var types []string
+		for i, e := range events {
+			types = append(types, e.Type)
+			if i > 0 && e.Seq <= events[i-1].Seq {
+				t.Errorf("Expected increasing sequence numbers, got %d after %d", e.Seq, events[i-1].Seq)
+			}
+			if e.ID != strconv.FormatInt(e.Seq, 10) || e.PublishedAt != nil || e.Attempts != 0 {
+				t.Errorf("Unexpected event state %+v", e)
+			}
+		}
+		want := []string{domain.EventTeamUpdated, domain.EventReviewersAssigned, domain.EventReviewerReassigned,
+			domain.EventPRMerged, domain.EventUserActivityChanged}
+		if !slices.Equal(types, want) {
+			t.Fatalf("Expected events %v, got %v", want, types)
+		}
+
+		if team := events[0].Team; team == nil || team.TeamName != "backend" || len(team.Members) != 3 || events[0].AggregateID != "team:backend" {
+			t.Errorf("Expected the team in its event, got %+v", events[0])
+		}
+		if pr := events[1].PullRequest; pr == nil || pr.PullRequestID != "pr-1" || !slices.Equal(pr.AssignedReviewers, []string{"u2"}) ||
+			events[1].AggregateID != "pull_request:pr-1" {
+			t.Errorf("Expected the created PR in its event, got %+v", events[1])
+		}
+		if e := events[2]; e.OldReviewerID != "u2" || e.NewReviewerID != "u3" || e.PullRequest.Version != 2 {
+			t.Errorf("Expected u2 to be replaced by u3 at version 2, got %+v", e)
+		}
+		if e := events[3]; e.PullRequest.Status != domain.StatusMerged || e.PullRequest.MergedAt == nil {
+			t.Errorf("Expected the merged PR in its event, got %+v", e.PullRequest)
+		}
+		if e := events[4]; e.User == nil || e.User.UserID != "u2" || e.User.IsActive || e.AggregateID != "team:backend" {
+			t.Errorf("Expected u2's deactivation in the team's order, got %+v", e)
+		}
+	})
+
+	t.Run("ClaimsInOrderPerAggregate", func(t *testing.T) {
+		repos := newRepos(t)
+		if repos.Outbox == nil {
+			t.Skip("outbox repository not provided")
+		}
+		seedTeam(t, repos, "backend", "u1", "u2", "u3")
+		for _, id := range []string{"pr-1", "pr-2"} {
+			if _, err := repos.PRs.CreatePullRequest(ctx, newPR(id, "u1", at(0), "u2")); err != nil {
+				t.Fatalf("CreatePullRequest failed: %v", err)
+			}
+		}
+		pr, _ := repos.PRs.GetPullRequestByID(ctx, "pr-1")
+		pr.AssignedReviewers = []string{"u3"}
+		if _, err := repos.PRs.UpdatePullRequest(ctx, pr); err != nil {
+			t.Fatalf("UpdatePullRequest failed: %v", err)
+		}
+
+		now := later()
+		claimed, err := repos.Outbox.ClaimOutboxEvents(ctx, now, time.Minute, 3)
+		if err != nil || len(claimed) != 3 {
+			t.Fatalf("Expected the team event and both creations, got %+v, %v", claimed, err)
+		}
+		if again, _ := repos.Outbox.ClaimOutboxEvents(ctx, now, time.Minute, 10); len(again) != 0 {
+			t.Fatalf("Expected pr-1's reassignment to wait for its leased creation, got %+v", again)
+		}
+
+		published := now
+		team, pr1, pr2 := claimed[0], claimed[1], claimed[2]
+		for _, e := range []domain.OutboxEvent{team, pr2} {
+			e.Attempts, e.PublishedAt = 1, &published
+			if err := repos.Outbox.UpdateOutboxEvent(ctx, e); err != nil {
+				t.Fatalf("UpdateOutboxEvent failed: %v", err)
+			}
+		}
+		pr1.Attempts, pr1.LastError, pr1.NextAttemptAt = 1, "sink unavailable", now.Add(10*time.Second)
+		pr1.PublishedSinks = []string{"stream", "webhook"}
+		if err := repos.Outbox.UpdateOutboxEvent(ctx, pr1); err != nil {
+			t.Fatalf("UpdateOutboxEvent failed: %v", err)
+		}
+		err = repos.Outbox.UpdateOutboxEvent(ctx, domain.OutboxEvent{Seq: 1 << 40, NextAttemptAt: now})
+		assertCode(t, err, domain.ErrNotFound)
+
+		if claimed, _ := repos.Outbox.ClaimOutboxEvents(ctx, now.Add(5*time.Second), time.Minute, 10); len(claimed) != 0 {
+			t.Fatalf("Expected pr-1 to wait for its retry, got %+v", claimed)
+		}
+		claimed, err = repos.Outbox.ClaimOutboxEvents(ctx, now.Add(10*time.Second), time.Minute, 10)
+		if err != nil || len(claimed) != 2 || claimed[0].Seq != pr1.Seq || claimed[1].Type != domain.EventReviewerReassigned {
+			t.Fatalf("Expected pr-1's creation and reassignment in order, got %+v, %v", claimed, err)
+		}
+		if claimed[0].Attempts != 1 || claimed[0].LastError != "sink unavailable" ||
+			!equalStrings(claimed[0].PublishedSinks, []string{"stream", "webhook"}) {
+			t.Errorf("Expected the failed attempt to be kept, got %+v", claimed[0])
+		}
+
+		if n, err := repos.Outbox.DeletePublishedOutboxEvents(ctx, published); err != nil || n != 0 {
+			t.Errorf("Expected nothing published before the cutoff, got %d, %v", n, err)
+		}
+		if n, err := repos.Outbox.DeletePublishedOutboxEvents(ctx, published.Add(time.Second)); err != nil || n != 2 {
+			t.Errorf("Expected the two published events to be deleted, got %d, %v", n, err)
+		}
+	})
+}
+
+func runUserMappingTests(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	t.Run("SetGetListDelete", func(t *testing.T) {
+		repos := newRepos(t)
+		if repos.Mappings == nil {
+			t.Skip("user mapping repository not provided")
+		}
+		for i, m := range []domain.UserMapping{
+			{Provider: "gitlab", Login: "alice", UserID: "u1"},
+			{Provider: domain.ProviderGitHub, Login: "octocat", UserID: "u2"},
+			{Provider: domain.ProviderGitHub, Login: "alice", UserID: "u1"},
+		} {
+			m.CreatedAt = at(i)
+			if _, err := repos.Mappings.SetUserMapping(ctx, m); err != nil {
+				t.Fatalf("SetUserMapping failed: %v", err)
+			}
+		}
+
+		updated, err := repos.Mappings.SetUserMapping(ctx, domain.UserMapping{Provider: domain.ProviderGitHub, Login: "octocat", UserID: "u3", CreatedAt: at(9)})
+		if err != nil || updated.UserID != "u3" || !updated.CreatedAt.Equal(at(1)) {
+			t.Fatalf("Expected octocat to be remapped keeping its creation time, got %+v, %v", updated, err)
+		}
+		got, err := repos.Mappings.GetUserMapping(ctx, domain.ProviderGitHub, "octocat")
+		if err != nil || got.UserID != "u3" {
+			t.Fatalf("Expected octocat to map to u3, got %+v, %v", got, err)
+		}
+		_, err = repos.Mappings.GetUserMapping(ctx, "gitlab", "octocat")
+		assertCode(t, err, domain.ErrNotFound)
+
+		github, err := repos.Mappings.ListUserMappings(ctx, domain.ProviderGitHub)
+		if err != nil || len(github) != 2 || github[0].Login != "alice" || github[1].Login != "octocat" {
+			t.Fatalf("Expected alice and octocat, got %+v, %v", github, err)
+		}
+		all, err := repos.Mappings.ListUserMappings(ctx, "")
+		if err != nil || len(all) != 3 || all[0].Provider != domain.ProviderGitHub || all[2].Provider != "gitlab" {
+			t.Fatalf("Expected every mapping ordered by provider, got %+v, %v", all, err)
+		}
+
+		deleted, err := repos.Mappings.DeleteUserMapping(ctx, domain.ProviderGitHub, "alice")
+		if err != nil || deleted.UserID != "u1" {
+			t.Fatalf("Expected alice to be deleted, got %+v, %v", deleted, err)
+		}
+		_, err = repos.Mappings.DeleteUserMapping(ctx, domain.ProviderGitHub, "alice")
+		assertCode(t, err, domain.ErrNotFound)
+		if _, err := repos.Mappings.GetUserMapping(ctx, "gitlab", "alice"); err != nil {
+			t.Errorf("Expected the gitlab mapping of alice to remain, got %v", err)
+		}
+	})
+}
+
+func runCodeOwnersTests(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	t.Run("SetAndGet", func(t *testing.T) {
+		repos := newRepos(t)
+		if repos.CodeOwners == nil {
+			t.Skip("CODEOWNERS repository not provided")
+		}
+		seedTeam(t, repos, "backend", "u1")
+
+		_, err := repos.CodeOwners.GetCodeOwners(ctx, "backend")
+		assertCode(t, err, domain.ErrNotFound)
+		_, err = repos.CodeOwners.SetCodeOwners(ctx, domain.CodeOwners{TeamName: "ghost", Rules: "* u1", UpdatedAt: at(0)})
+		assertCode(t, err, domain.ErrNotFound)
+
+		for i, rules := range []string{"* u1", "*.go u1\ndocs/ @backend"} {
+			saved, err := repos.CodeOwners.SetCodeOwners(ctx, domain.CodeOwners{TeamName: "backend", Rules: rules, UpdatedAt: at(i)})
+			if err != nil || saved.Rules != rules || !saved.UpdatedAt.Equal(at(i)) {
+				t.Fatalf("Expected the rules to be stored, got %+v, %v", saved, err)
+			}
+		}
+		got, err := repos.CodeOwners.GetCodeOwners(ctx, "backend")
+		if err != nil || got.TeamName != "backend" || got.Rules != "*.go u1\ndocs/ @backend" || !got.UpdatedAt.Equal(at(1)) {
+			t.Errorf("Expected the replaced rules, got %+v, %v", got, err)
+		}
+	})
+}
+
+func runReviewHistoryTests(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	t.Run("ListReviewedPullRequests", func(t *testing.T) {
+		repos := newRepos(t)
+		if repos.History == nil {
+			t.Skip("review history repository not provided")
+		}
+		seedTeam(t, repos, "backend", "u1", "u2", "u3")
+
+		old := newPR("pr-old", "u1", at(0), "u2")
+		unreviewed := newPR("pr-unreviewed", "u1", at(2))
+		late := newPR("pr-late", "u1", at(3), "u3", "u2")
+		late.Labels = []string{"api"}
+		early := newPR("pr-early", "u1", at(1), "u2")
+		early.ChangedPaths = []string{"internal/api/router.go"}
+		for _, pr := range []domain.PullRequest{old, unreviewed, late, early} {
+			if _, err := repos.PRs.CreatePullRequest(ctx, pr); err != nil {
+				t.Fatalf("Failed to create %s: %v", pr.PullRequestID, err)
+			}
+		}
+
+		prs, err := repos.History.ListReviewedPullRequests(ctx, at(1))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		var ids []string
+		for _, pr := range prs {
+			ids = append(ids, pr.PullRequestID)
+		}
+		if !equalStrings(ids, []string{"pr-early", "pr-late"}) {
+			t.Fatalf("Expected the reviewed PRs since the cutoff oldest first, got %v", ids)
+		}
+		if !equalStrings(prs[0].ChangedPaths, early.ChangedPaths) || !equalStrings(prs[1].Labels, late.Labels) ||
+			!equalStrings(prs[1].AssignedReviewers, late.AssignedReviewers) {
+			t.Errorf("Expected complete PRs, got %+v", prs)
+		}
+	})
+}
@@ -0,0 +1,1552 @@
+package sqlite
+
+import (
+	"Backend/internal/domain"
+	"Backend/internal/repository"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// timeLayout is fixed-width so that stored timestamps sort lexicographically.
+const timeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// migrations are applied in order; the number of applied migrations is kept in PRAGMA user_version.
+// Token roles, audit target ids, webhook event filters and changed paths and labels of PRs
+// are stored as JSON arrays.
+// SQLite has no array type, so assigned reviewers live in pr_reviewers, whose user_id index
+// replaces the GIN index used by PostgreSQL.
+var migrations = []string{
+	`
+	CREATE TABLE teams (
+		team_name TEXT PRIMARY KEY
+	);
+
+	CREATE TABLE users (
+		user_id TEXT PRIMARY KEY,
+		username TEXT NOT NULL,
+		team_name TEXT NOT NULL REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE RESTRICT,
+		is_active INTEGER NOT NULL
+	);
+
+	CREATE TABLE pull_requests (
+		pr_id TEXT PRIMARY KEY,
+		pr_name TEXT NOT NULL,
+		author_id TEXT NOT NULL REFERENCES users(user_id) ON UPDATE CASCADE ON DELETE RESTRICT,
+		status TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		merged_at TEXT,
+		version INTEGER NOT NULL DEFAULT 1
+	);
+
+	CREATE TABLE pr_reviewers (
+		pr_id TEXT NOT NULL REFERENCES pull_requests(pr_id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		user_id TEXT NOT NULL,
+		PRIMARY KEY (pr_id, position)
+	);
+	CREATE INDEX idx_pr_reviewers_user ON pr_reviewers (user_id);
+
+	CREATE TABLE idempotency_keys (
+		key TEXT PRIMARY KEY,
+		request_hash TEXT NOT NULL,
+		status_code INTEGER NOT NULL DEFAULT 0,
+		headers TEXT,
+		body BLOB,
+		created_at TEXT NOT NULL,
+		expires_at TEXT NOT NULL
+	);
+	CREATE INDEX idx_idempotency_expires ON idempotency_keys (expires_at);
+	`,
+	`
+	CREATE TABLE api_tokens (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		user_id TEXT,
+		roles TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		expires_at TEXT,
+		revoked_at TEXT
+	);
+	`,
+	`
+	CREATE TABLE audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		actor TEXT NOT NULL,
+		action TEXT NOT NULL,
+		target_ids TEXT NOT NULL,
+		before_state TEXT,
+		after_state TEXT,
+		request_id TEXT,
+		created_at TEXT NOT NULL
+	);
+	CREATE INDEX idx_audit_actor ON audit_log (actor, id);
+	CREATE INDEX idx_audit_created ON audit_log (created_at);
+	`,
+	`
+	CREATE TABLE webhook_subscriptions (
+		id TEXT PRIMARY KEY,
+		url TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		events TEXT NOT NULL,
+		created_at TEXT NOT NULL
+	);
+
+	CREATE TABLE webhook_deliveries (
+		id TEXT PRIMARY KEY,
+		subscription_id TEXT NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+		event_id TEXT NOT NULL,
+		event_type TEXT NOT NULL,
+		payload TEXT NOT NULL,
+		status TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at TEXT NOT NULL,
+		last_error TEXT,
+		created_at TEXT NOT NULL,
+		delivered_at TEXT
+	);
+	CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
+	CREATE INDEX idx_webhook_deliveries_subscription ON webhook_deliveries (subscription_id);
+	`,
+	`
+	CREATE TABLE outbox (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		aggregate_id TEXT NOT NULL,
+		event_type TEXT NOT NULL,
+		payload TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at TEXT NOT NULL,
+		last_error TEXT,
+		created_at TEXT NOT NULL,
+		published_at TEXT
+	);
+	CREATE INDEX idx_outbox_pending ON outbox (aggregate_id, id) WHERE published_at IS NULL;
+	CREATE INDEX idx_outbox_published ON outbox (published_at);
+	`,
+	`
+	CREATE TABLE user_mappings (
+		provider TEXT NOT NULL,
+		login TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		PRIMARY KEY (provider, login)
+	);
+	`,
+	`
+	ALTER TABLE pull_requests ADD COLUMN changed_paths TEXT NOT NULL DEFAULT '[]';
+
+	CREATE TABLE team_codeowners (
+		team_name TEXT PRIMARY KEY REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE CASCADE,
+		rules TEXT NOT NULL,
+		updated_at TEXT NOT NULL
+	);
+	`,
+	`
+	ALTER TABLE pull_requests ADD COLUMN labels TEXT NOT NULL DEFAULT '[]';
+
+	CREATE INDEX idx_pull_requests_created ON pull_requests (created_at);
+	`,
+	`
+	ALTER TABLE pull_requests ADD COLUMN code_host_ref TEXT NOT NULL DEFAULT '';
+	`,
+	`
+	ALTER TABLE outbox ADD COLUMN published_sinks TEXT NOT NULL DEFAULT '[]';
+	`,
+}
+
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// Open opens the database file at path with foreign keys enabled. SQLite allows a
+// single writer, so the pool is limited to one connection to avoid SQLITE_BUSY errors.
+func Open(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+func NewSQLiteRepository(db *sql.DB) *SQLiteRepository {
+	return &SQLiteRepository{db: db}
+}
+
+func (r *SQLiteRepository) Init(ctx context.Context) error {
+	var applied int
+	if err := r.db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&applied); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if applied > len(migrations) {
+		return fmt.Errorf("database schema version %d is newer than supported version %d", applied, len(migrations))
+	}
+
+	for i := applied; i < len(migrations); i++ {
+		tx, err := r.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply migration %d: %w", i+1, err)
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+func (r *SQLiteRepository) CheckMigrations(ctx context.Context) error {
+	var applied int
+	if err := r.db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&applied); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if applied != len(migrations) {
+		return fmt.Errorf("schema is at version %d, expected %d", applied, len(migrations))
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) Close() error {
+	return r.db.Close()
+}
+
+func (r *SQLiteRepository) CreateOrUpdateTeam(ctx context.Context, team domain.Team) (domain.Team, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.Team{}, err
+	}
+	defer tx.Rollback()
+
+	before, err := queryTeam(ctx, tx, team.TeamName)
+	if err != nil {
+		return domain.Team{}, err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO teams (team_name) VALUES (?) ON CONFLICT (team_name) DO NOTHING",
+		team.TeamName)
+	if err != nil {
+		return domain.Team{}, fmt.Errorf("failed to upsert team: %w", err)
+	}
+
+	for _, member := range team.Members {
+		var exists bool
+		if err := tx.QueryRowContext(ctx,
+			"SELECT EXISTS (SELECT 1 FROM teams WHERE team_name = ?)", member.TeamName).Scan(&exists); err != nil {
+			return domain.Team{}, fmt.Errorf("error checking team existence: %w", err)
+		}
+		if !exists {
+			return domain.Team{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("Team %s not found for user %s", member.TeamName, member.UserID))
+		}
+
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO users (user_id, username, team_name, is_active)
+			 VALUES (?, ?, ?, ?)
+			 ON CONFLICT (user_id) DO UPDATE
+			 SET username = excluded.username,
+			     team_name = excluded.team_name,
+			     is_active = excluded.is_active`,
+			member.UserID, member.Username, member.TeamName, member.IsActive)
+		if err != nil {
+			return domain.Team{}, fmt.Errorf("failed to upsert user %s: %w", member.UserID, err)
+		}
+	}
+
+	event := domain.Event{Type: domain.EventTeamUpdated, OccurredAt: time.Now().UTC(), Team: &team}
+	if err := insertOutboxEvents(ctx, tx, event); err != nil {
+		return domain.Team{}, err
+	}
+	if err := insertRequestedAudit(ctx, tx, func(entry domain.AuditEntry) domain.AuditEntry {
+		return domain.TeamAudit(entry, before, team)
+	}); err != nil {
+		return domain.Team{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return domain.Team{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return team, nil
+}
+
+func (r *SQLiteRepository) GetTeamByName(ctx context.Context, teamName string) (domain.Team, error) {
+	team, err := queryTeam(ctx, r.db, teamName)
+	if err != nil {
+		return domain.Team{}, err
+	}
+	if team == nil {
+		return domain.Team{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("Team %s not found", teamName))
+	}
+	return *team, nil
+}
+
+// queryTeam returns the team with its members, or nil if it does not exist.
+func queryTeam(ctx context.Context, q querier, teamName string) (*domain.Team, error) {
+	var tName string
+	err := q.QueryRowContext(ctx, "SELECT team_name FROM teams WHERE team_name = ?", teamName).Scan(&tName)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying team: %w", err)
+	}
+
+	rows, err := q.QueryContext(ctx,
+		"SELECT user_id, username, team_name, is_active FROM users WHERE team_name = ? ORDER BY user_id", teamName)
+	if err != nil {
+		return nil, fmt.Errorf("error querying team members: %w", err)
+	}
+	defer rows.Close()
+
+	team := domain.Team{TeamName: tName}
+	for rows.Next() {
+		var member domain.User
+		if err := rows.Scan(&member.UserID, &member.Username, &member.TeamName, &member.IsActive); err != nil {
+			return nil, fmt.Errorf("error scanning team member: %w", err)
+		}
+		team.Members = append(team.Members, member)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating team members: %w", err)
+	}
+
+	return &team, nil
+}
+
+func (r *SQLiteRepository) GetUserByID(ctx context.Context, userID string) (domain.User, error) {
+	var u domain.User
+	err := r.db.QueryRowContext(ctx,
+		"SELECT user_id, username, team_name, is_active FROM users WHERE user_id = ?", userID).
+		Scan(&u.UserID, &u.Username, &u.TeamName, &u.IsActive)
+
+	if err == sql.ErrNoRows {
+		return domain.User{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("User %s not found", userID))
+	}
+	if err != nil {
+		return domain.User{}, fmt.Errorf("error getting user from DB: %w", err)
+	}
+	return u, nil
+}
+
+func (r *SQLiteRepository) SetUserIsActive(ctx context.Context, userID string, isActive bool) (domain.User, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.User{}, err
+	}
+	defer tx.Rollback()
+
+	var u domain.User
+	err = tx.QueryRowContext(ctx,
+		"SELECT user_id, username, team_name, is_active FROM users WHERE user_id = ?", userID).
+		Scan(&u.UserID, &u.Username, &u.TeamName, &u.IsActive)
+	if err == sql.ErrNoRows {
+		return domain.User{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("User %s not found for update", userID))
+	}
+	if err != nil {
+		return domain.User{}, fmt.Errorf("error getting user from DB: %w", err)
+	}
+	if u.IsActive == isActive {
+		return u, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET is_active = ? WHERE user_id = ?", isActive, userID); err != nil {
+		return domain.User{}, fmt.Errorf("error updating user activity: %w", err)
+	}
+	before := u
+	u.IsActive = isActive
+
+	event := domain.Event{Type: domain.EventUserActivityChanged, OccurredAt: time.Now().UTC(), User: &u}
+	if err := insertOutboxEvents(ctx, tx, event); err != nil {
+		return domain.User{}, err
+	}
+	if err := insertRequestedAudit(ctx, tx, func(entry domain.AuditEntry) domain.AuditEntry {
+		return domain.UserAudit(entry, before, u)
+	}); err != nil {
+		return domain.User{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return domain.User{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return u, nil
+}
+
+func (r *SQLiteRepository) CreatePullRequest(ctx context.Context, pr domain.PullRequest) (domain.PullRequest, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.PullRequest{}, err
+	}
+	defer tx.Rollback()
+
+	var prExists, authorExists bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM pull_requests WHERE pr_id = ?),
+		        EXISTS (SELECT 1 FROM users WHERE user_id = ?)`,
+		pr.PullRequestID, pr.AuthorID).Scan(&prExists, &authorExists)
+	if err != nil {
+		return domain.PullRequest{}, fmt.Errorf("error checking PR constraints: %w", err)
+	}
+	if prExists {
+		return domain.PullRequest{}, domain.NewBusinessError(domain.ErrPRExists, fmt.Sprintf("Pull Request with ID %s already exists", pr.PullRequestID))
+	}
+	if !authorExists {
+		return domain.PullRequest{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("Author %s not found", pr.AuthorID))
+	}
+
+	paths, err := json.Marshal(append([]string{}, pr.ChangedPaths...))
+	if err != nil {
+		return domain.PullRequest{}, fmt.Errorf("failed to encode changed paths: %w", err)
+	}
+	labels, err := json.Marshal(append([]string{}, pr.Labels...))
+	if err != nil {
+		return domain.PullRequest{}, fmt.Errorf("failed to encode labels: %w", err)
+	}
+
+	pr.Version = 1
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO pull_requests (pr_id, pr_name, author_id, status, created_at, merged_at, version, changed_paths, labels, code_host_ref)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		pr.PullRequestID, pr.PullRequestName, pr.AuthorID, pr.Status, formatTime(pr.CreatedAt), formatTime(pr.MergedAt), pr.Version,
+		string(paths), string(labels), pr.CodeHostRef)
+	if err != nil {
+		return domain.PullRequest{}, fmt.Errorf("failed to create PR: %w", err)
+	}
+
+	if err := insertReviewers(ctx, tx, pr.PullRequestID, pr.AssignedReviewers); err != nil {
+		return domain.PullRequest{}, err
+	}
+
+	if err := insertOutboxEvents(ctx, tx, domain.PullRequestEvents(nil, pr, time.Now().UTC())...); err != nil {
+		return domain.PullRequest{}, err
+	}
+	if err := insertRequestedAudit(ctx, tx, func(entry domain.AuditEntry) domain.AuditEntry {
+		return domain.PullRequestAudit(entry, nil, pr)
+	}); err != nil {
+		return domain.PullRequest{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return domain.PullRequest{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return pr, nil
+}
+
+const prColumns = "pr_id, pr_name, author_id, status, created_at, merged_at, version, changed_paths, labels, code_host_ref"
+
+func (r *SQLiteRepository) GetPullRequestByID(ctx context.Context, prID string) (domain.PullRequest, error) {
+	pr, err := scanPullRequest(r.db.QueryRowContext(ctx,
+		"SELECT "+prColumns+" FROM pull_requests WHERE pr_id = ?", prID))
+	if err == sql.ErrNoRows {
+		return domain.PullRequest{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("Pull Request %s not found", prID))
+	}
+	if err != nil {
+		return domain.PullRequest{}, fmt.Errorf("error getting PR from DB: %w", err)
+	}
+
+	if pr.AssignedReviewers, err = queryReviewers(ctx, r.db, prID); err != nil {
+		return domain.PullRequest{}, err
+	}
+
+	return pr, nil
+}
+
+func (r *SQLiteRepository) UpdatePullRequest(ctx context.Context, pr domain.PullRequest) (domain.PullRequest, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.PullRequest{}, err
+	}
+	defer tx.Rollback()
+
+	before, err := scanPullRequest(tx.QueryRowContext(ctx, "SELECT "+prColumns+" FROM pull_requests WHERE pr_id = ?", pr.PullRequestID))
+	if err != nil && err != sql.ErrNoRows {
+		return domain.PullRequest{}, fmt.Errorf("error getting PR from DB: %w", err)
+	}
+	if before.AssignedReviewers, err = queryReviewers(ctx, tx, pr.PullRequestID); err != nil {
+		return domain.PullRequest{}, err
+	}
+
+	var newVersion int
+	err = tx.QueryRowContext(ctx,
+		`UPDATE pull_requests
+		 SET pr_name = ?, author_id = ?, status = ?, merged_at = ?, version = version + 1
+		 WHERE pr_id = ? AND version = ?
+		 RETURNING version`,
+		pr.PullRequestName, pr.AuthorID, pr.Status, formatTime(pr.MergedAt), pr.PullRequestID, pr.Version).Scan(&newVersion)
+
+	if err == sql.ErrNoRows {
+		var exists bool
+		if err := tx.QueryRowContext(ctx,
+			"SELECT EXISTS (SELECT 1 FROM pull_requests WHERE pr_id = ?)", pr.PullRequestID).Scan(&exists); err != nil {
+			return domain.PullRequest{}, fmt.Errorf("error checking PR existence: %w", err)
+		}
+		if !exists {
+			return domain.PullRequest{}, domain.NewBusinessError(domain.ErrNotFound, "Pull Request not found for update")
+		}
+		slog.WarnContext(ctx, "pull request version conflict", "pr_id", pr.PullRequestID, "version", pr.Version)
+		return domain.PullRequest{}, domain.NewBusinessError(domain.ErrVersionConflict,
+			fmt.Sprintf("Pull Request %s was modified concurrently", pr.PullRequestID))
+	}
+	if err != nil {
+		return domain.PullRequest{}, fmt.Errorf("error updating PR: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM pr_reviewers WHERE pr_id = ?", pr.PullRequestID); err != nil {
+		return domain.PullRequest{}, fmt.Errorf("error clearing PR reviewers: %w", err)
+	}
+	if err := insertReviewers(ctx, tx, pr.PullRequestID, pr.AssignedReviewers); err != nil {
+		return domain.PullRequest{}, err
+	}
+
+	pr.Version = newVersion
+	if err := insertOutboxEvents(ctx, tx, domain.PullRequestEvents(&before, pr, time.Now().UTC())...); err != nil {
+		return domain.PullRequest{}, err
+	}
+	if err := insertRequestedAudit(ctx, tx, func(entry domain.AuditEntry) domain.AuditEntry {
+		return domain.PullRequestAudit(entry, &before, pr)
+	}); err != nil {
+		return domain.PullRequest{}, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return domain.PullRequest{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return pr, nil
+}
+
+func (r *SQLiteRepository) GetPRsByReviewerID(ctx context.Context, userID string) ([]domain.PullRequestShort, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT DISTINCT p.pr_id, p.pr_name, p.author_id, p.status, p.created_at
+		 FROM pull_requests p
+		 JOIN pr_reviewers rv ON rv.pr_id = p.pr_id
+		 WHERE rv.user_id = ?
+		 ORDER BY p.created_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying PRs by reviewer: %w", err)
+	}
+	defer rows.Close()
+
+	var prs []domain.PullRequestShort
+	for rows.Next() {
+		var pr domain.PullRequestShort
+		var createdAt string
+		if err := rows.Scan(&pr.PullRequestID, &pr.PullRequestName, &pr.AuthorID, &pr.Status, &createdAt); err != nil {
+			return nil, fmt.Errorf("error scanning PR short: %w", err)
+		}
+		prs = append(prs, pr)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating PRs: %w", err)
+	}
+
+	return prs, nil
+}
+
+func (r *SQLiteRepository) ReserveIdempotencyKey(ctx context.Context, rec domain.IdempotencyRecord) (domain.IdempotencyRecord, bool, error) {
+	var key string
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO idempotency_keys (key, request_hash, status_code, headers, body, created_at, expires_at)
+		 VALUES (?, ?, 0, NULL, NULL, ?, ?)
+		 ON CONFLICT (key) DO UPDATE
+		 SET request_hash = excluded.request_hash,
+		     status_code = 0,
+		     headers = NULL,
+		     body = NULL,
+		     created_at = excluded.created_at,
+		     expires_at = excluded.expires_at
+		 WHERE idempotency_keys.expires_at <= excluded.created_at
+		 RETURNING key`,
+		rec.Key, rec.RequestHash, formatTime(&rec.CreatedAt), formatTime(&rec.ExpiresAt)).Scan(&key)
+	if err == nil {
+		rec.StatusCode = 0
+		return rec, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return domain.IdempotencyRecord{}, false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	var stored domain.IdempotencyRecord
+	var headers sql.NullString
+	var createdAt, expiresAt string
+	err = r.db.QueryRowContext(ctx,
+		`SELECT key, request_hash, status_code, headers, body, created_at, expires_at
+		 FROM idempotency_keys
+		 WHERE key = ?`, rec.Key).
+		Scan(&stored.Key, &stored.RequestHash, &stored.StatusCode, &headers, &stored.Body, &createdAt, &expiresAt)
+	if err != nil {
+		return domain.IdempotencyRecord{}, false, fmt.Errorf("error getting idempotency key: %w", err)
+	}
+	if headers.Valid {
+		if err := json.Unmarshal([]byte(headers.String), &stored.Headers); err != nil {
+			return domain.IdempotencyRecord{}, false, fmt.Errorf("error decoding stored headers: %w", err)
+		}
+	}
+	if stored.CreatedAt, err = time.Parse(timeLayout, createdAt); err != nil {
+		return domain.IdempotencyRecord{}, false, fmt.Errorf("error parsing timestamp: %w", err)
+	}
+	if stored.ExpiresAt, err = time.Parse(timeLayout, expiresAt); err != nil {
+		return domain.IdempotencyRecord{}, false, fmt.Errorf("error parsing timestamp: %w", err)
+	}
+
+	return stored, false, nil
+}
+
+func (r *SQLiteRepository) CompleteIdempotencyKey(ctx context.Context, rec domain.IdempotencyRecord) error {
+	headers, err := json.Marshal(rec.Headers)
+	if err != nil {
+		return fmt.Errorf("error encoding headers: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		`UPDATE idempotency_keys SET status_code = ?, headers = ?, body = ?
+		 WHERE key = ? AND request_hash = ?`,
+		rec.StatusCode, string(headers), rec.Body, rec.Key, rec.RequestHash)
+	if err != nil {
+		return fmt.Errorf("error completing idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	_, err := r.db.ExecContext(ctx,
+		"DELETE FROM idempotency_keys WHERE key = ? AND status_code = 0", key)
+	if err != nil {
+		return fmt.Errorf("error releasing idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx,
+		"DELETE FROM idempotency_keys WHERE expires_at <= ?", formatTime(&now))
+	if err != nil {
+		return 0, fmt.Errorf("error deleting expired idempotency keys: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (r *SQLiteRepository) GetReviewStats(ctx context.Context) (domain.ReviewStats, error) {
+	stats := domain.ReviewStats{OpenReviewsByUser: make(map[string]int)}
+
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COALESCE(SUM(p.status = ?), 0),
+		        COALESCE(SUM(p.status = ?), 0),
+		        COALESCE(SUM(p.status = ? AND NOT EXISTS (SELECT 1 FROM pr_reviewers rv WHERE rv.pr_id = p.pr_id)), 0)
+		 FROM pull_requests p`, domain.StatusOpen, domain.StatusMerged, domain.StatusOpen).
+		Scan(&stats.OpenPRs, &stats.MergedPRs, &stats.OpenPRsWithoutReviewers)
+	if err != nil {
+		return domain.ReviewStats{}, fmt.Errorf("error counting PRs: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT rv.user_id, COUNT(*)
+		 FROM pr_reviewers rv
+		 JOIN pull_requests p ON p.pr_id = rv.pr_id
+		 WHERE p.status = ?
+		 GROUP BY rv.user_id`, domain.StatusOpen)
+	if err != nil {
+		return domain.ReviewStats{}, fmt.Errorf("error counting open reviews: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID string
+		var count int
+		if err := rows.Scan(&userID, &count); err != nil {
+			return domain.ReviewStats{}, fmt.Errorf("error scanning open reviews: %w", err)
+		}
+		stats.OpenReviewsByUser[userID] = count
+	}
+	if err := rows.Err(); err != nil {
+		return domain.ReviewStats{}, fmt.Errorf("error iterating open reviews: %w", err)
+	}
+
+	return stats, nil
+}
+
+func (r *SQLiteRepository) ListReviewedPullRequests(ctx context.Context, since time.Time) ([]domain.PullRequest, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT rv.pr_id, rv.user_id
+		 FROM pr_reviewers rv
+		 JOIN pull_requests p ON p.pr_id = rv.pr_id
+		 WHERE p.created_at >= ?
+		 ORDER BY rv.pr_id, rv.position`, formatTime(&since))
+	if err != nil {
+		return nil, fmt.Errorf("error querying reviewers: %w", err)
+	}
+	defer rows.Close()
+
+	reviewers := make(map[string][]string)
+	for rows.Next() {
+		var prID, userID string
+		if err := rows.Scan(&prID, &userID); err != nil {
+			return nil, fmt.Errorf("error scanning reviewer: %w", err)
+		}
+		reviewers[prID] = append(reviewers[prID], userID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating reviewers: %w", err)
+	}
+
+	rows, err = r.db.QueryContext(ctx,
+		"SELECT "+prColumns+" FROM pull_requests WHERE created_at >= ? ORDER BY created_at, pr_id", formatTime(&since))
+	if err != nil {
+		return nil, fmt.Errorf("error querying PRs: %w", err)
+	}
+	defer rows.Close()
+
+	var prs []domain.PullRequest
+	for rows.Next() {
+		pr, err := scanPullRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning PR: %w", err)
+		}
+		if pr.AssignedReviewers = reviewers[pr.PullRequestID]; len(pr.AssignedReviewers) > 0 {
+			prs = append(prs, pr)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating PRs: %w", err)
+	}
+	return prs, nil
+}
+
+// scanPullRequest scans the prColumns of a PR; its reviewers are queried separately.
+func scanPullRequest(row rowScanner) (domain.PullRequest, error) {
+	var pr domain.PullRequest
+	var createdAt, paths, labels string
+	var mergedAt sql.NullString
+	err := row.Scan(&pr.PullRequestID, &pr.PullRequestName, &pr.AuthorID, &pr.Status, &createdAt, &mergedAt, &pr.Version, &paths, &labels, &pr.CodeHostRef)
+	if err != nil {
+		return domain.PullRequest{}, err
+	}
+
+	if pr.CreatedAt, err = parseTime(sql.NullString{String: createdAt, Valid: true}); err != nil {
+		return domain.PullRequest{}, err
+	}
+	if pr.MergedAt, err = parseTime(mergedAt); err != nil {
+		return domain.PullRequest{}, err
+	}
+	if pr.ChangedPaths, err = decodeStrings(paths); err != nil {
+		return domain.PullRequest{}, fmt.Errorf("error decoding changed paths: %w", err)
+	}
+	if pr.Labels, err = decodeStrings(labels); err != nil {
+		return domain.PullRequest{}, fmt.Errorf("error decoding labels: %w", err)
+	}
+	return pr, nil
+}
+
+// decodeStrings decodes a JSON array, returning nil for an empty one.
+func decodeStrings(data string) ([]string, error) {
+	var values []string
+	if err := json.Unmarshal([]byte(data), &values); err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+	return values, nil
+}
+
+func queryReviewers(ctx context.Context, q querier, prID string) ([]string, error) {
+	rows, err := q.QueryContext(ctx,
+		"SELECT user_id FROM pr_reviewers WHERE pr_id = ? ORDER BY position", prID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying PR reviewers: %w", err)
+	}
+	defer rows.Close()
+
+	reviewers := []string{}
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("error scanning PR reviewer: %w", err)
+		}
+		reviewers = append(reviewers, userID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating PR reviewers: %w", err)
+	}
+	return reviewers, nil
+}
+
+func insertReviewers(ctx context.Context, tx *sql.Tx, prID string, reviewers []string) error {
+	for i, userID := range reviewers {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO pr_reviewers (pr_id, position, user_id) VALUES (?, ?, ?)", prID, i, userID); err != nil {
+			return fmt.Errorf("failed to assign reviewer %s: %w", userID, err)
+		}
+	}
+	return nil
+}
+
+func formatTime(t *time.Time) sql.NullString {
+	if t == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: t.UTC().Format(timeLayout), Valid: true}
+}
+
+func parseTime(s sql.NullString) (*time.Time, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	t, err := time.Parse(timeLayout, s.String)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing timestamp %q: %w", s.String, err)
+	}
+	return &t, nil
+}
+
+const apiTokenColumns = "id, name, token_hash, user_id, roles, created_at, expires_at, revoked_at"
+
+func (r *SQLiteRepository) CreateAPIToken(ctx context.Context, token domain.APIToken) (domain.APIToken, error) {
+	roles, err := json.Marshal(token.Roles)
+	if err != nil {
+		return domain.APIToken{}, fmt.Errorf("failed to encode roles: %w", err)
+	}
+	var userID sql.NullString
+	if token.UserID != "" {
+		userID = sql.NullString{String: token.UserID, Valid: true}
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.APIToken{}, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM api_tokens WHERE id = ? OR token_hash = ?)", token.ID, token.TokenHash).Scan(&exists); err != nil {
+		return domain.APIToken{}, fmt.Errorf("error checking API token: %w", err)
+	}
+	if exists {
+		return domain.APIToken{}, domain.NewBusinessError(domain.ErrTokenExists, "API token already exists")
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO api_tokens (`+apiTokenColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, NULL)`,
+		token.ID, token.Name, token.TokenHash, userID, string(roles), formatTime(&token.CreatedAt), formatTime(token.ExpiresAt))
+	if err != nil {
+		return domain.APIToken{}, fmt.Errorf("failed to create API token: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.APIToken{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	token.RevokedAt = nil
+	return token, nil
+}
+
+func (r *SQLiteRepository) GetAPITokenByHash(ctx context.Context, tokenHash string) (domain.APIToken, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+apiTokenColumns+` FROM api_tokens WHERE token_hash = ?`, tokenHash)
+	token, err := scanAPIToken(row)
+	if err == sql.ErrNoRows {
+		return domain.APIToken{}, domain.NewBusinessError(domain.ErrNotFound, "API token not found")
+	}
+	if err != nil {
+		return domain.APIToken{}, fmt.Errorf("error getting API token: %w", err)
+	}
+	return token, nil
+}
+
+func (r *SQLiteRepository) ListAPITokens(ctx context.Context) ([]domain.APIToken, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+apiTokenColumns+` FROM api_tokens ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("error listing API tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []domain.APIToken{}
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning API token: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating API tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+func (r *SQLiteRepository) RevokeAPIToken(ctx context.Context, id string, at time.Time) (domain.APIToken, error) {
+	row := r.db.QueryRowContext(ctx,
+		`UPDATE api_tokens SET revoked_at = COALESCE(revoked_at, ?)
+		 WHERE id = ?
+		 RETURNING `+apiTokenColumns, formatTime(&at), id)
+	token, err := scanAPIToken(row)
+	if err == sql.ErrNoRows {
+		return domain.APIToken{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("API token %s not found", id))
+	}
+	if err != nil {
+		return domain.APIToken{}, fmt.Errorf("error revoking API token: %w", err)
+	}
+	return token, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAPIToken(row rowScanner) (domain.APIToken, error) {
+	var token domain.APIToken
+	var userID, expiresAt, revokedAt sql.NullString
+	var roles, createdAt string
+	if err := row.Scan(&token.ID, &token.Name, &token.TokenHash, &userID, &roles, &createdAt, &expiresAt, &revokedAt); err != nil {
+		return domain.APIToken{}, err
+	}
+	token.UserID = userID.String
+	if err := json.Unmarshal([]byte(roles), &token.Roles); err != nil {
+		return domain.APIToken{}, fmt.Errorf("error decoding roles: %w", err)
+	}
+
+	created, err := parseTime(sql.NullString{String: createdAt, Valid: true})
+	if err != nil {
+		return domain.APIToken{}, err
+	}
+	token.CreatedAt = *created
+	if token.ExpiresAt, err = parseTime(expiresAt); err != nil {
+		return domain.APIToken{}, err
+	}
+	if token.RevokedAt, err = parseTime(revokedAt); err != nil {
+		return domain.APIToken{}, err
+	}
+	return token, nil
+}
+
+const auditColumns = "id, actor, action, target_ids, before_state, after_state, request_id, created_at"
+
+func (r *SQLiteRepository) AppendAuditEntry(ctx context.Context, entry domain.AuditEntry) (domain.AuditEntry, error) {
+	return insertAuditEntry(ctx, r.db, entry)
+}
+
+// insertRequestedAudit appends the entry requested with repository.WithAudit,
+// completed by complete, in tx, i.e. atomically with the change it describes.
+func insertRequestedAudit(ctx context.Context, tx *sql.Tx, complete func(domain.AuditEntry) domain.AuditEntry) error {
+	entry, ok := repository.AuditFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	_, err := insertAuditEntry(ctx, tx, complete(entry))
+	return err
+}
+
+func insertAuditEntry(ctx context.Context, q querier, entry domain.AuditEntry) (domain.AuditEntry, error) {
+	if entry.TargetIDs == nil {
+		entry.TargetIDs = []string{}
+	}
+	targets, err := json.Marshal(entry.TargetIDs)
+	if err != nil {
+		return domain.AuditEntry{}, fmt.Errorf("failed to encode audit targets: %w", err)
+	}
+	err = q.QueryRowContext(ctx,
+		`INSERT INTO audit_log (actor, action, target_ids, before_state, after_state, request_id, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING id`,
+		entry.Actor, entry.Action, string(targets), nullJSON(entry.Before), nullJSON(entry.After),
+		entry.RequestID, formatTime(&entry.CreatedAt)).Scan(&entry.ID)
+	if err != nil {
+		return domain.AuditEntry{}, fmt.Errorf("failed to append audit entry: %w", err)
+	}
+	return entry, nil
+}
+
+func (r *SQLiteRepository) ListAuditEntries(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	query := `SELECT ` + auditColumns + ` FROM audit_log WHERE 1 = 1`
+	var args []any
+	if filter.Actor != "" {
+		query += ` AND actor = ?`
+		args = append(args, filter.Actor)
+	}
+	if filter.Target != "" {
+		query += ` AND EXISTS (SELECT 1 FROM json_each(audit_log.target_ids) WHERE value = ?)`
+		args = append(args, filter.Target)
+	}
+	if filter.From != nil {
+		query += ` AND created_at >= ?`
+		args = append(args, formatTime(filter.From))
+	}
+	if filter.To != nil {
+		query += ` AND created_at < ?`
+		args = append(args, formatTime(filter.To))
+	}
+	query += ` ORDER BY id DESC`
+	if filter.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []domain.AuditEntry{}
+	for rows.Next() {
+		var entry domain.AuditEntry
+		var targets, createdAt string
+		var before, after, requestID sql.NullString
+		if err := rows.Scan(&entry.ID, &entry.Actor, &entry.Action, &targets, &before, &after, &requestID, &createdAt); err != nil {
+			return nil, fmt.Errorf("error scanning audit entry: %w", err)
+		}
+		if err := json.Unmarshal([]byte(targets), &entry.TargetIDs); err != nil {
+			return nil, fmt.Errorf("error decoding audit targets: %w", err)
+		}
+		if before.Valid {
+			entry.Before = json.RawMessage(before.String)
+		}
+		if after.Valid {
+			entry.After = json.RawMessage(after.String)
+		}
+		entry.RequestID = requestID.String
+		created, err := parseTime(sql.NullString{String: createdAt, Valid: true})
+		if err != nil {
+			return nil, err
+		}
+		entry.CreatedAt = *created
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit entries: %w", err)
+	}
+	return entries, nil
+}
+
+func nullJSON(raw json.RawMessage) sql.NullString {
+	if len(raw) == 0 {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: string(raw), Valid: true}
+}
+
+func (r *SQLiteRepository) CreateWebhookSubscription(ctx context.Context, sub domain.WebhookSubscription) (domain.WebhookSubscription, error) {
+	events, err := json.Marshal(sub.Events)
+	if err != nil {
+		return domain.WebhookSubscription{}, fmt.Errorf("failed to encode webhook events: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.WebhookSubscription{}, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM webhook_subscriptions WHERE id = ?)", sub.ID).Scan(&exists); err != nil {
+		return domain.WebhookSubscription{}, fmt.Errorf("error checking webhook subscription: %w", err)
+	}
+	if exists {
+		return domain.WebhookSubscription{}, domain.NewBusinessError(domain.ErrWebhookExists, "Webhook subscription already exists")
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO webhook_subscriptions (id, url, secret, events, created_at) VALUES (?, ?, ?, ?, ?)",
+		sub.ID, sub.URL, sub.Secret, string(events), formatTime(&sub.CreatedAt))
+	if err != nil {
+		return domain.WebhookSubscription{}, fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.WebhookSubscription{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return sub, nil
+}
+
+const webhookSubscriptionColumns = "id, url, secret, events, created_at"
+
+func (r *SQLiteRepository) ListWebhookSubscriptions(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+webhookSubscriptionColumns+` FROM webhook_subscriptions ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("error listing webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []domain.WebhookSubscription{}
+	for rows.Next() {
+		sub, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning webhook subscription: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+func (r *SQLiteRepository) DeleteWebhookSubscription(ctx context.Context, id string) (domain.WebhookSubscription, error) {
+	row := r.db.QueryRowContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = ? RETURNING `+webhookSubscriptionColumns, id)
+	sub, err := scanWebhookSubscription(row)
+	if err == sql.ErrNoRows {
+		return domain.WebhookSubscription{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("Webhook subscription %s not found", id))
+	}
+	if err != nil {
+		return domain.WebhookSubscription{}, fmt.Errorf("error deleting webhook subscription: %w", err)
+	}
+	return sub, nil
+}
+
+func scanWebhookSubscription(row rowScanner) (domain.WebhookSubscription, error) {
+	var sub domain.WebhookSubscription
+	var events, createdAt string
+	if err := row.Scan(&sub.ID, &sub.URL, &sub.Secret, &events, &createdAt); err != nil {
+		return domain.WebhookSubscription{}, err
+	}
+	if err := json.Unmarshal([]byte(events), &sub.Events); err != nil {
+		return domain.WebhookSubscription{}, fmt.Errorf("error decoding webhook events: %w", err)
+	}
+	created, err := parseTime(sql.NullString{String: createdAt, Valid: true})
+	if err != nil {
+		return domain.WebhookSubscription{}, err
+	}
+	sub.CreatedAt = *created
+	return sub, nil
+}
+
+func (r *SQLiteRepository) CreateWebhookDeliveries(ctx context.Context, deliveries []domain.WebhookDelivery) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, d := range deliveries {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO webhook_deliveries (id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at, created_at)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			d.ID, d.SubscriptionID, d.EventID, d.EventType, string(d.Payload), d.Status, d.Attempts,
+			formatTime(&d.NextAttemptAt), formatTime(&d.CreatedAt))
+		if err != nil {
+			return fmt.Errorf("failed to create webhook delivery: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+const webhookDeliverySelect = `SELECT d.id, d.subscription_id, s.url, s.secret, d.event_id, d.event_type, d.payload, d.status,
+	d.attempts, d.next_attempt_at, d.last_error, d.created_at, d.delivered_at
+	FROM webhook_deliveries d JOIN webhook_subscriptions s ON s.id = d.subscription_id`
+
+func (r *SQLiteRepository) ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.WebhookDelivery, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	deliveries, err := queryWebhookDeliveries(ctx, tx, webhookDeliverySelect+`
+		WHERE d.status = ? AND d.next_attempt_at <= ?
+		ORDER BY d.created_at, d.id LIMIT ?`, domain.DeliveryPending, formatTime(&now), limit)
+	if err != nil {
+		return nil, err
+	}
+	leaseUntil := now.Add(lease)
+	for i := range deliveries {
+		if _, err := tx.ExecContext(ctx, "UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id = ?",
+			formatTime(&leaseUntil), deliveries[i].ID); err != nil {
+			return nil, fmt.Errorf("failed to claim webhook delivery: %w", err)
+		}
+		deliveries[i].NextAttemptAt = leaseUntil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (r *SQLiteRepository) UpdateWebhookDelivery(ctx context.Context, d domain.WebhookDelivery) error {
+	var lastError sql.NullString
+	if d.LastError != "" {
+		lastError = sql.NullString{String: d.LastError, Valid: true}
+	}
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE webhook_deliveries SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ?, delivered_at = ?
+		 WHERE id = ?`,
+		d.Status, d.Attempts, formatTime(&d.NextAttemptAt), lastError, formatTime(d.DeliveredAt), d.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("Webhook delivery %s not found", d.ID))
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) ListWebhookDeliveries(ctx context.Context, status string, limit int) ([]domain.WebhookDelivery, error) {
+	return queryWebhookDeliveries(ctx, r.db, webhookDeliverySelect+`
+		WHERE d.status = ? ORDER BY d.created_at DESC, d.id DESC LIMIT ?`, status, limit)
+}
+
+func (r *SQLiteRepository) RequeueWebhookDelivery(ctx context.Context, id string, now time.Time) (domain.WebhookDelivery, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.WebhookDelivery{}, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		"UPDATE webhook_deliveries SET status = ?, attempts = 0, next_attempt_at = ? WHERE id = ? AND status = ?",
+		domain.DeliveryPending, formatTime(&now), id, domain.DeliveryDead)
+	if err != nil {
+		return domain.WebhookDelivery{}, fmt.Errorf("failed to requeue webhook delivery: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return domain.WebhookDelivery{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("Dead webhook delivery %s not found", id))
+	}
+	deliveries, err := queryWebhookDeliveries(ctx, tx, webhookDeliverySelect+` WHERE d.id = ?`, id)
+	if err != nil {
+		return domain.WebhookDelivery{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.WebhookDelivery{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return deliveries[0], nil
+}
+
+func (r *SQLiteRepository) DeleteDeliveredWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx,
+		"DELETE FROM webhook_deliveries WHERE status = ? AND delivered_at < ?", domain.DeliveryDelivered, formatTime(&before))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete delivered webhook deliveries: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func queryWebhookDeliveries(ctx context.Context, q querier, query string, args ...any) ([]domain.WebhookDelivery, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []domain.WebhookDelivery{}
+	for rows.Next() {
+		var d domain.WebhookDelivery
+		var payload, nextAttemptAt, createdAt string
+		var lastError, deliveredAt sql.NullString
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.URL, &d.Secret, &d.EventID, &d.EventType, &payload, &d.Status,
+			&d.Attempts, &nextAttemptAt, &lastError, &createdAt, &deliveredAt); err != nil {
+			return nil, fmt.Errorf("error scanning webhook delivery: %w", err)
+		}
+		d.Payload = json.RawMessage(payload)
+		d.LastError = lastError.String
+		next, err := parseTime(sql.NullString{String: nextAttemptAt, Valid: true})
+		if err != nil {
+			return nil, err
+		}
+		created, err := parseTime(sql.NullString{String: createdAt, Valid: true})
+		if err != nil {
+			return nil, err
+		}
+		d.NextAttemptAt, d.CreatedAt = *next, *created
+		if d.DeliveredAt, err = parseTime(deliveredAt); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// insertOutboxEvents records events in tx, i.e. atomically with the change they describe.
+func insertOutboxEvents(ctx context.Context, tx *sql.Tx, events ...domain.Event) error {
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s event: %w", event.Type, err)
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO outbox (aggregate_id, event_type, payload, next_attempt_at, created_at) VALUES (?, ?, ?, ?, ?)`,
+			event.AggregateID(), event.Type, string(payload), formatTime(&event.OccurredAt), formatTime(&event.OccurredAt))
+		if err != nil {
+			return fmt.Errorf("failed to record %s event: %w", event.Type, err)
+		}
+	}
+	return nil
+}
+
+const outboxColumns = "id, aggregate_id, payload, attempts, next_attempt_at, last_error, published_sinks, published_at"
+
+func (r *SQLiteRepository) ClaimOutboxEvents(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]domain.OutboxEvent, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	events, err := queryOutboxEvents(ctx, tx, `SELECT `+outboxColumns+` FROM outbox o
+		WHERE o.published_at IS NULL AND (
+			SELECT h.next_attempt_at FROM outbox h
+			WHERE h.aggregate_id = o.aggregate_id AND h.published_at IS NULL
+			ORDER BY h.id LIMIT 1
+		) <= ?
+		ORDER BY o.id LIMIT ?`, formatTime(&now), limit)
+	if err != nil {
+		return nil, err
+	}
+	leaseUntil := now.Add(lease)
+	for i := range events {
+		if _, err := tx.ExecContext(ctx, "UPDATE outbox SET next_attempt_at = ? WHERE id = ?",
+			formatTime(&leaseUntil), events[i].Seq); err != nil {
+			return nil, fmt.Errorf("failed to claim outbox event: %w", err)
+		}
+		events[i].NextAttemptAt = leaseUntil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return events, nil
+}
+
+func (r *SQLiteRepository) UpdateOutboxEvent(ctx context.Context, event domain.OutboxEvent) error {
+	var lastError sql.NullString
+	if event.LastError != "" {
+		lastError = sql.NullString{String: event.LastError, Valid: true}
+	}
+	sinks, err := json.Marshal(append([]string{}, event.PublishedSinks...))
+	if err != nil {
+		return fmt.Errorf("failed to encode published sinks: %w", err)
+	}
+	res, err := r.db.ExecContext(ctx,
+		"UPDATE outbox SET attempts = ?, next_attempt_at = ?, last_error = ?, published_sinks = ?, published_at = ? WHERE id = ?",
+		event.Attempts, formatTime(&event.NextAttemptAt), lastError, string(sinks), formatTime(event.PublishedAt), event.Seq)
+	if err != nil {
+		return fmt.Errorf("failed to update outbox event: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("Outbox event %d not found", event.Seq))
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) DeletePublishedOutboxEvents(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx,
+		"DELETE FROM outbox WHERE published_at IS NOT NULL AND published_at < ?", formatTime(&before))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete published outbox events: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+func queryOutboxEvents(ctx context.Context, q querier, query string, args ...any) ([]domain.OutboxEvent, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []domain.OutboxEvent{}
+	for rows.Next() {
+		var e domain.OutboxEvent
+		var payload, nextAttemptAt, sinks string
+		var lastError, publishedAt sql.NullString
+		if err := rows.Scan(&e.Seq, &e.AggregateID, &payload, &e.Attempts, &nextAttemptAt, &lastError, &sinks, &publishedAt); err != nil {
+			return nil, fmt.Errorf("error scanning outbox event: %w", err)
+		}
+		var err error
+		if e.PublishedSinks, err = decodeStrings(sinks); err != nil {
+			return nil, fmt.Errorf("error decoding published sinks of outbox event %d: %w", e.Seq, err)
+		}
+		if err := json.Unmarshal([]byte(payload), &e.Event); err != nil {
+			return nil, fmt.Errorf("error decoding outbox event %d: %w", e.Seq, err)
+		}
+		e.ID = strconv.FormatInt(e.Seq, 10)
+		e.LastError = lastError.String
+		next, err := parseTime(sql.NullString{String: nextAttemptAt, Valid: true})
+		if err != nil {
+			return nil, err
+		}
+		e.NextAttemptAt = *next
+		if e.PublishedAt, err = parseTime(publishedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating outbox events: %w", err)
+	}
+	return events, nil
+}
+
+const userMappingColumns = "provider, login, user_id, created_at"
+
+func (r *SQLiteRepository) SetUserMapping(ctx context.Context, m domain.UserMapping) (domain.UserMapping, error) {
+	row := r.db.QueryRowContext(ctx,
+		`INSERT INTO user_mappings (`+userMappingColumns+`) VALUES (?, ?, ?, ?)
+		 ON CONFLICT (provider, login) DO UPDATE SET user_id = excluded.user_id
+		 RETURNING `+userMappingColumns,
+		m.Provider, m.Login, m.UserID, formatTime(&m.CreatedAt))
+	saved, err := scanUserMapping(row)
+	if err != nil {
+		return domain.UserMapping{}, fmt.Errorf("failed to set user mapping: %w", err)
+	}
+	return saved, nil
+}
+
+func (r *SQLiteRepository) GetUserMapping(ctx context.Context, provider, login string) (domain.UserMapping, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+userMappingColumns+` FROM user_mappings WHERE provider = ? AND login = ?`, provider, login)
+	m, err := scanUserMapping(row)
+	if err == sql.ErrNoRows {
+		return domain.UserMapping{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("No user is mapped to %s login %s", provider, login))
+	}
+	if err != nil {
+		return domain.UserMapping{}, fmt.Errorf("error getting user mapping: %w", err)
+	}
+	return m, nil
+}
+
+func (r *SQLiteRepository) ListUserMappings(ctx context.Context, provider string) ([]domain.UserMapping, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+userMappingColumns+` FROM user_mappings WHERE ? = '' OR provider = ? ORDER BY provider, login`, provider, provider)
+	if err != nil {
+		return nil, fmt.Errorf("error listing user mappings: %w", err)
+	}
+	defer rows.Close()
+
+	mappings := []domain.UserMapping{}
+	for rows.Next() {
+		m, err := scanUserMapping(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning user mapping: %w", err)
+		}
+		mappings = append(mappings, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating user mappings: %w", err)
+	}
+	return mappings, nil
+}
+
+func (r *SQLiteRepository) DeleteUserMapping(ctx context.Context, provider, login string) (domain.UserMapping, error) {
+	row := r.db.QueryRowContext(ctx, `DELETE FROM user_mappings WHERE provider = ? AND login = ? RETURNING `+userMappingColumns, provider, login)
+	m, err := scanUserMapping(row)
+	if err == sql.ErrNoRows {
+		return domain.UserMapping{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("No user is mapped to %s login %s", provider, login))
+	}
+	if err != nil {
+		return domain.UserMapping{}, fmt.Errorf("error deleting user mapping: %w", err)
+	}
+	return m, nil
+}
+
+func scanUserMapping(row rowScanner) (domain.UserMapping, error) {
+	var m domain.UserMapping
+	var createdAt string
+	if err := row.Scan(&m.Provider, &m.Login, &m.UserID, &createdAt); err != nil {
+		return domain.UserMapping{}, err
+	}
+	created, err := parseTime(sql.NullString{String: createdAt, Valid: true})
+	if err != nil {
+		return domain.UserMapping{}, err
+	}
+	m.CreatedAt = *created
+	return m, nil
+}
+
+func (r *SQLiteRepository) SetCodeOwners(ctx context.Context, owners domain.CodeOwners) (domain.CodeOwners, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.CodeOwners{}, err
+	}
+	defer tx.Rollback()
+
+	before, err := queryCodeOwners(ctx, tx, owners.TeamName)
+	if err != nil {
+		return domain.CodeOwners{}, err
+	}
+
+	var updatedAt string
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO team_codeowners (team_name, rules, updated_at)
+		 SELECT team_name, ?, ? FROM teams WHERE team_name = ?
+		 ON CONFLICT (team_name) DO UPDATE SET rules = excluded.rules, updated_at = excluded.updated_at
+		 RETURNING team_name, rules, updated_at`,
+		owners.Rules, formatTime(&owners.UpdatedAt), owners.TeamName).Scan(&owners.TeamName, &owners.Rules, &updatedAt)
+	if err == sql.ErrNoRows {
+		return domain.CodeOwners{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("Team %s not found", owners.TeamName))
+	}
+	if err != nil {
+		return domain.CodeOwners{}, fmt.Errorf("failed to set CODEOWNERS rules: %w", err)
+	}
+	t, err := parseTime(sql.NullString{String: updatedAt, Valid: true})
+	if err != nil {
+		return domain.CodeOwners{}, err
+	}
+	owners.UpdatedAt = *t
+
+	if err := insertRequestedAudit(ctx, tx, func(entry domain.AuditEntry) domain.AuditEntry {
+		return domain.CodeOwnersAudit(entry, before, owners)
+	}); err != nil {
+		return domain.CodeOwners{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return domain.CodeOwners{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return owners, nil
+}
+
+func (r *SQLiteRepository) GetCodeOwners(ctx context.Context, teamName string) (domain.CodeOwners, error) {
+	owners, err := queryCodeOwners(ctx, r.db, teamName)
+	if err != nil {
+		return domain.CodeOwners{}, err
+	}
+	if owners == nil {
+		return domain.CodeOwners{}, domain.NewBusinessError(domain.ErrNotFound, fmt.Sprintf("Team %s has no CODEOWNERS rules", teamName))
+	}
+	return *owners, nil
+}
+
+// queryCodeOwners returns the rules of a team, or nil if it has none.
+func queryCodeOwners(ctx context.Context, q querier, teamName string) (*domain.CodeOwners, error) {
+	var owners domain.CodeOwners
+	var updatedAt string
+	err := q.QueryRowContext(ctx,
+		"SELECT team_name, rules, updated_at FROM team_codeowners WHERE team_name = ?", teamName).
+		Scan(&owners.TeamName, &owners.Rules, &updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting CODEOWNERS rules: %w", err)
+	}
+	t, err := parseTime(sql.NullString{String: updatedAt, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+	owners.UpdatedAt = *t
+	return &owners, nil
+}
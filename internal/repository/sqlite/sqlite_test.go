@@ -0,0 +1,55 @@
+package sqlite_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"Backend/internal/repository"
+	"Backend/internal/repository/repotest"
+	"Backend/internal/repository/sqlite"
+)
+
+var _ repository.TeamRepository = (*sqlite.SQLiteRepository)(nil)
+var _ repository.PullRequestRepository = (*sqlite.SQLiteRepository)(nil)
+var _ repository.IdempotencyRepository = (*sqlite.SQLiteRepository)(nil)
+var _ repository.StatsRepository = (*sqlite.SQLiteRepository)(nil)
+var _ repository.HealthRepository = (*sqlite.SQLiteRepository)(nil)
+var _ repository.TokenRepository = (*sqlite.SQLiteRepository)(nil)
+var _ repository.AuditRepository = (*sqlite.SQLiteRepository)(nil)
+var _ repository.WebhookRepository = (*sqlite.SQLiteRepository)(nil)
+var _ repository.OutboxRepository = (*sqlite.SQLiteRepository)(nil)
+var _ repository.UserMappingRepository = (*sqlite.SQLiteRepository)(nil)
+var _ repository.CodeOwnersRepository = (*sqlite.SQLiteRepository)(nil)
+var _ repository.ReviewHistoryRepository = (*sqlite.SQLiteRepository)(nil)
+
+func TestSQLiteRepository(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repotest.Repositories {
+		db, err := sqlite.Open(filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatalf("Failed to open database: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		repo := sqlite.NewSQLiteRepository(db)
+		if err := repo.Init(context.Background()); err != nil {
+			t.Fatalf("Failed to initialize schema: %v", err)
+		}
+		if err := repo.Init(context.Background()); err != nil {
+			t.Fatalf("Expected repeated Init to be a no-op, got %v", err)
+		}
+		return repotest.Repositories{Teams: repo, PRs: repo, Idempotency: repo, Stats: repo, Health: repo, Tokens: repo, Audit: repo, Webhooks: repo, Outbox: repo, Mappings: repo, CodeOwners: repo, History: repo}
+	})
+}
+
+func TestSQLiteRepository_CheckMigrationsBeforeInit(t *testing.T) {
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := sqlite.NewSQLiteRepository(db).CheckMigrations(context.Background()); err == nil {
+		t.Errorf("Expected CheckMigrations to fail on an empty database")
+	}
+}
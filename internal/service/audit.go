@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"Backend/internal/auth"
+	"Backend/internal/domain"
+	"Backend/internal/logging"
+	"Backend/internal/repository"
+	"Backend/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	DefaultAuditLimit = 100
+	MaxAuditLimit     = 1000
+)
+
+type AuditService interface {
+	ListAuditEntries(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error)
+}
+
+type AuditServiceImpl struct {
+	auditRepo repository.AuditRepository
+}
+
+func NewAuditService(auditRepo repository.AuditRepository) AuditService {
+	return &AuditServiceImpl{auditRepo: auditRepo}
+}
+
+func (s *AuditServiceImpl) ListAuditEntries(ctx context.Context, filter domain.AuditFilter) (_ []domain.AuditEntry, err error) {
+	ctx, span := tracer.Start(ctx, "AuditService.ListAuditEntries", trace.WithAttributes(
+		attribute.String("audit.actor", filter.Actor), attribute.String("audit.target", filter.Target)))
+	defer func() { tracing.End(span, err) }()
+
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultAuditLimit
+	}
+	filter.Limit = min(filter.Limit, MaxAuditLimit)
+	return s.auditRepo.ListAuditEntries(ctx, filter)
+}
+
+// withAudit asks the repositories to record action together with the change
+// it makes, in the same transaction, so that an entry exists exactly for every
+// committed mutation.
+func withAudit(ctx context.Context, action string) context.Context {
+	return repository.WithAudit(ctx, domain.AuditEntry{
+		Actor:     actor(ctx),
+		Action:    action,
+		RequestID: logging.RequestID(ctx),
+		CreatedAt: time.Now().UTC(),
+	})
+}
+
+func actor(ctx context.Context) string {
+	identity, ok := auth.IdentityFromContext(ctx)
+	switch {
+	case !ok:
+		return "anonymous"
+	case identity.UserID != "":
+		return identity.UserID
+	default:
+		return identity.Subject
+	}
+}
+
+type auditedPRService struct {
+	PRService
+}
+
+// NewAuditedPRService records creation, merge, reassignment, closing and
+// reopening of pull requests. Repeated merges, closes and reopens change
+// nothing and are not recorded.
+func NewAuditedPRService(inner PRService) PRService {
+	return &auditedPRService{PRService: inner}
+}
+
+func (s *auditedPRService) CreateAndAssignReviewers(ctx context.Context, prID, prName, authorID string, changedPaths, labels []string, codeHostRef string) (domain.PullRequest, error) {
+	return s.PRService.CreateAndAssignReviewers(withAudit(ctx, domain.AuditPRCreate), prID, prName, authorID, changedPaths, labels, codeHostRef)
+}
+
+func (s *auditedPRService) MergePullRequest(ctx context.Context, prID string, expectedVersion int) (domain.PullRequest, error) {
+	return s.PRService.MergePullRequest(withAudit(ctx, domain.AuditPRMerge), prID, expectedVersion)
+}
+
+func (s *auditedPRService) ReassignReviewer(ctx context.Context, prID, oldUserID string, expectedVersion int) (domain.PullRequest, string, error) {
+	return s.PRService.ReassignReviewer(withAudit(ctx, domain.AuditPRReassign), prID, oldUserID, expectedVersion)
+}
+
+func (s *auditedPRService) ClosePullRequest(ctx context.Context, prID string, expectedVersion int) (domain.PullRequest, error) {
+	return s.PRService.ClosePullRequest(withAudit(ctx, domain.AuditPRClose), prID, expectedVersion)
+}
+
+func (s *auditedPRService) ReopenPullRequest(ctx context.Context, prID string, expectedVersion int) (domain.PullRequest, error) {
+	return s.PRService.ReopenPullRequest(withAudit(ctx, domain.AuditPRReopen), prID, expectedVersion)
+}
+
+type auditedTeamService struct {
+	TeamService
+}
+
+// NewAuditedTeamService records team upserts.
+func NewAuditedTeamService(inner TeamService) TeamService {
+	return &auditedTeamService{TeamService: inner}
+}
+
+func (s *auditedTeamService) CreateOrUpdateTeam(ctx context.Context, team domain.Team) (domain.Team, error) {
+	return s.TeamService.CreateOrUpdateTeam(withAudit(ctx, domain.AuditTeamUpsert), team)
+}
+
+type auditedUserService struct {
+	UserService
+}
+
+// NewAuditedUserService records activity changes.
+func NewAuditedUserService(inner UserService) UserService {
+	return &auditedUserService{UserService: inner}
+}
+
+func (s *auditedUserService) SetUserIsActive(ctx context.Context, userID string, isActive bool) (domain.User, error) {
+	return s.UserService.SetUserIsActive(withAudit(ctx, domain.AuditUserSetActive), userID, isActive)
+}
+
+type auditedCodeOwnersService struct {
+	CodeOwnersService
+}
+
+// NewAuditedCodeOwnersService records uploads of CODEOWNERS rules.
+func NewAuditedCodeOwnersService(inner CodeOwnersService) CodeOwnersService {
+	return &auditedCodeOwnersService{CodeOwnersService: inner}
+}
+
+func (s *auditedCodeOwnersService) SetCodeOwners(ctx context.Context, teamName, rules string) (domain.CodeOwners, error) {
+	return s.CodeOwnersService.SetCodeOwners(withAudit(ctx, domain.AuditCodeOwnersSet), teamName, rules)
+}
@@ -0,0 +1,103 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"Backend/internal/auth"
+	"Backend/internal/domain"
+	"Backend/internal/logging"
+	"Backend/internal/repository/memory"
+	"Backend/internal/service"
+)
+
+func TestAuditedServices_RecordMutations(t *testing.T) {
+	repo := memory.NewMemoryRepository()
+	teams := service.NewAuditedTeamService(service.NewTeamService(repo))
+	users := service.NewAuditedUserService(service.NewUserService(repo, repo))
+	prs := service.NewAuditedPRService(service.NewPRServiceWithOptions(repo, repo, service.PRServiceOptions{ReviewerCount: 1}))
+	audit := service.NewAuditService(repo)
+
+	lead := auth.WithIdentity(context.Background(), domain.Identity{Subject: "jwt:lead", UserID: "lead", Roles: []domain.Role{domain.RoleTeamLead}})
+	ctx := logging.WithRequestID(lead, "req-1")
+
+	_, err := teams.CreateOrUpdateTeam(ctx, domain.Team{TeamName: "backend", Members: []domain.User{
+		{UserID: "alice", Username: "Alice", TeamName: "backend", IsActive: true},
+		{UserID: "bob", Username: "Bob", TeamName: "backend", IsActive: true},
+		{UserID: "carol", Username: "Carol", TeamName: "backend", IsActive: true},
+	}})
+	if err != nil {
+		t.Fatalf("CreateOrUpdateTeam failed: %v", err)
+	}
+	if _, err := prs.CreateAndAssignReviewers(ctx, "pr-42", "Fix", "alice", nil, nil, ""); err != nil {
+		t.Fatalf("CreateAndAssignReviewers failed: %v", err)
+	}
+	if _, err := users.SetUserIsActive(ctx, "bob", false); err != nil {
+		t.Fatalf("SetUserIsActive failed: %v", err)
+	}
+	if _, err := prs.MergePullRequest(ctx, "pr-42", domain.AnyVersion); err != nil {
+		t.Fatalf("MergePullRequest failed: %v", err)
+	}
+	if _, err := prs.MergePullRequest(ctx, "pr-42", domain.AnyVersion); err != nil {
+		t.Fatalf("Repeated MergePullRequest failed: %v", err)
+	}
+	if _, err := users.SetUserIsActive(context.Background(), "missing", true); errorCode(err) != domain.ErrNotFound {
+		t.Fatalf("Expected NOT_FOUND, got %v", err)
+	}
+
+	entries, err := audit.ListAuditEntries(context.Background(), domain.AuditFilter{})
+	if err != nil {
+		t.Fatalf("ListAuditEntries failed: %v", err)
+	}
+	var actions []string
+	for _, entry := range entries {
+		actions = append(actions, entry.Action)
+		if entry.Actor != "lead" || entry.RequestID != "req-1" {
+			t.Errorf("Expected actor and request id from the context, got %+v", entry)
+		}
+	}
+	want := []string{domain.AuditPRMerge, domain.AuditUserSetActive, domain.AuditPRCreate, domain.AuditTeamUpsert}
+	if !slices.Equal(actions, want) {
+		t.Fatalf("Expected actions %v, got %v", want, actions)
+	}
+
+	bob, err := audit.ListAuditEntries(context.Background(), domain.AuditFilter{Target: "bob"})
+	if err != nil {
+		t.Fatalf("ListAuditEntries failed: %v", err)
+	}
+	var deactivation *domain.AuditEntry
+	for i := range bob {
+		if bob[i].Action == domain.AuditUserSetActive {
+			deactivation = &bob[i]
+		}
+	}
+	if deactivation == nil {
+		t.Fatalf("Expected bob's deactivation to be found by target, got %+v", bob)
+	}
+	var before, after domain.User
+	if err := json.Unmarshal(deactivation.Before, &before); err != nil || !before.IsActive {
+		t.Errorf("Expected an active user before, got %s (%v)", deactivation.Before, err)
+	}
+	if err := json.Unmarshal(deactivation.After, &after); err != nil || after.IsActive {
+		t.Errorf("Expected an inactive user after, got %s (%v)", deactivation.After, err)
+	}
+
+	if entries[3].Before != nil {
+		t.Errorf("Expected no before snapshot for a new team, got %s", entries[3].Before)
+	}
+}
+
+func TestAuditedServices_AnonymousActor(t *testing.T) {
+	repo := memory.NewMemoryRepository()
+	teams := service.NewAuditedTeamService(service.NewTeamService(repo))
+
+	if _, err := teams.CreateOrUpdateTeam(context.Background(), domain.Team{TeamName: "backend"}); err != nil {
+		t.Fatalf("CreateOrUpdateTeam failed: %v", err)
+	}
+	entries, err := service.NewAuditService(repo).ListAuditEntries(context.Background(), domain.AuditFilter{Actor: "anonymous"})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Expected one anonymous entry, got %v, %v", entries, err)
+	}
+}
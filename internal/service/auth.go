@@ -0,0 +1,167 @@
+package service
+
+import (
+	"Backend/internal/auth"
+	"Backend/internal/domain"
+	"Backend/internal/repository"
+	"Backend/internal/tracing"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type AuthService interface {
+	// Authenticate resolves an API token to the identity it was issued for.
+	Authenticate(ctx context.Context, token string) (domain.Identity, error)
+	// IssueToken creates a token and returns it together with its secret, which is not stored.
+	IssueToken(ctx context.Context, name, userID string, roles []domain.Role, expiresAt *time.Time) (domain.APIToken, string, error)
+	ListTokens(ctx context.Context) ([]domain.APIToken, error)
+	RevokeToken(ctx context.Context, id string) (domain.APIToken, error)
+	// EnsureToken registers a token with a known secret unless it already exists.
+	EnsureToken(ctx context.Context, name, secret string, roles []domain.Role) error
+}
+
+type AuthServiceImpl struct {
+	tokenRepo repository.TokenRepository
+	teamRepo  repository.TeamRepository
+}
+
+func NewAuthService(tokenRepo repository.TokenRepository, teamRepo repository.TeamRepository) AuthService {
+	return &AuthServiceImpl{tokenRepo: tokenRepo, teamRepo: teamRepo}
+}
+
+var errInvalidToken = domain.NewBusinessError(domain.ErrUnauthorized, "Invalid or expired API token")
+
+func (s *AuthServiceImpl) Authenticate(ctx context.Context, token string) (_ domain.Identity, err error) {
+	ctx, span := tracer.Start(ctx, "AuthService.Authenticate")
+	defer func() { tracing.End(span, err) }()
+
+	stored, err := s.tokenRepo.GetAPITokenByHash(ctx, auth.HashToken(token))
+	var bErr *domain.BusinessError
+	if errors.As(err, &bErr) && bErr.Code == domain.ErrNotFound {
+		return domain.Identity{}, errInvalidToken
+	}
+	if err != nil {
+		return domain.Identity{}, err
+	}
+
+	now := time.Now().UTC()
+	if stored.RevokedAt != nil || (stored.ExpiresAt != nil && !now.Before(*stored.ExpiresAt)) {
+		return domain.Identity{}, errInvalidToken
+	}
+
+	span.SetAttributes(attribute.String("auth.token_id", stored.ID))
+	return domain.Identity{Subject: "token:" + stored.ID, UserID: stored.UserID, Roles: stored.Roles}, nil
+}
+
+func (s *AuthServiceImpl) IssueToken(ctx context.Context, name, userID string, roles []domain.Role, expiresAt *time.Time) (_ domain.APIToken, _ string, err error) {
+	ctx, span := tracer.Start(ctx, "AuthService.IssueToken", trace.WithAttributes(attribute.String("user.id", userID)))
+	defer func() { tracing.End(span, err) }()
+
+	now := time.Now().UTC()
+	if err := checkTokenRequest(userID, roles, expiresAt, now); err != nil {
+		return domain.APIToken{}, "", err
+	}
+	if userID != "" {
+		if _, err := s.teamRepo.GetUserByID(ctx, userID); err != nil {
+			return domain.APIToken{}, "", err
+		}
+	}
+
+	secret := auth.GenerateToken()
+	token := domain.APIToken{
+		ID:        newTokenID(),
+		Name:      name,
+		TokenHash: auth.HashToken(secret),
+		UserID:    userID,
+		Roles:     roles,
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+	}
+	created, err := s.tokenRepo.CreateAPIToken(ctx, token)
+	if err != nil {
+		return domain.APIToken{}, "", err
+	}
+
+	slog.InfoContext(ctx, "api token issued", "token_id", created.ID, "user_id", userID, "roles", roles)
+	return created, secret, nil
+}
+
+func (s *AuthServiceImpl) ListTokens(ctx context.Context) (_ []domain.APIToken, err error) {
+	ctx, span := tracer.Start(ctx, "AuthService.ListTokens")
+	defer func() { tracing.End(span, err) }()
+
+	return s.tokenRepo.ListAPITokens(ctx)
+}
+
+func (s *AuthServiceImpl) RevokeToken(ctx context.Context, id string) (_ domain.APIToken, err error) {
+	ctx, span := tracer.Start(ctx, "AuthService.RevokeToken", trace.WithAttributes(attribute.String("auth.token_id", id)))
+	defer func() { tracing.End(span, err) }()
+
+	token, err := s.tokenRepo.RevokeAPIToken(ctx, id, time.Now().UTC())
+	if err != nil {
+		return domain.APIToken{}, err
+	}
+
+	slog.InfoContext(ctx, "api token revoked", "token_id", id)
+	return token, nil
+}
+
+func (s *AuthServiceImpl) EnsureToken(ctx context.Context, name, secret string, roles []domain.Role) error {
+	_, err := s.tokenRepo.GetAPITokenByHash(ctx, auth.HashToken(secret))
+	if err == nil {
+		return nil
+	}
+	var bErr *domain.BusinessError
+	if !errors.As(err, &bErr) || bErr.Code != domain.ErrNotFound {
+		return err
+	}
+
+	_, err = s.tokenRepo.CreateAPIToken(ctx, domain.APIToken{
+		ID:        newTokenID(),
+		Name:      name,
+		TokenHash: auth.HashToken(secret),
+		Roles:     roles,
+		CreatedAt: time.Now().UTC(),
+	})
+	if errors.As(err, &bErr) && bErr.Code == domain.ErrTokenExists {
+		return nil
+	}
+	return err
+}
+
+func checkTokenRequest(userID string, roles []domain.Role, expiresAt *time.Time, now time.Time) error {
+	var details []domain.FieldError
+	if len(roles) == 0 {
+		details = append(details, domain.FieldError{Field: "roles", Message: "is required"})
+	}
+	for i, role := range roles {
+		if !slices.Contains(domain.Roles, role) {
+			details = append(details, domain.FieldError{Field: fmt.Sprintf("roles[%d]", i), Message: fmt.Sprintf("unknown role %q", role)})
+		}
+	}
+	if userID == "" && (slices.Contains(roles, domain.RoleMember) || slices.Contains(roles, domain.RoleTeamLead)) {
+		details = append(details, domain.FieldError{Field: "user_id", Message: "is required for member and team-lead tokens"})
+	}
+	if expiresAt != nil && !expiresAt.After(now) {
+		details = append(details, domain.FieldError{Field: "expires_at", Message: "must be in the future"})
+	}
+	if len(details) > 0 {
+		return domain.NewValidationError(details...)
+	}
+	return nil
+}
+
+func newTokenID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "tok_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,115 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"Backend/internal/domain"
+	"Backend/internal/repository/memory"
+	"Backend/internal/service"
+)
+
+func newAuthService(t *testing.T) service.AuthService {
+	t.Helper()
+	repo := memory.NewMemoryRepository()
+	_, err := repo.CreateOrUpdateTeam(context.Background(), domain.Team{
+		TeamName: "backend",
+		Members:  []domain.User{{UserID: "u1", Username: "Alice", TeamName: "backend", IsActive: true}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to seed team: %v", err)
+	}
+	return service.NewAuthService(repo, repo)
+}
+
+func errorCode(err error) domain.ErrorCode {
+	var bErr *domain.BusinessError
+	if errors.As(err, &bErr) {
+		return bErr.Code
+	}
+	return ""
+}
+
+func TestAuthService_IssueAuthenticateRevoke(t *testing.T) {
+	ctx := context.Background()
+	s := newAuthService(t)
+
+	token, secret, err := s.IssueToken(ctx, "alice laptop", "u1", []domain.Role{domain.RoleMember}, nil)
+	if err != nil {
+		t.Fatalf("IssueToken failed: %v", err)
+	}
+	if token.TokenHash == secret || token.TokenHash == "" {
+		t.Errorf("Expected only the hash of the secret to be stored")
+	}
+
+	identity, err := s.Authenticate(ctx, secret)
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if identity.UserID != "u1" || !identity.HasAnyRole(domain.RoleMember) || identity.HasAnyRole(domain.RoleAdmin) {
+		t.Errorf("Unexpected identity %+v", identity)
+	}
+
+	if _, err := s.Authenticate(ctx, secret+"x"); errorCode(err) != domain.ErrUnauthorized {
+		t.Errorf("Expected UNAUTHORIZED for an unknown token, got %v", err)
+	}
+
+	if _, err := s.RevokeToken(ctx, token.ID); err != nil {
+		t.Fatalf("RevokeToken failed: %v", err)
+	}
+	if _, err := s.Authenticate(ctx, secret); errorCode(err) != domain.ErrUnauthorized {
+		t.Errorf("Expected UNAUTHORIZED for a revoked token, got %v", err)
+	}
+}
+
+func TestAuthService_IssueTokenValidation(t *testing.T) {
+	ctx := context.Background()
+	s := newAuthService(t)
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name      string
+		userID    string
+		roles     []domain.Role
+		expiresAt *time.Time
+		want      domain.ErrorCode
+	}{
+		{"unknown role", "", []domain.Role{"root"}, nil, domain.ErrValidationFailed},
+		{"member without user", "", []domain.Role{domain.RoleMember}, nil, domain.ErrValidationFailed},
+		{"expired", "", []domain.Role{domain.RoleBot}, &past, domain.ErrValidationFailed},
+		{"unknown user", "u404", []domain.Role{domain.RoleMember}, nil, domain.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := s.IssueToken(ctx, "token", tt.userID, tt.roles, tt.expiresAt)
+			if got := errorCode(err); got != tt.want {
+				t.Errorf("Expected %s, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestAuthService_EnsureTokenIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	s := newAuthService(t)
+	secret := "prr_bootstrap-secret-for-tests-only"
+
+	for i := 0; i < 2; i++ {
+		if err := s.EnsureToken(ctx, "bootstrap", secret, []domain.Role{domain.RoleAdmin}); err != nil {
+			t.Fatalf("EnsureToken failed: %v", err)
+		}
+	}
+	tokens, err := s.ListTokens(ctx)
+	if err != nil {
+		t.Fatalf("ListTokens failed: %v", err)
+	}
+	if len(tokens) != 1 {
+		t.Fatalf("Expected a single bootstrap token, got %d", len(tokens))
+	}
+	identity, err := s.Authenticate(ctx, secret)
+	if err != nil || !identity.HasAnyRole(domain.RoleAdmin) {
+		t.Errorf("Expected bootstrap token to authenticate as admin, got %+v %v", identity, err)
+	}
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"Backend/internal/codeowners"
+	"Backend/internal/domain"
+	"Backend/internal/repository"
+	"Backend/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type CodeOwnersService interface {
+	// SetCodeOwners replaces the rules of a team. Every owner must be an
+	// existing user id or @team.
+	SetCodeOwners(ctx context.Context, teamName, rules string) (domain.CodeOwners, error)
+	GetCodeOwners(ctx context.Context, teamName string) (domain.CodeOwners, error)
+}
+
+type CodeOwnersServiceImpl struct {
+	ownersRepo repository.CodeOwnersRepository
+	teamRepo   repository.TeamRepository
+}
+
+func NewCodeOwnersService(ownersRepo repository.CodeOwnersRepository, teamRepo repository.TeamRepository) CodeOwnersService {
+	return &CodeOwnersServiceImpl{ownersRepo: ownersRepo, teamRepo: teamRepo}
+}
+
+func (s *CodeOwnersServiceImpl) SetCodeOwners(ctx context.Context, teamName, rules string) (_ domain.CodeOwners, err error) {
+	ctx, span := tracer.Start(ctx, "CodeOwnersService.SetCodeOwners", trace.WithAttributes(attribute.String("team.name", teamName)))
+	defer func() { tracing.End(span, err) }()
+
+	parsed, err := codeowners.Parse(rules)
+	if err != nil {
+		return domain.CodeOwners{}, domain.NewValidationError(domain.FieldError{Field: "rules", Message: err.Error()})
+	}
+	if err := s.checkOwners(ctx, parsed); err != nil {
+		return domain.CodeOwners{}, err
+	}
+
+	saved, err := s.ownersRepo.SetCodeOwners(ctx, domain.CodeOwners{TeamName: teamName, Rules: rules, UpdatedAt: time.Now().UTC()})
+	if err != nil {
+		return domain.CodeOwners{}, err
+	}
+
+	slog.InfoContext(ctx, "codeowners saved", "team_name", teamName, "rules", len(parsed))
+	return saved, nil
+}
+
+// checkOwners rejects rules naming unknown users or teams, which are most
+// likely typos.
+func (s *CodeOwnersServiceImpl) checkOwners(ctx context.Context, rules codeowners.Ruleset) error {
+	checked := make(map[string]bool)
+	for _, rule := range rules {
+		for _, owner := range rule.Owners {
+			if checked[owner] {
+				continue
+			}
+			checked[owner] = true
+
+			var err error
+			if name, ok := strings.CutPrefix(owner, "@"); ok {
+				_, err = s.teamRepo.GetTeamByName(ctx, name)
+			} else {
+				_, err = s.teamRepo.GetUserByID(ctx, owner)
+			}
+			var bErr *domain.BusinessError
+			if errors.As(err, &bErr) && bErr.Code == domain.ErrNotFound {
+				return domain.NewValidationError(domain.FieldError{
+					Field: "rules", Message: fmt.Sprintf("line %d: unknown owner %s", rule.Line, owner)})
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *CodeOwnersServiceImpl) GetCodeOwners(ctx context.Context, teamName string) (_ domain.CodeOwners, err error) {
+	ctx, span := tracer.Start(ctx, "CodeOwnersService.GetCodeOwners", trace.WithAttributes(attribute.String("team.name", teamName)))
+	defer func() { tracing.End(span, err) }()
+
+	return s.ownersRepo.GetCodeOwners(ctx, teamName)
+}
@@ -0,0 +1,163 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"Backend/internal/domain"
+	"Backend/internal/repository/memory"
+	"Backend/internal/service"
+)
+
+// newCodeOwnersFixture seeds team backend (alice, bob, carol, dave; dave is
+// inactive) and team frontend (erin), with rules giving backend's Go code to
+// bob and dave and its web code to the frontend team.
+func newCodeOwnersFixture(t *testing.T) (*memory.MemoryRepository, service.PRService) {
+	t.Helper()
+	ctx := context.Background()
+	repo := memory.NewMemoryRepository()
+	teams := map[string][]domain.User{
+		"backend": {
+			{UserID: "alice", Username: "Alice", IsActive: true},
+			{UserID: "bob", Username: "Bob", IsActive: true},
+			{UserID: "carol", Username: "Carol", IsActive: true},
+			{UserID: "dave", Username: "Dave", IsActive: false},
+		},
+		"frontend": {{UserID: "erin", Username: "Erin", IsActive: true}},
+	}
+	for name, members := range teams {
+		for i := range members {
+			members[i].TeamName = name
+		}
+		if _, err := repo.CreateOrUpdateTeam(ctx, domain.Team{TeamName: name, Members: members}); err != nil {
+			t.Fatalf("CreateOrUpdateTeam failed: %v", err)
+		}
+	}
+
+	owners := service.NewCodeOwnersService(repo, repo)
+	if _, err := owners.SetCodeOwners(ctx, "backend", "*.go bob dave\nweb/ @frontend\n"); err != nil {
+		t.Fatalf("SetCodeOwners failed: %v", err)
+	}
+	return repo, service.NewPRServiceWithOptions(repo, repo, service.PRServiceOptions{ReviewerCount: 1, CodeOwners: repo})
+}
+
+func TestCreateAndAssignReviewers_PrefersActiveOwners(t *testing.T) {
+	_, prs := newCodeOwnersFixture(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		id        string
+		paths     []string
+		reviewers []string
+	}{
+		{"pr-go", []string{"./internal/service/service.go"}, []string{"bob"}},
+		{"pr-web", []string{"README.md", "web/src/app.ts"}, []string{"erin"}},
+		// Nobody owns the docs, so the reviewer comes from the author's team.
+		{"pr-docs", []string{"docs/setup.md"}, []string{"bob", "carol"}},
+	}
+	for _, tt := range tests {
+		// Repeat to rule out a lucky random pick.
+		for i := range 10 {
+			pr, err := prs.CreateAndAssignReviewers(ctx, tt.id+string(rune('a'+i)), "Change", "alice", tt.paths, nil, "")
+			if err != nil {
+				t.Fatalf("%s: CreateAndAssignReviewers failed: %v", tt.id, err)
+			}
+			if len(pr.AssignedReviewers) != 1 || !slices.Contains(tt.reviewers, pr.AssignedReviewers[0]) {
+				t.Fatalf("%s: expected one of %v, got %v", tt.id, tt.reviewers, pr.AssignedReviewers)
+			}
+		}
+	}
+
+	pr, err := prs.GetPullRequest(ctx, "pr-weba")
+	if err != nil || !slices.Equal(pr.ChangedPaths, []string{"README.md", "web/src/app.ts"}) {
+		t.Errorf("Expected the changed paths to be stored, got %v, %v", pr.ChangedPaths, err)
+	}
+}
+
+func TestCreateAndAssignReviewers_FillsUpFromTeam(t *testing.T) {
+	repo, _ := newCodeOwnersFixture(t)
+	prs := service.NewPRServiceWithOptions(repo, repo, service.PRServiceOptions{ReviewerCount: 2, CodeOwners: repo})
+
+	pr, err := prs.CreateAndAssignReviewers(context.Background(), "pr-1", "Change", "alice", []string{"main.go"}, nil, "")
+	if err != nil {
+		t.Fatalf("CreateAndAssignReviewers failed: %v", err)
+	}
+	slices.Sort(pr.AssignedReviewers)
+	if !slices.Equal(pr.AssignedReviewers, []string{"bob", "carol"}) {
+		t.Errorf("Expected the owner bob and teammate carol, got %v", pr.AssignedReviewers)
+	}
+}
+
+// countingTeamRepo counts user lookups by id.
+type countingTeamRepo struct {
+	*memory.MemoryRepository
+	lookups map[string]int
+}
+
+func (r *countingTeamRepo) GetUserByID(ctx context.Context, userID string) (domain.User, error) {
+	r.lookups[userID]++
+	return r.MemoryRepository.GetUserByID(ctx, userID)
+}
+
+func TestCreateAndAssignReviewers_LooksUpEachOwnerOnce(t *testing.T) {
+	repo, _ := newCodeOwnersFixture(t)
+	teams := &countingTeamRepo{MemoryRepository: repo, lookups: map[string]int{}}
+	prs := service.NewPRServiceWithOptions(repo, teams, service.PRServiceOptions{ReviewerCount: 1, CodeOwners: repo})
+
+	paths := []string{"a.go", "b.go", "c/d.go", "e/f/g.go"}
+	if _, err := prs.CreateAndAssignReviewers(context.Background(), "pr-1", "Change", "alice", paths, nil, ""); err != nil {
+		t.Fatalf("CreateAndAssignReviewers failed: %v", err)
+	}
+	if teams.lookups["bob"] != 1 || teams.lookups["dave"] != 1 {
+		t.Errorf("Expected bob and dave to be looked up once, got %v", teams.lookups)
+	}
+}
+
+func TestCreateAndAssignReviewers_RejectsBlankPaths(t *testing.T) {
+	_, prs := newCodeOwnersFixture(t)
+
+	_, err := prs.CreateAndAssignReviewers(context.Background(), "pr-1", "Change", "alice", []string{"main.go", " "}, nil, "")
+	var bErr *domain.BusinessError
+	if !errors.As(err, &bErr) || bErr.Code != domain.ErrValidationFailed || bErr.Details[0].Field != "changed_paths[1]" {
+		t.Errorf("Expected a validation error on changed_paths[1], got %v", err)
+	}
+}
+
+func TestReassignReviewer_PrefersOwners(t *testing.T) {
+	repo, prs := newCodeOwnersFixture(t)
+	ctx := context.Background()
+	if _, err := repo.CreateOrUpdateTeam(ctx, domain.Team{TeamName: "frontend", Members: []domain.User{
+		{UserID: "erin", Username: "Erin", TeamName: "frontend", IsActive: true},
+		{UserID: "frank", Username: "Frank", TeamName: "frontend", IsActive: true},
+	}}); err != nil {
+		t.Fatalf("CreateOrUpdateTeam failed: %v", err)
+	}
+
+	pr, err := prs.CreateAndAssignReviewers(ctx, "pr-1", "Change", "alice", []string{"web/index.html"}, nil, "")
+	if err != nil {
+		t.Fatalf("CreateAndAssignReviewers failed: %v", err)
+	}
+	old := pr.AssignedReviewers[0]
+	_, newID, err := prs.ReassignReviewer(ctx, "pr-1", old, domain.AnyVersion)
+	if err != nil {
+		t.Fatalf("ReassignReviewer failed: %v", err)
+	}
+	if want := map[string]string{"erin": "frank", "frank": "erin"}[old]; newID != want {
+		t.Errorf("Expected %s to be replaced by the other owner, got %s", old, newID)
+	}
+}
+
+func TestSetCodeOwners_RejectsUnknownOwners(t *testing.T) {
+	repo, _ := newCodeOwnersFixture(t)
+	owners := service.NewCodeOwnersService(repo, repo)
+
+	for _, rules := range []string{"*.go bob mallory", "docs/ @docs", "[a].go bob"} {
+		_, err := owners.SetCodeOwners(context.Background(), "backend", rules)
+		var bErr *domain.BusinessError
+		if !errors.As(err, &bErr) || bErr.Code != domain.ErrValidationFailed || bErr.Details[0].Field != "rules" {
+			t.Errorf("%q: expected a validation error on rules, got %v", rules, err)
+		}
+	}
+}
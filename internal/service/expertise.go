@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"maps"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"Backend/internal/domain"
+	"Backend/internal/repository"
+	"Backend/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	DefaultExpertiseWindow   = 180 * 24 * time.Hour
+	DefaultExpertiseHalfLife = 30 * 24 * time.Hour
+	DefaultExpertiseRefresh  = 5 * time.Minute
+
+	// expertiseDepth is the number of directory levels of a changed path that
+	// are areas: internal/api/router.go is in internal/ and internal/api/.
+	expertiseDepth  = 2
+	labelAreaPrefix = "label:"
+)
+
+type ExpertiseOptions struct {
+	// Window bounds the age of the PRs expertise is learnt from.
+	Window time.Duration
+	// HalfLife is the age at which a review counts half as much as a new one.
+	HalfLife time.Duration
+	// Refresh is how long reviewer selection reuses the scores and open
+	// review counts before reading them again.
+	Refresh time.Duration
+}
+
+// pullRequestAreas returns the areas a PR touches: its labels, lowercased, and
+// the leading directories of its changed paths.
+func pullRequestAreas(pr domain.PullRequest) []string {
+	seen := make(map[string]bool)
+	var areas []string
+	add := func(area string) {
+		if !seen[area] {
+			seen[area] = true
+			areas = append(areas, area)
+		}
+	}
+	for _, label := range pr.Labels {
+		add(labelAreaPrefix + strings.ToLower(label))
+	}
+	for _, path := range pr.ChangedPaths {
+		dirs := strings.Split(path, "/")
+		dirs = dirs[:len(dirs)-1]
+		for depth := 1; depth <= min(len(dirs), expertiseDepth); depth++ {
+			add(strings.Join(dirs[:depth], "/") + "/")
+		}
+	}
+	return areas
+}
+
+// expertiseModel scores users by the PRs they were assigned to review.
+type expertiseModel struct {
+	history repository.ReviewHistoryRepository
+	opts    ExpertiseOptions
+
+	// mu guards the snapshot ranker uses, taken at loadedAt. Assignments made
+	// since then are added to load so that a burst of PRs is still spread.
+	mu       sync.Mutex
+	cached   map[string]map[string]*domain.ExpertiseScore
+	load     map[string]int
+	loadedAt time.Time
+}
+
+func newExpertiseModel(history repository.ReviewHistoryRepository, opts ExpertiseOptions) *expertiseModel {
+	if opts.Window <= 0 {
+		opts.Window = DefaultExpertiseWindow
+	}
+	if opts.HalfLife <= 0 {
+		opts.HalfLife = DefaultExpertiseHalfLife
+	}
+	if opts.Refresh <= 0 {
+		opts.Refresh = DefaultExpertiseRefresh
+	}
+	return &expertiseModel{history: history, opts: opts}
+}
+
+// scores returns the scores at now by user and area. Only finished reviews,
+// of merged or closed PRs, count: open assignments are load, not expertise.
+func (m *expertiseModel) scores(ctx context.Context, now time.Time) (map[string]map[string]*domain.ExpertiseScore, error) {
+	prs, err := m.history.ListReviewedPullRequests(ctx, now.Add(-m.opts.Window))
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make(map[string]map[string]*domain.ExpertiseScore)
+	for _, pr := range prs {
+		areas := pullRequestAreas(pr)
+		if pr.Status == domain.StatusOpen || len(areas) == 0 {
+			continue
+		}
+		weight := 1.0
+		if pr.CreatedAt != nil {
+			weight = math.Exp2(-max(now.Sub(*pr.CreatedAt), 0).Hours() / m.opts.HalfLife.Hours())
+		}
+		for _, userID := range pr.AssignedReviewers {
+			byArea := scores[userID]
+			if byArea == nil {
+				byArea = make(map[string]*domain.ExpertiseScore)
+				scores[userID] = byArea
+			}
+			for _, area := range areas {
+				score := byArea[area]
+				if score == nil {
+					score = &domain.ExpertiseScore{UserID: userID, Area: area}
+					byArea[area] = score
+				}
+				score.Score += weight
+				score.Reviews++
+			}
+		}
+	}
+	return scores, nil
+}
+
+// snapshot returns the cached scores and a copy of the open review counts,
+// reading both again once they are older than opts.Refresh.
+func (m *expertiseModel) snapshot(ctx context.Context, stats repository.StatsRepository, now time.Time) (map[string]map[string]*domain.ExpertiseScore, map[string]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.cached == nil || now.Sub(m.loadedAt) >= m.opts.Refresh {
+		scores, err := m.scores(ctx, now)
+		if err != nil {
+			return nil, nil, err
+		}
+		reviewStats, err := stats.GetReviewStats(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		m.cached, m.load, m.loadedAt = scores, maps.Clone(reviewStats.OpenReviewsByUser), now
+		if m.load == nil {
+			m.load = make(map[string]int)
+		}
+	}
+	return m.cached, maps.Clone(m.load), nil
+}
+
+// assigned records that userID got (delta 1) or lost (delta -1) an open review
+// since the last snapshot.
+func (m *expertiseModel) assigned(userID string, delta int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.load != nil {
+		m.load[userID] = max(m.load[userID]+delta, 0)
+	}
+}
+
+// ranker returns a function ordering candidates by one plus their mean
+// expertise in the areas of pr, divided by one plus the number of open PRs
+// they review: an expert is preferred to an idle newcomer until their open
+// reviews outnumber their expertise score. Ties go to the less loaded
+// candidate and otherwise keep their order.
+func (m *expertiseModel) ranker(ctx context.Context, stats repository.StatsRepository, pr domain.PullRequest) (func(candidates []string), error) {
+	scores, load, err := m.snapshot(ctx, stats, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	areas := pullRequestAreas(pr)
+	rank := func(userID string) float64 {
+		var expertise float64
+		for _, area := range areas {
+			if score := scores[userID][area]; score != nil {
+				expertise += score.Score
+			}
+		}
+		if len(areas) > 0 {
+			expertise /= float64(len(areas))
+		}
+		return (1 + expertise) / float64(1+load[userID])
+	}
+	return func(candidates []string) {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			a, b := candidates[i], candidates[j]
+			if ra, rb := rank(a), rank(b); ra != rb {
+				return ra > rb
+			}
+			return load[a] < load[b]
+		})
+	}, nil
+}
+
+type ExpertiseService interface {
+	// ListExpertise returns the current scores, best first, optionally only
+	// those of userID or of area.
+	ListExpertise(ctx context.Context, userID, area string) ([]domain.ExpertiseScore, error)
+}
+
+type ExpertiseServiceImpl struct {
+	teamRepo repository.TeamRepository
+	model    *expertiseModel
+}
+
+func NewExpertiseService(history repository.ReviewHistoryRepository, teamRepo repository.TeamRepository, opts ExpertiseOptions) ExpertiseService {
+	return &ExpertiseServiceImpl{teamRepo: teamRepo, model: newExpertiseModel(history, opts)}
+}
+
+func (s *ExpertiseServiceImpl) ListExpertise(ctx context.Context, userID, area string) (_ []domain.ExpertiseScore, err error) {
+	ctx, span := tracer.Start(ctx, "ExpertiseService.ListExpertise", trace.WithAttributes(
+		attribute.String("user.id", userID), attribute.String("expertise.area", area)))
+	defer func() { tracing.End(span, err) }()
+
+	if userID != "" {
+		if _, err := s.teamRepo.GetUserByID(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	scores, err := s.model.scores(ctx, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	list := []domain.ExpertiseScore{}
+	for user, byArea := range scores {
+		if userID != "" && user != userID {
+			continue
+		}
+		for _, score := range byArea {
+			if area != "" && score.Area != area {
+				continue
+			}
+			score.Score = math.Round(score.Score*1000) / 1000
+			list = append(list, *score)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Score != list[j].Score {
+			return list[i].Score > list[j].Score
+		}
+		if list[i].UserID != list[j].UserID {
+			return list[i].UserID < list[j].UserID
+		}
+		return list[i].Area < list[j].Area
+	})
+	return list, nil
+}
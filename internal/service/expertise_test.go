@@ -0,0 +1,146 @@
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"Backend/internal/domain"
+	"Backend/internal/repository/memory"
+	"Backend/internal/service"
+)
+
+// newExpertiseFixture seeds team backend (alice, bob, carol, dave) where bob
+// reviewed three merged API PRs an hour ago and carol one docs PR 60 days ago.
+func newExpertiseFixture(t *testing.T) *memory.MemoryRepository {
+	t.Helper()
+	ctx := context.Background()
+	repo := memory.NewMemoryRepository()
+	members := []domain.User{}
+	for _, id := range []string{"alice", "bob", "carol", "dave"} {
+		members = append(members, domain.User{UserID: id, Username: id, TeamName: "backend", IsActive: true})
+	}
+	if _, err := repo.CreateOrUpdateTeam(ctx, domain.Team{TeamName: "backend", Members: members}); err != nil {
+		t.Fatalf("CreateOrUpdateTeam failed: %v", err)
+	}
+
+	now := time.Now().UTC()
+	history := []struct {
+		reviewer string
+		age      time.Duration
+		paths    []string
+		labels   []string
+	}{
+		{"bob", time.Hour, []string{"internal/api/router.go"}, []string{"API"}},
+		{"bob", time.Hour, []string{"internal/api/handler.go", "internal/api/dto.go"}, nil},
+		{"bob", time.Hour, []string{"internal/api/v1/users.go"}, []string{"api"}},
+		{"carol", 60 * 24 * time.Hour, []string{"docs/setup.md"}, nil},
+	}
+	for i, h := range history {
+		createdAt := now.Add(-h.age)
+		pr := domain.PullRequest{PullRequestID: fmt.Sprintf("old-%d", i), PullRequestName: "Old", AuthorID: "alice",
+			Status: domain.StatusMerged, AssignedReviewers: []string{h.reviewer}, CreatedAt: &createdAt, MergedAt: &createdAt,
+			ChangedPaths: h.paths, Labels: h.labels}
+		if _, err := repo.CreatePullRequest(ctx, pr); err != nil {
+			t.Fatalf("CreatePullRequest failed: %v", err)
+		}
+	}
+	return repo
+}
+
+func TestListExpertise(t *testing.T) {
+	repo := newExpertiseFixture(t)
+	expertise := service.NewExpertiseService(repo, repo, service.ExpertiseOptions{HalfLife: 30 * 24 * time.Hour})
+	ctx := context.Background()
+
+	scores, err := expertise.ListExpertise(ctx, "bob", "")
+	if err != nil {
+		t.Fatalf("ListExpertise failed: %v", err)
+	}
+	reviews := map[string]int{}
+	for _, s := range scores {
+		reviews[s.Area] = s.Reviews
+		if s.Score < 0.99*float64(s.Reviews) || s.Score > float64(s.Reviews) {
+			t.Errorf("Expected recent reviews to count almost fully, got %+v", s)
+		}
+	}
+	want := map[string]int{"internal/": 3, "internal/api/": 3, "label:api": 2}
+	if fmt.Sprint(reviews) != fmt.Sprint(want) {
+		t.Errorf("Expected reviews by area %v, got %v", want, reviews)
+	}
+
+	scores, err = expertise.ListExpertise(ctx, "", "docs/")
+	if err != nil || len(scores) != 1 || scores[0].UserID != "carol" || scores[0].Score != 0.25 {
+		t.Errorf("Expected carol's review to count a quarter after two half-lives, got %+v, %v", scores, err)
+	}
+
+	if _, err := expertise.ListExpertise(ctx, "nobody", ""); err == nil {
+		t.Error("Expected an unknown user to be rejected")
+	}
+}
+
+func TestExpertiseStrategy_BalancesExpertiseWithLoad(t *testing.T) {
+	repo := newExpertiseFixture(t)
+	prs := service.NewPRServiceWithOptions(repo, repo, service.PRServiceOptions{
+		ReviewerCount: 1, Strategy: service.StrategyExpertise, History: repo, Stats: repo})
+	ctx := context.Background()
+	create := func(id string, paths ...string) string {
+		t.Helper()
+		pr, err := prs.CreateAndAssignReviewers(ctx, id, "Change", "alice", paths, nil, "")
+		if err != nil {
+			t.Fatalf("CreateAndAssignReviewers failed: %v", err)
+		}
+		return pr.AssignedReviewers[0]
+	}
+
+	// bob knows the API and is idle; each PR he takes adds to his load.
+	for i := range 3 {
+		if got := create(fmt.Sprintf("api-%d", i), "internal/api/middleware.go"); got != "bob" {
+			t.Fatalf("Expected the expert bob to review API change %d, got %s", i, got)
+		}
+	}
+	// With as many open reviews as finished ones, bob ranks like an idle
+	// teammate, and ties go to the less loaded.
+	if got := create("api-busy", "internal/api/middleware.go"); got == "bob" {
+		t.Errorf("Expected a busy bob to be spared, got %s", got)
+	}
+	// Without history, the least loaded reviewer is picked.
+	if got := create("scripts", "scripts/build.sh"); got == "bob" {
+		t.Errorf("Expected the least loaded reviewer for an unknown area, got %s", got)
+	}
+}
+
+// countingHistory counts the reads of the review history and statistics.
+type countingHistory struct {
+	*memory.MemoryRepository
+	historyReads, statsReads int
+}
+
+func (r *countingHistory) ListReviewedPullRequests(ctx context.Context, since time.Time) ([]domain.PullRequest, error) {
+	r.historyReads++
+	return r.MemoryRepository.ListReviewedPullRequests(ctx, since)
+}
+
+func (r *countingHistory) GetReviewStats(ctx context.Context) (domain.ReviewStats, error) {
+	r.statsReads++
+	return r.MemoryRepository.GetReviewStats(ctx)
+}
+
+func TestExpertiseStrategy_ReusesScoresUntilRefresh(t *testing.T) {
+	repo := newExpertiseFixture(t)
+	counting := &countingHistory{MemoryRepository: repo}
+	prs := service.NewPRServiceWithOptions(repo, repo, service.PRServiceOptions{
+		ReviewerCount: 1, Strategy: service.StrategyExpertise, History: counting, Stats: counting,
+		Expertise: service.ExpertiseOptions{Refresh: time.Hour}})
+
+	for i := range 5 {
+		if _, err := prs.CreateAndAssignReviewers(context.Background(), fmt.Sprintf("pr-%d", i), "Change", "alice",
+			[]string{"internal/api/middleware.go"}, nil, ""); err != nil {
+			t.Fatalf("CreateAndAssignReviewers failed: %v", err)
+		}
+	}
+	if counting.historyReads != 1 || counting.statsReads != 1 {
+		t.Errorf("Expected one read of history and stats, got %d and %d", counting.historyReads, counting.statsReads)
+	}
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"Backend/internal/domain"
+	"Backend/internal/repository"
+	"Backend/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Lifecycle actions of a PullRequestChange.
+const (
+	ChangeOpened   = "opened"
+	ChangeClosed   = "closed"
+	ChangeMerged   = "merged"
+	ChangeReopened = "reopened"
+)
+
+// PullRequestChange is a lifecycle event of a pull request reported by a code
+// host, already translated from the host's payload.
+type PullRequestChange struct {
+	Provider      string
+	Action        string
+	PullRequestID string
+	Title         string
+	// AuthorLogin is the author's account on the code host; it is only needed
+	// to open a PR.
+	AuthorLogin string
+	Labels      []string
+}
+
+type IntegrationService interface {
+	// SetUserMapping maps a code host login to an existing user.
+	SetUserMapping(ctx context.Context, provider, login, userID string) (domain.UserMapping, error)
+	ListUserMappings(ctx context.Context, provider string) ([]domain.UserMapping, error)
+	DeleteUserMapping(ctx context.Context, provider, login string) (domain.UserMapping, error)
+	// ApplyPullRequestChange drives the PR service. Code hosts redeliver
+	// events, so applying a change twice has the effect of applying it once.
+	ApplyPullRequestChange(ctx context.Context, change PullRequestChange) (domain.PullRequest, error)
+}
+
+type IntegrationServiceImpl struct {
+	prService   PRService
+	mappingRepo repository.UserMappingRepository
+	teamRepo    repository.TeamRepository
+}
+
+func NewIntegrationService(prService PRService, mappingRepo repository.UserMappingRepository, teamRepo repository.TeamRepository) IntegrationService {
+	return &IntegrationServiceImpl{prService: prService, mappingRepo: mappingRepo, teamRepo: teamRepo}
+}
+
+func (s *IntegrationServiceImpl) SetUserMapping(ctx context.Context, provider, login, userID string) (_ domain.UserMapping, err error) {
+	ctx, span := tracer.Start(ctx, "IntegrationService.SetUserMapping", trace.WithAttributes(
+		attribute.String("integration.provider", provider), attribute.String("user.id", userID)))
+	defer func() { tracing.End(span, err) }()
+
+	if !slices.Contains(domain.Providers, provider) {
+		return domain.UserMapping{}, domain.NewValidationError(domain.FieldError{
+			Field: "provider", Message: "must be one of " + strings.Join(domain.Providers, ", ")})
+	}
+	if _, err := s.teamRepo.GetUserByID(ctx, userID); err != nil {
+		return domain.UserMapping{}, err
+	}
+
+	m, err := s.mappingRepo.SetUserMapping(ctx, domain.UserMapping{
+		Provider:  provider,
+		Login:     strings.ToLower(login),
+		UserID:    userID,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return domain.UserMapping{}, err
+	}
+
+	slog.InfoContext(ctx, "user mapping set", "provider", provider, "login", m.Login, "user_id", userID)
+	return m, nil
+}
+
+func (s *IntegrationServiceImpl) ListUserMappings(ctx context.Context, provider string) (_ []domain.UserMapping, err error) {
+	ctx, span := tracer.Start(ctx, "IntegrationService.ListUserMappings")
+	defer func() { tracing.End(span, err) }()
+
+	return s.mappingRepo.ListUserMappings(ctx, provider)
+}
+
+func (s *IntegrationServiceImpl) DeleteUserMapping(ctx context.Context, provider, login string) (_ domain.UserMapping, err error) {
+	ctx, span := tracer.Start(ctx, "IntegrationService.DeleteUserMapping", trace.WithAttributes(attribute.String("integration.provider", provider)))
+	defer func() { tracing.End(span, err) }()
+
+	m, err := s.mappingRepo.DeleteUserMapping(ctx, provider, strings.ToLower(login))
+	if err != nil {
+		return domain.UserMapping{}, err
+	}
+
+	slog.InfoContext(ctx, "user mapping deleted", "provider", provider, "login", m.Login)
+	return m, nil
+}
+
+func (s *IntegrationServiceImpl) ApplyPullRequestChange(ctx context.Context, change PullRequestChange) (_ domain.PullRequest, err error) {
+	ctx, span := tracer.Start(ctx, "IntegrationService.ApplyPullRequestChange", trace.WithAttributes(
+		attribute.String("integration.provider", change.Provider), attribute.String("integration.action", change.Action),
+		attribute.String("pr.id", change.PullRequestID)))
+	defer func() { tracing.End(span, err) }()
+
+	switch change.Action {
+	case ChangeOpened:
+		m, err := s.mappingRepo.GetUserMapping(ctx, change.Provider, strings.ToLower(change.AuthorLogin))
+		if err != nil {
+			return domain.PullRequest{}, err
+		}
+		pr, err := s.prService.CreateAndAssignReviewers(ctx, change.PullRequestID, change.Title, m.UserID, nil, change.Labels, change.PullRequestID)
+		var bErr *domain.BusinessError
+		if errors.As(err, &bErr) && bErr.Code == domain.ErrPRExists {
+			return s.prService.GetPullRequest(ctx, change.PullRequestID)
+		}
+		return pr, err
+	case ChangeMerged:
+		return s.prService.MergePullRequest(ctx, change.PullRequestID, domain.AnyVersion)
+	case ChangeClosed:
+		return s.prService.ClosePullRequest(ctx, change.PullRequestID, domain.AnyVersion)
+	case ChangeReopened:
+		return s.prService.ReopenPullRequest(ctx, change.PullRequestID, domain.AnyVersion)
+	default:
+		return domain.PullRequest{}, domain.NewValidationError(domain.FieldError{Field: "action", Message: "unknown action " + change.Action})
+	}
+}
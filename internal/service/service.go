@@ -2,17 +2,37 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"math/rand"
+	"strings"
 	"time"
 
+	"Backend/internal/codeowners"
 	"Backend/internal/domain"
 	"Backend/internal/repository"
+	"Backend/internal/tracing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("Backend/internal/service")
+
 type PRService interface {
-	CreateAndAssignReviewers(ctx context.Context, prID, prName, authorID string) (domain.PullRequest, error)
-	MergePullRequest(ctx context.Context, prID string) (domain.PullRequest, error)
-	ReassignReviewer(ctx context.Context, prID, oldUserID string) (domain.PullRequest, string, error)
+	// CreateAndAssignReviewers prefers active owners of changedPaths, under the
+	// CODEOWNERS rules of the author's team, over other team members.
+	// codeHostRef is only given for PRs opened by code host webhooks.
+	CreateAndAssignReviewers(ctx context.Context, prID, prName, authorID string, changedPaths, labels []string, codeHostRef string) (domain.PullRequest, error)
+	GetPullRequest(ctx context.Context, prID string) (domain.PullRequest, error)
+	MergePullRequest(ctx context.Context, prID string, expectedVersion int) (domain.PullRequest, error)
+	ReassignReviewer(ctx context.Context, prID, oldUserID string, expectedVersion int) (domain.PullRequest, string, error)
+	// ClosePullRequest closes an open PR without merging it; ReopenPullRequest
+	// opens it again with the same reviewers.
+	ClosePullRequest(ctx context.Context, prID string, expectedVersion int) (domain.PullRequest, error)
+	ReopenPullRequest(ctx context.Context, prID string, expectedVersion int) (domain.PullRequest, error)
 }
 
 type TeamService interface {
@@ -25,21 +45,70 @@ type UserService interface {
 	GetReviewPRsByUserID(ctx context.Context, userID string) ([]domain.PullRequestShort, error)
 }
 
+const (
+	DefaultReviewerCount = 2
+	StrategyRandom       = "random"
+	// StrategyExpertise prefers reviewers who reviewed the areas of a PR
+	// before, weighed against their open reviews.
+	StrategyExpertise = "expertise"
+
+	// MaxChangedPaths and MaxLabels bound the changed paths and labels stored
+	// with a PR.
+	MaxChangedPaths = 1000
+	MaxLabels       = 100
+	maxPathLength   = 1024
+	maxLabelLength  = 255
+)
+
+type PRServiceOptions struct {
+	// ReviewerCount is the number of reviewers assigned to a new PR.
+	ReviewerCount int
+	Strategy      string
+	// CodeOwners, when set, makes reviewer selection prefer the owners of the
+	// changed paths.
+	CodeOwners repository.CodeOwnersRepository
+	// History and Stats are required by StrategyExpertise.
+	History   repository.ReviewHistoryRepository
+	Stats     repository.StatsRepository
+	Expertise ExpertiseOptions
+}
+
 type PRServiceImpl struct {
-	prRepo   repository.PullRequestRepository
-	teamRepo repository.TeamRepository
-	random   *rand.Rand
+	prRepo        repository.PullRequestRepository
+	teamRepo      repository.TeamRepository
+	ownersRepo    repository.CodeOwnersRepository
+	statsRepo     repository.StatsRepository
+	random        *rand.Rand
+	reviewerCount int
+	// expertise ranks candidates under StrategyExpertise.
+	expertise *expertiseModel
 }
 
 func NewPRService(prRepo repository.PullRequestRepository, teamRepo repository.TeamRepository) PRService {
-	return &PRServiceImpl{
-		prRepo:   prRepo,
-		teamRepo: teamRepo,
-		random:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	return NewPRServiceWithOptions(prRepo, teamRepo, PRServiceOptions{})
+}
+
+func NewPRServiceWithOptions(prRepo repository.PullRequestRepository, teamRepo repository.TeamRepository, opts PRServiceOptions) PRService {
+	if opts.ReviewerCount <= 0 {
+		opts.ReviewerCount = DefaultReviewerCount
+	}
+	s := &PRServiceImpl{
+		prRepo:        prRepo,
+		teamRepo:      teamRepo,
+		ownersRepo:    opts.CodeOwners,
+		statsRepo:     opts.Stats,
+		random:        rand.New(rand.NewSource(time.Now().UnixNano())),
+		reviewerCount: opts.ReviewerCount,
 	}
+	if opts.Strategy == StrategyExpertise && opts.History != nil && opts.Stats != nil {
+		s.expertise = newExpertiseModel(opts.History, opts.Expertise)
+	}
+	return s
 }
 
-func (s *PRServiceImpl) selectRandomReviewers(candidates []string, count int) []string {
+// pickReviewers shuffles candidates, orders them with rank when it is set and
+// returns the first count.
+func (s *PRServiceImpl) pickReviewers(candidates []string, count int, rank func([]string)) []string {
 	if len(candidates) == 0 {
 		return []string{}
 	}
@@ -47,6 +116,9 @@ func (s *PRServiceImpl) selectRandomReviewers(candidates []string, count int) []
 	s.random.Shuffle(len(candidates), func(i, j int) {
 		candidates[i], candidates[j] = candidates[j], candidates[i]
 	})
+	if rank != nil {
+		rank(candidates)
+	}
 
 	numToSelect := count
 	if len(candidates) < count {
@@ -56,7 +128,142 @@ func (s *PRServiceImpl) selectRandomReviewers(candidates []string, count int) []
 	return candidates[:numToSelect]
 }
 
-func (s *PRServiceImpl) CreateAndAssignReviewers(ctx context.Context, prID, prName, authorID string) (domain.PullRequest, error) {
+// selectReviewers picks count reviewers for pr, taking them from owners first
+// and filling up from candidates. Both lists are reordered in place.
+func (s *PRServiceImpl) selectReviewers(ctx context.Context, pr domain.PullRequest, owners, candidates []string, count int) ([]string, error) {
+	var rank func([]string)
+	if s.expertise != nil {
+		var err error
+		if rank, err = s.expertise.ranker(ctx, s.statsRepo, pr); err != nil {
+			return nil, err
+		}
+	}
+
+	reviewers := s.pickReviewers(owners, count, rank)
+	picked := make(map[string]bool, len(reviewers))
+	for _, id := range reviewers {
+		picked[id] = true
+	}
+	var rest []string
+	for _, id := range candidates {
+		if !picked[id] {
+			rest = append(rest, id)
+		}
+	}
+	return append(reviewers, s.pickReviewers(rest, count-len(reviewers), rank)...), nil
+}
+
+func cleanChangedPaths(paths []string) ([]string, error) {
+	if len(paths) > MaxChangedPaths {
+		return nil, domain.NewValidationError(domain.FieldError{
+			Field: "changed_paths", Message: fmt.Sprintf("must not contain more than %d paths", MaxChangedPaths)})
+	}
+	var cleaned []string
+	for i, path := range paths {
+		path = codeowners.Clean(strings.TrimSpace(path))
+		if path == "" || len(path) > maxPathLength {
+			return nil, domain.NewValidationError(domain.FieldError{
+				Field: fmt.Sprintf("changed_paths[%d]", i), Message: fmt.Sprintf("must be a path of 1 to %d characters", maxPathLength)})
+		}
+		cleaned = append(cleaned, path)
+	}
+	return cleaned, nil
+}
+
+func cleanLabels(labels []string) ([]string, error) {
+	if len(labels) > MaxLabels {
+		return nil, domain.NewValidationError(domain.FieldError{
+			Field: "labels", Message: fmt.Sprintf("must not contain more than %d labels", MaxLabels)})
+	}
+	var cleaned []string
+	for i, label := range labels {
+		label = strings.TrimSpace(label)
+		if label == "" || len(label) > maxLabelLength {
+			return nil, domain.NewValidationError(domain.FieldError{
+				Field: fmt.Sprintf("labels[%d]", i), Message: fmt.Sprintf("must be a label of 1 to %d characters", maxLabelLength)})
+		}
+		cleaned = append(cleaned, label)
+	}
+	return cleaned, nil
+}
+
+// pathOwners returns the active users owning any of paths under the CODEOWNERS
+// rules of team teamName, with owning teams expanded to their members, minus
+// the users in exclude. Users are returned in the order they are first found.
+func (s *PRServiceImpl) pathOwners(ctx context.Context, teamName string, paths []string, exclude map[string]bool) ([]string, error) {
+	if s.ownersRepo == nil || len(paths) == 0 {
+		return nil, nil
+	}
+	stored, err := s.ownersRepo.GetCodeOwners(ctx, teamName)
+	var bErr *domain.BusinessError
+	if errors.As(err, &bErr) && bErr.Code == domain.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rules, err := codeowners.Parse(stored.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("parse CODEOWNERS of team %s: %w", teamName, err)
+	}
+
+	seen := make(map[string]bool)
+	var owners []string
+	add := func(user domain.User) {
+		if user.IsActive && !exclude[user.UserID] && !seen[user.UserID] {
+			seen[user.UserID] = true
+			owners = append(owners, user.UserID)
+		}
+	}
+	// Paths often share owners, so each distinct owner is looked up only once.
+	resolved := make(map[string]bool)
+	for _, path := range paths {
+		for _, owner := range rules.Owners(path) {
+			if resolved[owner] {
+				continue
+			}
+			resolved[owner] = true
+			// Owners may have been removed since the rules were uploaded.
+			if name, ok := strings.CutPrefix(owner, "@"); ok {
+				team, err := s.teamRepo.GetTeamByName(ctx, name)
+				if errors.As(err, &bErr) && bErr.Code == domain.ErrNotFound {
+					continue
+				}
+				if err != nil {
+					return nil, err
+				}
+				for _, member := range team.Members {
+					add(domain.User{UserID: member.UserID, IsActive: member.IsActive})
+				}
+				continue
+			}
+			user, err := s.teamRepo.GetUserByID(ctx, owner)
+			if errors.As(err, &bErr) && bErr.Code == domain.ErrNotFound {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			add(user)
+		}
+	}
+	return owners, nil
+}
+
+func (s *PRServiceImpl) CreateAndAssignReviewers(ctx context.Context, prID, prName, authorID string, changedPaths, labels []string, codeHostRef string) (_ domain.PullRequest, err error) {
+	ctx, span := tracer.Start(ctx, "PRService.CreateAndAssignReviewers", trace.WithAttributes(
+		attribute.String("pr.id", prID), attribute.String("pr.author_id", authorID), attribute.Int("pr.changed_paths", len(changedPaths))))
+	defer func() { tracing.End(span, err) }()
+
+	changedPaths, err = cleanChangedPaths(changedPaths)
+	if err != nil {
+		return domain.PullRequest{}, err
+	}
+	labels, err = cleanLabels(labels)
+	if err != nil {
+		return domain.PullRequest{}, err
+	}
+
 	author, err := s.teamRepo.GetUserByID(ctx, authorID)
 	if err != nil {
 		return domain.PullRequest{}, err
@@ -74,50 +281,114 @@ func (s *PRServiceImpl) CreateAndAssignReviewers(ctx context.Context, prID, prNa
 		}
 	}
 
-	reviewers := s.selectRandomReviewers(candidates, 2)
-
 	now := time.Now().UTC()
 	newPR := domain.PullRequest{
-		PullRequestID:     prID,
-		PullRequestName:   prName,
-		AuthorID:          authorID,
-		Status:            domain.StatusOpen,
-		AssignedReviewers: reviewers,
-		CreatedAt:         &now,
+		PullRequestID:   prID,
+		PullRequestName: prName,
+		AuthorID:        authorID,
+		Status:          domain.StatusOpen,
+		CreatedAt:       &now,
+		ChangedPaths:    changedPaths,
+		Labels:          labels,
+		CodeHostRef:     codeHostRef,
+	}
+
+	owners, err := s.pathOwners(ctx, author.TeamName, changedPaths, map[string]bool{authorID: true})
+	if err != nil {
+		return domain.PullRequest{}, err
+	}
+	reviewers, err := s.selectReviewers(ctx, newPR, owners, candidates, s.reviewerCount)
+	if err != nil {
+		return domain.PullRequest{}, err
+	}
+	newPR.AssignedReviewers = reviewers
+
+	created, err := s.prRepo.CreatePullRequest(ctx, newPR)
+	if err != nil {
+		return domain.PullRequest{}, err
 	}
 
-	return s.prRepo.CreatePullRequest(ctx, newPR)
+	if s.expertise != nil {
+		for _, id := range reviewers {
+			s.expertise.assigned(id, 1)
+		}
+	}
+
+	slog.InfoContext(ctx, "pull request created", "pr_id", prID, "author_id", authorID, "reviewers", reviewers)
+	return created, nil
+}
+
+func (s *PRServiceImpl) GetPullRequest(ctx context.Context, prID string) (_ domain.PullRequest, err error) {
+	ctx, span := tracer.Start(ctx, "PRService.GetPullRequest", trace.WithAttributes(attribute.String("pr.id", prID)))
+	defer func() { tracing.End(span, err) }()
+
+	return s.prRepo.GetPullRequestByID(ctx, prID)
+}
+
+func checkVersion(pr domain.PullRequest, expectedVersion int) error {
+	if expectedVersion != domain.AnyVersion && pr.Version != expectedVersion {
+		return domain.NewBusinessError(domain.ErrVersionConflict,
+			fmt.Sprintf("Pull Request %s is at version %d, expected %d", pr.PullRequestID, pr.Version, expectedVersion))
+	}
+	return nil
 }
 
-func (s *PRServiceImpl) MergePullRequest(ctx context.Context, prID string) (domain.PullRequest, error) {
+func (s *PRServiceImpl) MergePullRequest(ctx context.Context, prID string, expectedVersion int) (_ domain.PullRequest, err error) {
+	ctx, span := tracer.Start(ctx, "PRService.MergePullRequest", trace.WithAttributes(attribute.String("pr.id", prID)))
+	defer func() { tracing.End(span, err) }()
+
 	pr, err := s.prRepo.GetPullRequestByID(ctx, prID)
 	if err != nil {
 		return domain.PullRequest{}, err
 	}
 
+	if err := checkVersion(pr, expectedVersion); err != nil {
+		return domain.PullRequest{}, err
+	}
+
 	if pr.Status == domain.StatusMerged {
 		return pr, nil
 	}
+	if pr.Status == domain.StatusClosed {
+		return domain.PullRequest{}, domain.NewBusinessError(domain.ErrPRClosed, "cannot merge closed PR")
+	}
 
 	pr.Status = domain.StatusMerged
 	now := time.Now().UTC()
 	pr.MergedAt = &now
 
-	return s.prRepo.UpdatePullRequest(ctx, pr)
+	merged, err := s.prRepo.UpdatePullRequest(ctx, pr)
+	if err != nil {
+		return domain.PullRequest{}, err
+	}
+
+	slog.InfoContext(ctx, "pull request merged", "pr_id", prID)
+	return merged, nil
 }
 
-func (s *PRServiceImpl) ReassignReviewer(ctx context.Context, prID, oldUserID string) (domain.PullRequest, string, error) {
+func (s *PRServiceImpl) ReassignReviewer(ctx context.Context, prID, oldUserID string, expectedVersion int) (_ domain.PullRequest, _ string, err error) {
+	ctx, span := tracer.Start(ctx, "PRService.ReassignReviewer", trace.WithAttributes(
+		attribute.String("pr.id", prID), attribute.String("pr.old_reviewer_id", oldUserID)))
+	defer func() { tracing.End(span, err) }()
+
 	pr, err := s.prRepo.GetPullRequestByID(ctx, prID)
 	if err != nil {
 		return domain.PullRequest{}, "", err
 	}
 
+	if err := checkVersion(pr, expectedVersion); err != nil {
+		return domain.PullRequest{}, "", err
+	}
+
 	if pr.Status == domain.StatusMerged {
 		return domain.PullRequest{}, "", &domain.BusinessError{
 			Code:    domain.ErrPRMerged,
 			Message: "cannot reassign on merged PR",
 		}
 	}
+	if pr.Status == domain.StatusClosed {
+		return domain.PullRequest{}, "", domain.NewBusinessError(domain.ErrPRClosed, "cannot reassign on closed PR")
+	}
 
 	oldReviewerIndex := -1
 	for i, assignedID := range pr.AssignedReviewers {
@@ -148,6 +419,18 @@ func (s *PRServiceImpl) ReassignReviewer(ctx context.Context, prID, oldUserID st
 	}
 	assignedSet[pr.AuthorID] = true
 
+	var owners []string
+	if len(pr.ChangedPaths) > 0 {
+		author, err := s.teamRepo.GetUserByID(ctx, pr.AuthorID)
+		if err != nil {
+			return domain.PullRequest{}, "", err
+		}
+		owners, err = s.pathOwners(ctx, author.TeamName, pr.ChangedPaths, assignedSet)
+		if err != nil {
+			return domain.PullRequest{}, "", err
+		}
+	}
+
 	var candidates []string
 	for _, member := range team.Members {
 		if member.IsActive && !assignedSet[member.UserID] {
@@ -155,14 +438,19 @@ func (s *PRServiceImpl) ReassignReviewer(ctx context.Context, prID, oldUserID st
 		}
 	}
 
-	if len(candidates) == 0 {
+	if len(owners) == 0 && len(candidates) == 0 {
 		return domain.PullRequest{}, "", &domain.BusinessError{
 			Code:    domain.ErrNoCandidate,
 			Message: "no active replacement candidate in team",
 		}
 	}
 
-	newUserID := s.selectRandomReviewers(candidates, 1)[0]
+	picked, err := s.selectReviewers(ctx, pr, owners, candidates, 1)
+	if err != nil {
+		return domain.PullRequest{}, "", err
+	}
+	newUserID := picked[0]
+	span.SetAttributes(attribute.String("pr.new_reviewer_id", newUserID))
 
 	pr.AssignedReviewers[oldReviewerIndex] = newUserID
 
@@ -170,7 +458,12 @@ func (s *PRServiceImpl) ReassignReviewer(ctx context.Context, prID, oldUserID st
 	if err != nil {
 		return domain.PullRequest{}, "", err
 	}
+	if s.expertise != nil {
+		s.expertise.assigned(oldUserID, -1)
+		s.expertise.assigned(newUserID, 1)
+	}
 
+	slog.InfoContext(ctx, "reviewer reassigned", "pr_id", prID, "old_user_id", oldUserID, "new_user_id", newUserID)
 	return updatedPR, newUserID, nil
 }
 
@@ -182,11 +475,23 @@ func NewTeamService(teamRepo repository.TeamRepository) TeamService {
 	return &TeamServiceImpl{teamRepo: teamRepo}
 }
 
-func (s *TeamServiceImpl) CreateOrUpdateTeam(ctx context.Context, team domain.Team) (domain.Team, error) {
-	return s.teamRepo.CreateOrUpdateTeam(ctx, team)
+func (s *TeamServiceImpl) CreateOrUpdateTeam(ctx context.Context, team domain.Team) (_ domain.Team, err error) {
+	ctx, span := tracer.Start(ctx, "TeamService.CreateOrUpdateTeam", trace.WithAttributes(attribute.String("team.name", team.TeamName)))
+	defer func() { tracing.End(span, err) }()
+
+	saved, err := s.teamRepo.CreateOrUpdateTeam(ctx, team)
+	if err != nil {
+		return domain.Team{}, err
+	}
+
+	slog.InfoContext(ctx, "team saved", "team_name", team.TeamName, "members", len(team.Members))
+	return saved, nil
 }
 
-func (s *TeamServiceImpl) GetTeamByName(ctx context.Context, teamName string) (domain.Team, error) {
+func (s *TeamServiceImpl) GetTeamByName(ctx context.Context, teamName string) (_ domain.Team, err error) {
+	ctx, span := tracer.Start(ctx, "TeamService.GetTeamByName", trace.WithAttributes(attribute.String("team.name", teamName)))
+	defer func() { tracing.End(span, err) }()
+
 	return s.teamRepo.GetTeamByName(ctx, teamName)
 }
 
@@ -199,14 +504,72 @@ func NewUserService(teamRepo repository.TeamRepository, prRepo repository.PullRe
 	return &UserServiceImpl{teamRepo: teamRepo, prRepo: prRepo}
 }
 
-func (s *UserServiceImpl) SetUserIsActive(ctx context.Context, userID string, isActive bool) (domain.User, error) {
-	return s.teamRepo.SetUserIsActive(ctx, userID, isActive)
+func (s *UserServiceImpl) SetUserIsActive(ctx context.Context, userID string, isActive bool) (_ domain.User, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.SetUserIsActive", trace.WithAttributes(attribute.String("user.id", userID)))
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.teamRepo.SetUserIsActive(ctx, userID, isActive)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	slog.InfoContext(ctx, "user activity changed", "user_id", userID, "is_active", isActive)
+	return user, nil
 }
 
-func (s *UserServiceImpl) GetReviewPRsByUserID(ctx context.Context, userID string) ([]domain.PullRequestShort, error) {
+func (s *UserServiceImpl) GetReviewPRsByUserID(ctx context.Context, userID string) (_ []domain.PullRequestShort, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetReviewPRsByUserID", trace.WithAttributes(attribute.String("user.id", userID)))
+	defer func() { tracing.End(span, err) }()
+
 	if _, err := s.teamRepo.GetUserByID(ctx, userID); err != nil {
 		return nil, err
 	}
 
 	return s.prRepo.GetPRsByReviewerID(ctx, userID)
 }
+
+func (s *PRServiceImpl) ClosePullRequest(ctx context.Context, prID string, expectedVersion int) (_ domain.PullRequest, err error) {
+	ctx, span := tracer.Start(ctx, "PRService.ClosePullRequest", trace.WithAttributes(attribute.String("pr.id", prID)))
+	defer func() { tracing.End(span, err) }()
+
+	return s.setStatus(ctx, prID, expectedVersion, domain.StatusOpen, domain.StatusClosed)
+}
+
+func (s *PRServiceImpl) ReopenPullRequest(ctx context.Context, prID string, expectedVersion int) (_ domain.PullRequest, err error) {
+	ctx, span := tracer.Start(ctx, "PRService.ReopenPullRequest", trace.WithAttributes(attribute.String("pr.id", prID)))
+	defer func() { tracing.End(span, err) }()
+
+	return s.setStatus(ctx, prID, expectedVersion, domain.StatusClosed, domain.StatusOpen)
+}
+
+// setStatus moves a PR from status from to status to. A PR already in status
+// to is returned unchanged; merged PRs cannot change.
+func (s *PRServiceImpl) setStatus(ctx context.Context, prID string, expectedVersion int, from, to domain.PullRequestStatus) (domain.PullRequest, error) {
+	pr, err := s.prRepo.GetPullRequestByID(ctx, prID)
+	if err != nil {
+		return domain.PullRequest{}, err
+	}
+
+	if err := checkVersion(pr, expectedVersion); err != nil {
+		return domain.PullRequest{}, err
+	}
+
+	switch pr.Status {
+	case to:
+		return pr, nil
+	case domain.StatusMerged:
+		return domain.PullRequest{}, domain.NewBusinessError(domain.ErrPRMerged, "cannot change status of merged PR")
+	case from:
+	default:
+		return domain.PullRequest{}, fmt.Errorf("pull request %s has unexpected status %s", prID, pr.Status)
+	}
+
+	pr.Status = to
+	updated, err := s.prRepo.UpdatePullRequest(ctx, pr)
+	if err != nil {
+		return domain.PullRequest{}, err
+	}
+
+	slog.InfoContext(ctx, "pull request status changed", "pr_id", prID, "status", to)
+	return updated, nil
+}
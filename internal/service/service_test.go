@@ -133,7 +133,7 @@ func TestCreateAndAssignReviewers_Success(t *testing.T) {
 
 	prService := service.NewPRService(mockPRRepo, mockTeamRepo)
 
-	_, err := prService.CreateAndAssignReviewers(ctx, "pr-1", "Test PR", authorID)
+	_, err := prService.CreateAndAssignReviewers(ctx, "pr-1", "Test PR", authorID, nil, nil, "")
 
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
@@ -172,7 +172,7 @@ func TestCreateAndAssignReviewers_NotEnoughCandidates(t *testing.T) {
 
 	prService := service.NewPRService(mockPRRepo, mockTeamRepo)
 
-	_, err := prService.CreateAndAssignReviewers(ctx, "pr-2", "Small Team PR", authorID)
+	_, err := prService.CreateAndAssignReviewers(ctx, "pr-2", "Small Team PR", authorID, nil, nil, "")
 
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
@@ -200,7 +200,7 @@ func TestMergePullRequest_Idempotent(t *testing.T) {
 
 	prService := service.NewPRService(mockPRRepo, newMockTeamRepo())
 
-	_, err := prService.MergePullRequest(ctx, prID)
+	_, err := prService.MergePullRequest(ctx, prID, domain.AnyVersion)
 
 	if err != nil {
 		t.Errorf("Expected no error (idempotent), got %v", err)
@@ -249,7 +249,7 @@ func TestReassignReviewer_Success(t *testing.T) {
 
 	prService := service.NewPRService(mockPRRepo, mockTeamRepo)
 
-	updatedPR, newUserID, err := prService.ReassignReviewer(ctx, prID, oldUserID)
+	updatedPR, newUserID, err := prService.ReassignReviewer(ctx, prID, oldUserID, domain.AnyVersion)
 
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
@@ -282,7 +282,7 @@ func TestReassignReviewer_PRMerged(t *testing.T) {
 
 	prService := service.NewPRService(mockPRRepo, newMockTeamRepo())
 
-	_, _, err := prService.ReassignReviewer(ctx, prID, "u2")
+	_, _, err := prService.ReassignReviewer(ctx, prID, "u2", domain.AnyVersion)
 
 	var businessErr *domain.BusinessError
 	if !errors.As(err, &businessErr) || businessErr.Code != domain.ErrPRMerged {
@@ -342,3 +342,68 @@ func TestUserService_GetReviewPRsByUserID_UserNotFound(t *testing.T) {
 		t.Errorf("Expected error code %s, got %v", domain.ErrNotFound, err)
 	}
 }
+
+func TestMergePullRequest_VersionConflict(t *testing.T) {
+	ctx := context.Background()
+	prID := "pr-stale"
+
+	mockPRRepo := newMockPRRepo()
+	mockPRRepo.GetPullRequestByIDFn = func(ctx context.Context, id string) (domain.PullRequest, error) {
+		return domain.PullRequest{PullRequestID: prID, Status: domain.StatusOpen, Version: 3}, nil
+	}
+	mockPRRepo.UpdatePullRequestFn = func(ctx context.Context, pr domain.PullRequest) (domain.PullRequest, error) {
+		t.Fatal("UpdatePullRequest should NOT be called with a stale version")
+		return pr, nil
+	}
+
+	prService := service.NewPRService(mockPRRepo, newMockTeamRepo())
+
+	_, err := prService.MergePullRequest(ctx, prID, 2)
+
+	var businessErr *domain.BusinessError
+	if !errors.As(err, &businessErr) || businessErr.Code != domain.ErrVersionConflict {
+		t.Errorf("Expected error code %s, got %v", domain.ErrVersionConflict, err)
+	}
+}
+
+func TestClosePullRequest_MergedCannotBeClosed(t *testing.T) {
+	ctx := context.Background()
+	prID := "pr-merged"
+
+	mockPRRepo := newMockPRRepo()
+	mockPRRepo.GetPullRequestByIDFn = func(ctx context.Context, id string) (domain.PullRequest, error) {
+		return domain.PullRequest{PullRequestID: prID, Status: domain.StatusMerged}, nil
+	}
+	mockPRRepo.UpdatePullRequestFn = func(ctx context.Context, pr domain.PullRequest) (domain.PullRequest, error) {
+		t.Fatal("UpdatePullRequest should NOT be called on a merged PR")
+		return pr, nil
+	}
+
+	prService := service.NewPRService(mockPRRepo, newMockTeamRepo())
+
+	_, err := prService.ClosePullRequest(ctx, prID, domain.AnyVersion)
+
+	var businessErr *domain.BusinessError
+	if !errors.As(err, &businessErr) || businessErr.Code != domain.ErrPRMerged {
+		t.Errorf("Expected PR_MERGED, got %v", err)
+	}
+}
+
+func TestMergePullRequest_ClosedCannotBeMerged(t *testing.T) {
+	ctx := context.Background()
+	prID := "pr-closed"
+
+	mockPRRepo := newMockPRRepo()
+	mockPRRepo.GetPullRequestByIDFn = func(ctx context.Context, id string) (domain.PullRequest, error) {
+		return domain.PullRequest{PullRequestID: prID, Status: domain.StatusClosed}, nil
+	}
+
+	prService := service.NewPRService(mockPRRepo, newMockTeamRepo())
+
+	_, err := prService.MergePullRequest(ctx, prID, domain.AnyVersion)
+
+	var businessErr *domain.BusinessError
+	if !errors.As(err, &businessErr) || businessErr.Code != domain.ErrPRClosed {
+		t.Errorf("Expected PR_CLOSED, got %v", err)
+	}
+}
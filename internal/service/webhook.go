@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"time"
+
+	"Backend/internal/domain"
+	"Backend/internal/repository"
+	"Backend/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	DefaultDeadLetterLimit = 100
+	MaxDeadLetterLimit     = 1000
+)
+
+// EventPublisher receives the events relayed from the outbox; it is an
+// outbox.Sink.
+type EventPublisher interface {
+	Publish(ctx context.Context, event domain.Event) error
+}
+
+type WebhookService interface {
+	EventPublisher
+	// CreateSubscription stores a subscription and returns it together with its
+	// signing secret, which is generated when secret is empty.
+	CreateSubscription(ctx context.Context, rawURL, secret string, events []string) (domain.WebhookSubscription, string, error)
+	ListSubscriptions(ctx context.Context) ([]domain.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id string) (domain.WebhookSubscription, error)
+	ListDeadLetters(ctx context.Context, limit int) ([]domain.WebhookDelivery, error)
+	// RetryDeadLetter schedules a dead delivery for another round of attempts.
+	RetryDeadLetter(ctx context.Context, id string) (domain.WebhookDelivery, error)
+}
+
+type WebhookServiceImpl struct {
+	webhookRepo repository.WebhookRepository
+}
+
+func NewWebhookService(webhookRepo repository.WebhookRepository) WebhookService {
+	return &WebhookServiceImpl{webhookRepo: webhookRepo}
+}
+
+func (s *WebhookServiceImpl) CreateSubscription(ctx context.Context, rawURL, secret string, events []string) (_ domain.WebhookSubscription, _ string, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.CreateSubscription")
+	defer func() { tracing.End(span, err) }()
+
+	if err := checkSubscription(rawURL, events); err != nil {
+		return domain.WebhookSubscription{}, "", err
+	}
+	if secret == "" {
+		secret = "whsec_" + randomHex(24)
+	}
+
+	sub, err := s.webhookRepo.CreateWebhookSubscription(ctx, domain.WebhookSubscription{
+		ID:        "whs_" + randomHex(8),
+		URL:       rawURL,
+		Secret:    secret,
+		Events:    events,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return domain.WebhookSubscription{}, "", err
+	}
+
+	slog.InfoContext(ctx, "webhook subscription created", "subscription_id", sub.ID, "events", events)
+	return sub, secret, nil
+}
+
+func checkSubscription(rawURL string, events []string) error {
+	var details []domain.FieldError
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		details = append(details, domain.FieldError{Field: "url", Message: "must be an absolute http or https URL"})
+	}
+	if len(events) == 0 {
+		details = append(details, domain.FieldError{Field: "events", Message: "is required"})
+	}
+	for i, event := range events {
+		if event != "*" && !slices.Contains(domain.EventTypes, event) {
+			details = append(details, domain.FieldError{Field: fmt.Sprintf("events[%d]", i), Message: fmt.Sprintf("unknown event %q", event)})
+		}
+	}
+	if len(details) > 0 {
+		return domain.NewValidationError(details...)
+	}
+	return nil
+}
+
+func (s *WebhookServiceImpl) ListSubscriptions(ctx context.Context) (_ []domain.WebhookSubscription, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.ListSubscriptions")
+	defer func() { tracing.End(span, err) }()
+
+	return s.webhookRepo.ListWebhookSubscriptions(ctx)
+}
+
+func (s *WebhookServiceImpl) DeleteSubscription(ctx context.Context, id string) (_ domain.WebhookSubscription, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.DeleteSubscription", trace.WithAttributes(attribute.String("webhook.subscription_id", id)))
+	defer func() { tracing.End(span, err) }()
+
+	sub, err := s.webhookRepo.DeleteWebhookSubscription(ctx, id)
+	if err != nil {
+		return domain.WebhookSubscription{}, err
+	}
+
+	slog.InfoContext(ctx, "webhook subscription deleted", "subscription_id", id)
+	return sub, nil
+}
+
+func (s *WebhookServiceImpl) ListDeadLetters(ctx context.Context, limit int) (_ []domain.WebhookDelivery, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.ListDeadLetters")
+	defer func() { tracing.End(span, err) }()
+
+	if limit <= 0 {
+		limit = DefaultDeadLetterLimit
+	}
+	return s.webhookRepo.ListWebhookDeliveries(ctx, domain.DeliveryDead, min(limit, MaxDeadLetterLimit))
+}
+
+func (s *WebhookServiceImpl) RetryDeadLetter(ctx context.Context, id string) (_ domain.WebhookDelivery, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.RetryDeadLetter", trace.WithAttributes(attribute.String("webhook.delivery_id", id)))
+	defer func() { tracing.End(span, err) }()
+
+	delivery, err := s.webhookRepo.RequeueWebhookDelivery(ctx, id, time.Now().UTC())
+	if err != nil {
+		return domain.WebhookDelivery{}, err
+	}
+
+	slog.InfoContext(ctx, "webhook delivery requeued", "delivery_id", id)
+	return delivery, nil
+}
+
+// Publish queues one delivery of event per matching subscription; the
+// delivery worker sends them. An event relayed again is delivered again, with
+// the same event id.
+func (s *WebhookServiceImpl) Publish(ctx context.Context, event domain.Event) (err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.Publish", trace.WithAttributes(
+		attribute.String("event.id", event.ID), attribute.String("event.type", event.Type)))
+	defer func() { tracing.End(span, err) }()
+
+	subs, err := s.webhookRepo.ListWebhookSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode event %s: %w", event.ID, err)
+	}
+
+	now := time.Now().UTC()
+	var deliveries []domain.WebhookDelivery
+	for _, sub := range subs {
+		if !sub.Matches(event.Type) {
+			continue
+		}
+		deliveries = append(deliveries, domain.WebhookDelivery{
+			ID:             "whd_" + randomHex(8),
+			SubscriptionID: sub.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        payload,
+			Status:         domain.DeliveryPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return s.webhookRepo.CreateWebhookDeliveries(ctx, deliveries)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,100 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"Backend/internal/domain"
+	"Backend/internal/repository/memory"
+	"Backend/internal/service"
+)
+
+func TestWebhookService_PublishesPREvents(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewMemoryRepository()
+	webhooks := service.NewWebhookService(repo)
+	prs := service.NewPRServiceWithOptions(repo, repo, service.PRServiceOptions{ReviewerCount: 1})
+
+	_, err := repo.CreateOrUpdateTeam(ctx, domain.Team{TeamName: "backend", Members: []domain.User{
+		{UserID: "alice", Username: "Alice", TeamName: "backend", IsActive: true},
+		{UserID: "bob", Username: "Bob", TeamName: "backend", IsActive: true},
+		{UserID: "carol", Username: "Carol", TeamName: "backend", IsActive: true},
+	}})
+	if err != nil {
+		t.Fatalf("CreateOrUpdateTeam failed: %v", err)
+	}
+	all, secret, err := webhooks.CreateSubscription(ctx, "https://hooks.example.com/all", "", []string{"*"})
+	if err != nil {
+		t.Fatalf("CreateSubscription failed: %v", err)
+	}
+	if !strings.HasPrefix(secret, "whsec_") || !strings.HasPrefix(all.ID, "whs_") {
+		t.Errorf("Expected a generated id and secret, got %q and %q", all.ID, secret)
+	}
+	if _, secret, _ := webhooks.CreateSubscription(ctx, "https://hooks.example.com/merged", "mine", []string{domain.EventPRMerged}); secret != "mine" {
+		t.Errorf("Expected the given secret to be kept, got %q", secret)
+	}
+
+	pr, err := prs.CreateAndAssignReviewers(ctx, "pr-1", "Fix", "alice", nil, nil, "")
+	if err != nil {
+		t.Fatalf("CreateAndAssignReviewers failed: %v", err)
+	}
+	if _, _, err := prs.ReassignReviewer(ctx, "pr-1", pr.AssignedReviewers[0], domain.AnyVersion); err != nil {
+		t.Fatalf("ReassignReviewer failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := prs.MergePullRequest(ctx, "pr-1", domain.AnyVersion); err != nil {
+			t.Fatalf("MergePullRequest failed: %v", err)
+		}
+	}
+
+	events, err := repo.ClaimOutboxEvents(ctx, time.Now().UTC(), time.Minute, 10)
+	if err != nil {
+		t.Fatalf("ClaimOutboxEvents failed: %v", err)
+	}
+	for _, e := range events {
+		if err := webhooks.Publish(ctx, e.Event); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+
+	pending, err := repo.ListWebhookDeliveries(ctx, domain.DeliveryPending, 10)
+	if err != nil {
+		t.Fatalf("ListWebhookDeliveries failed: %v", err)
+	}
+	counts := map[string]int{}
+	for _, d := range pending {
+		counts[d.SubscriptionID+" "+d.EventType]++
+	}
+	if len(pending) != 5 || counts[all.ID+" "+domain.EventTeamUpdated] != 1 || counts[all.ID+" "+domain.EventReviewersAssigned] != 1 ||
+		counts[all.ID+" "+domain.EventReviewerReassigned] != 1 || counts[all.ID+" "+domain.EventPRMerged] != 1 {
+		t.Fatalf("Expected four events for the catch-all subscription and one merge, got %v", counts)
+	}
+
+	var event domain.Event
+	for _, d := range pending {
+		if d.SubscriptionID == all.ID && d.EventType == domain.EventReviewerReassigned {
+			if err := json.Unmarshal(d.Payload, &event); err != nil {
+				t.Fatalf("Failed to decode payload: %v", err)
+			}
+		}
+	}
+	if event.OldReviewerID != pr.AssignedReviewers[0] || event.NewReviewerID == "" || event.PullRequest == nil || event.PullRequest.PullRequestID != "pr-1" {
+		t.Errorf("Expected reassignment details in the payload, got %+v", event)
+	}
+}
+
+func TestWebhookService_ValidatesSubscription(t *testing.T) {
+	webhooks := service.NewWebhookService(memory.NewMemoryRepository())
+
+	_, _, err := webhooks.CreateSubscription(context.Background(), "ftp://example.com", "", []string{"*", "pull_request.closed"})
+	bErr, ok := err.(*domain.BusinessError)
+	if !ok || bErr.Code != domain.ErrValidationFailed || len(bErr.Details) != 2 {
+		t.Fatalf("Expected url and events[1] to be rejected, got %v", err)
+	}
+	if bErr.Details[0].Field != "url" || bErr.Details[1].Field != "events[1]" {
+		t.Errorf("Unexpected details %+v", bErr.Details)
+	}
+}
@@ -0,0 +1,169 @@
+// Package stream fans out the events published from the outbox to live
+// subscribers, such as Server-Sent Events clients.
+//
+// The broker keeps the most recent events in a bounded buffer, so that a
+// subscriber reconnecting with the id of the last event it received gets the
+// events it missed. Events are kept in the order they were published, which
+// is the order they occurred for any one pull request.
+package stream
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"Backend/internal/domain"
+)
+
+// Streamed lists the event types delivered to subscribers.
+var Streamed = []string{domain.EventReviewersAssigned, domain.EventReviewerReassigned, domain.EventPRMerged}
+
+// subscriberBuffer is how many events may wait for a slow subscriber before it
+// is disconnected; it can then resume from the broker's buffer.
+const subscriberBuffer = 64
+
+// Filter selects the events of a subscriber. An event matches when the user
+// and, if Team is set, one of Members is its author or one of its reviewers,
+// including the replaced reviewer of a reassignment.
+type Filter struct {
+	UserID  string
+	Team    string
+	Members []string
+}
+
+type Subscription struct {
+	// C receives the matching events. It is closed when the subscriber falls
+	// too far behind or the broker is closed.
+	C <-chan domain.Event
+
+	c       chan domain.Event
+	filter  Filter
+	members map[string]bool
+}
+
+// matches reports whether event is one the subscriber asked for. It is called
+// with the broker lock held.
+func (s *Subscription) matches(event domain.Event) bool {
+	if !slices.Contains(Streamed, event.Type) || event.PullRequest == nil {
+		return false
+	}
+	users := append([]string{event.PullRequest.AuthorID, event.OldReviewerID}, event.PullRequest.AssignedReviewers...)
+	if s.filter.UserID != "" && !slices.Contains(users, s.filter.UserID) {
+		return false
+	}
+	if s.filter.Team != "" && !slices.ContainsFunc(users, func(id string) bool { return s.members[id] }) {
+		return false
+	}
+	return true
+}
+
+type Broker struct {
+	mu     sync.Mutex
+	size   int
+	buffer []domain.Event
+	subs   map[*Subscription]bool
+	closed bool
+}
+
+// NewBroker returns a broker keeping the last size events for resuming
+// subscribers.
+func NewBroker(size int) *Broker {
+	return &Broker{size: size, subs: make(map[*Subscription]bool)}
+}
+
+// Publish hands event to the matching subscribers. An event that is still in
+// the buffer has been published before and is dropped, as the outbox delivers
+// at least once. Team updates refresh the members of team filters.
+func (b *Broker) Publish(_ context.Context, event domain.Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if event.Type == domain.EventTeamUpdated && event.Team != nil {
+		for sub := range b.subs {
+			if sub.filter.Team == event.Team.TeamName {
+				sub.members = make(map[string]bool, len(event.Team.Members))
+				for _, u := range event.Team.Members {
+					sub.members[u.UserID] = true
+				}
+			}
+		}
+		return nil
+	}
+	if !slices.Contains(Streamed, event.Type) || b.position(event.ID) >= 0 {
+		return nil
+	}
+
+	b.buffer = append(b.buffer, event)
+	if len(b.buffer) > b.size {
+		b.buffer = slices.Delete(b.buffer, 0, len(b.buffer)-b.size)
+	}
+	for sub := range b.subs {
+		if !sub.matches(event) {
+			continue
+		}
+		select {
+		case sub.c <- event:
+		default:
+			b.remove(sub)
+		}
+	}
+	return nil
+}
+
+// Subscribe registers a subscriber and returns the buffered events it missed:
+// those published after lastEventID, or every matching buffered event when
+// lastEventID is not in the buffer any more. An empty lastEventID starts with
+// new events only.
+func (b *Broker) Subscribe(filter Filter, lastEventID string) ([]domain.Event, *Subscription) {
+	c := make(chan domain.Event, subscriberBuffer)
+	sub := &Subscription{C: c, c: c, filter: filter, members: make(map[string]bool, len(filter.Members))}
+	for _, id := range filter.Members {
+		sub.members[id] = true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var missed []domain.Event
+	if lastEventID != "" {
+		for _, event := range b.buffer[b.position(lastEventID)+1:] {
+			if sub.matches(event) {
+				missed = append(missed, event)
+			}
+		}
+	}
+	if b.closed {
+		close(c)
+	} else {
+		b.subs[sub] = true
+	}
+	return missed, sub
+}
+
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(sub)
+}
+
+// Close disconnects every subscriber; later subscriptions are closed at once.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for sub := range b.subs {
+		b.remove(sub)
+	}
+}
+
+func (b *Broker) remove(sub *Subscription) {
+	if b.subs[sub] {
+		delete(b.subs, sub)
+		close(sub.c)
+	}
+}
+
+// position returns the index of the event id in the buffer, or -1.
+func (b *Broker) position(id string) int {
+	return slices.IndexFunc(b.buffer, func(e domain.Event) bool { return e.ID == id })
+}
@@ -0,0 +1,115 @@
+package stream
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"Backend/internal/domain"
+)
+
+func prEvent(id int, typ, author string, reviewers ...string) domain.Event {
+	return domain.Event{
+		ID:          strconv.Itoa(id),
+		Type:        typ,
+		PullRequest: &domain.PullRequest{PullRequestID: "pr-" + strconv.Itoa(id), AuthorID: author, AssignedReviewers: reviewers},
+	}
+}
+
+func ids(events []domain.Event) string {
+	var s string
+	for _, e := range events {
+		s += e.ID + ","
+	}
+	return s
+}
+
+func drain(sub *Subscription) []domain.Event {
+	var events []domain.Event
+	for {
+		select {
+		case e, ok := <-sub.C:
+			if !ok {
+				return events
+			}
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
+
+func TestBroker_FiltersByUserAndTeam(t *testing.T) {
+	ctx := context.Background()
+	b := NewBroker(10)
+	_, all := b.Subscribe(Filter{}, "")
+	_, bob := b.Subscribe(Filter{UserID: "bob"}, "")
+	_, team := b.Subscribe(Filter{Team: "backend", Members: []string{"alice"}}, "")
+
+	b.Publish(ctx, prEvent(1, domain.EventReviewersAssigned, "alice", "bob"))
+	b.Publish(ctx, prEvent(2, domain.EventReviewersAssigned, "carol", "dave"))
+	b.Publish(ctx, domain.Event{ID: "3", Type: domain.EventTeamUpdated, Team: &domain.Team{TeamName: "backend", Members: []domain.User{{UserID: "carol"}}}})
+	reassigned := prEvent(4, domain.EventReviewerReassigned, "erin", "frank")
+	reassigned.OldReviewerID = "bob"
+	b.Publish(ctx, reassigned)
+	b.Publish(ctx, prEvent(5, domain.EventPRMerged, "carol"))
+
+	for name, tc := range map[string]struct {
+		sub  *Subscription
+		want string
+	}{
+		"all":  {all, "1,2,4,5,"},
+		"bob":  {bob, "1,4,"},
+		"team": {team, "1,5,"},
+	} {
+		if got := ids(drain(tc.sub)); got != tc.want {
+			t.Errorf("%s: expected events %s, got %s", name, tc.want, got)
+		}
+	}
+}
+
+func TestBroker_ResumesFromBuffer(t *testing.T) {
+	ctx := context.Background()
+	b := NewBroker(3)
+	for i := 1; i <= 5; i++ {
+		b.Publish(ctx, prEvent(i, domain.EventReviewersAssigned, "alice", "bob"))
+	}
+	b.Publish(ctx, prEvent(4, domain.EventReviewersAssigned, "alice", "bob"))
+
+	for lastID, want := range map[string]string{"": "", "3": "4,5,", "5": "", "1": "3,4,5,"} {
+		missed, sub := b.Subscribe(Filter{}, lastID)
+		if got := ids(missed); got != want {
+			t.Errorf("Last-Event-ID %q: expected %s, got %s", lastID, want, got)
+		}
+		b.Unsubscribe(sub)
+	}
+}
+
+func TestBroker_DisconnectsSlowSubscribers(t *testing.T) {
+	ctx := context.Background()
+	b := NewBroker(10)
+	_, sub := b.Subscribe(Filter{}, "")
+	for i := 0; i <= subscriberBuffer; i++ {
+		b.Publish(ctx, prEvent(i, domain.EventPRMerged, "alice"))
+	}
+	if got := len(drain(sub)); got != subscriberBuffer {
+		t.Fatalf("Expected %d buffered events before the channel closed, got %d", subscriberBuffer, got)
+	}
+	if !isClosed(sub) {
+		t.Error("Expected the subscription to be closed")
+	}
+
+	b.Close()
+	if _, sub := b.Subscribe(Filter{}, ""); !isClosed(sub) {
+		t.Error("Expected subscriptions after Close to be closed")
+	}
+}
+
+func isClosed(sub *Subscription) bool {
+	select {
+	case _, ok := <-sub.C:
+		return !ok
+	default:
+		return false
+	}
+}
@@ -0,0 +1,80 @@
+package tracing
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// fileClient is an otlptrace.Client that appends each export request as one
+// line of OTLP/JSON, the format read by the collector's otlpjsonfile receiver.
+type fileClient struct {
+	mu sync.Mutex
+	w  io.WriteCloser
+}
+
+func (c *fileClient) Start(ctx context.Context) error {
+	return nil
+}
+
+func (c *fileClient) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.w.Close()
+}
+
+func (c *fileClient) UploadTraces(ctx context.Context, protoSpans []*tracepb.ResourceSpans) error {
+	resourceSpans := make([]any, 0, len(protoSpans))
+	for _, rs := range protoSpans {
+		raw, err := protojson.Marshal(rs)
+		if err != nil {
+			return fmt.Errorf("failed to encode spans: %w", err)
+		}
+		var v any
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return fmt.Errorf("failed to encode spans: %w", err)
+		}
+		resourceSpans = append(resourceSpans, hexIDs(v))
+	}
+
+	line, err := json.Marshal(map[string]any{"resourceSpans": resourceSpans})
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+	line = append(line, '\n')
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err = c.w.Write(line)
+	return err
+}
+
+// hexIDs rewrites trace and span ids from the protobuf JSON base64 encoding
+// to the hex encoding required by OTLP/JSON.
+func hexIDs(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			s, ok := child.(string)
+			if ok && (k == "traceId" || k == "spanId" || k == "parentSpanId") {
+				if b, err := base64.StdEncoding.DecodeString(s); err == nil {
+					v[k] = hex.EncodeToString(b)
+				}
+				continue
+			}
+			v[k] = hexIDs(child)
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = hexIDs(child)
+		}
+	}
+	return v
+}
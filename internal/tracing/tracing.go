@@ -0,0 +1,91 @@
+// Package tracing configures OpenTelemetry tracing and provides the helpers
+// used to instrument handlers, services and repositories.
+package tracing
+
+import (
+	"Backend/internal/domain"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterNone     = "none"
+	ExporterStdout   = "stdout"
+	ExporterOTLPFile = "otlp-file"
+)
+
+// Exporters lists the accepted values of Options.Exporter.
+var Exporters = []string{ExporterNone, ExporterStdout, ExporterOTLPFile}
+
+type Options struct {
+	Exporter string
+	// File is where the otlp-file exporter appends OTLP/JSON lines.
+	File        string
+	ServiceName string
+}
+
+// Setup installs the global tracer provider and W3C trace context propagator.
+// The returned function flushes pending spans and must be called on shutdown.
+func Setup(opts Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var exporter sdktrace.SpanExporter
+	switch opts.Exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout exporter: %w", err)
+		}
+		exporter = exp
+	case ExporterOTLPFile:
+		f, err := os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+		exp, err := otlptrace.New(context.Background(), &fileClient{w: f})
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to create OTLP file exporter: %w", err)
+		}
+		exporter = exp
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", opts.Exporter)
+	}
+
+	res := resource.NewSchemaless(attribute.String("service.name", opts.ServiceName))
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// End finishes span, recording err. Business errors are expected outcomes and
+// only annotate the span with their code; anything else marks it as failed.
+func End(span trace.Span, err error) {
+	if err != nil {
+		var bErr *domain.BusinessError
+		if errors.As(err, &bErr) {
+			span.SetAttributes(attribute.String("error.code", string(bErr.Code)))
+		} else {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
@@ -0,0 +1,93 @@
+package tracing
+
+import (
+	"Backend/internal/domain"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSetup_OTLPFileWritesHexIDs(t *testing.T) {
+	prev := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(prev)
+
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	shutdown, err := Setup(Options{Exporter: ExporterOTLPFile, File: path, ServiceName: "test"})
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	_, child := otel.Tracer("test").Start(ctx, "child")
+	child.End()
+	parent.End()
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read trace file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected 1 export line, got %d", len(lines))
+	}
+
+	var req struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID      string `json:"traceId"`
+					SpanID       string `json:"spanId"`
+					ParentSpanID string `json:"parentSpanId"`
+					Name         string `json:"name"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &req); err != nil {
+		t.Fatalf("Export line is not valid JSON: %v", err)
+	}
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+	wantTrace := parent.SpanContext().TraceID().String()
+	for _, s := range spans {
+		if s.TraceID != wantTrace || len(s.SpanID) != 16 {
+			t.Errorf("Expected hex ids in trace %s, got %+v", wantTrace, s)
+		}
+	}
+	if spans[0].Name != "child" || spans[0].ParentSpanID != parent.SpanContext().SpanID().String() {
+		t.Errorf("Expected child span linked to parent, got %+v", spans[0])
+	}
+}
+
+func TestEnd_BusinessErrorsDoNotFailSpan(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)).Tracer("test")
+
+	_, span := tracer.Start(context.Background(), "not-found")
+	End(span, domain.NewBusinessError(domain.ErrNotFound, "missing"))
+	_, span = tracer.Start(context.Background(), "db-error")
+	End(span, errors.New("connection reset"))
+
+	ended := rec.Ended()
+	if ended[0].Status().Code == codes.Error {
+		t.Errorf("Expected business error not to mark span as failed")
+	}
+	if ended[1].Status().Code != codes.Error {
+		t.Errorf("Expected unexpected error to mark span as failed")
+	}
+}
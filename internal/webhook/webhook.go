@@ -0,0 +1,186 @@
+// Package webhook delivers queued events to webhook subscribers.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"Backend/internal/domain"
+	"Backend/internal/metrics"
+	"Backend/internal/poll"
+	"Backend/internal/repository"
+	"Backend/internal/tracing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+var tracer = otel.Tracer("Backend/internal/webhook")
+
+// Sign returns the value of the signature header for body sent at ts:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<unix seconds>.<body>">".
+// Receivers recompute the HMAC with their secret and should reject stale
+// timestamps to prevent replays.
+func Sign(secret string, ts time.Time, body []byte) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t + "."))
+	mac.Write(body)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+type Options struct {
+	// MaxAttempts is the number of attempts after which a delivery is dead.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry; it doubles with every
+	// further attempt up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	PollInterval   time.Duration
+	// Timeout bounds a single HTTP request.
+	Timeout   time.Duration
+	BatchSize int
+	// Retention is how long delivered deliveries are kept; zero keeps them forever.
+	Retention time.Duration
+	Metrics   *metrics.Metrics
+}
+
+type Worker struct {
+	repo   repository.WebhookRepository
+	client *http.Client
+	opts   Options
+	now    func() time.Time
+}
+
+func NewWorker(repo repository.WebhookRepository, opts Options) *Worker {
+	return &Worker{
+		repo: repo,
+		client: &http.Client{
+			Timeout: opts.Timeout,
+			// A redirect is reported as a failure rather than followed, so that
+			// the payload is only ever sent to the subscribed URL.
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		opts: opts,
+		now:  func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Run delivers due deliveries until ctx is done.
+func (w *Worker) Run(ctx context.Context) {
+	poll.Loop(ctx, "webhook deliveries", w.opts.PollInterval, w.opts.BatchSize, w.processBatch, w.cleanup)
+}
+
+func (w *Worker) cleanup(ctx context.Context) {
+	if w.opts.Retention <= 0 {
+		return
+	}
+	n, err := w.repo.DeleteDeliveredWebhookDeliveries(ctx, w.now().Add(-w.opts.Retention))
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to delete delivered webhook deliveries", "error", err)
+		return
+	}
+	slog.DebugContext(ctx, "delivered webhook deliveries deleted", "count", n)
+}
+
+// processBatch claims due deliveries, sends them concurrently and records the
+// outcome of each. The lease outlasts the requests, so a delivery is only
+// claimed again if the worker holding it died.
+func (w *Worker) processBatch(ctx context.Context) (int, error) {
+	lease := 2*w.opts.Timeout + time.Minute
+	deliveries, err := w.repo.ClaimWebhookDeliveries(ctx, w.now(), lease, w.opts.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range deliveries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.attempt(ctx, d)
+		}()
+	}
+	wg.Wait()
+	return len(deliveries), nil
+}
+
+func (w *Worker) attempt(ctx context.Context, d domain.WebhookDelivery) {
+	ctx, span := tracer.Start(ctx, "Webhook.Deliver", trace.WithAttributes(
+		attribute.String("webhook.delivery_id", d.ID), attribute.String("webhook.subscription_id", d.SubscriptionID),
+		attribute.String("event.type", d.EventType), attribute.Int("webhook.attempt", d.Attempts+1)))
+	err := w.send(ctx, d)
+	tracing.End(span, err)
+
+	d.Attempts++
+	now := w.now()
+	result := "delivered"
+	switch {
+	case err == nil:
+		d.Status, d.DeliveredAt, d.LastError = domain.DeliveryDelivered, &now, ""
+	case d.Attempts >= w.opts.MaxAttempts:
+		result = "dead"
+		d.Status, d.LastError = domain.DeliveryDead, err.Error()
+		slog.WarnContext(ctx, "webhook delivery dead-lettered", "delivery_id", d.ID, "attempts", d.Attempts, "error", err)
+	default:
+		result = "retry"
+		d.LastError, d.NextAttemptAt = err.Error(), now.Add(w.backoff(d.Attempts))
+		slog.InfoContext(ctx, "webhook delivery failed, retrying", "delivery_id", d.ID, "attempts", d.Attempts, "next_attempt_at", d.NextAttemptAt, "error", err)
+	}
+	if w.opts.Metrics != nil {
+		w.opts.Metrics.ObserveWebhookDelivery(result)
+	}
+
+	// The outcome is recorded even when shutting down, otherwise the attempt
+	// would be repeated without being counted.
+	if err := w.repo.UpdateWebhookDelivery(context.WithoutCancel(ctx), d); err != nil {
+		slog.ErrorContext(ctx, "failed to record webhook delivery", "delivery_id", d.ID, "error", err)
+	}
+}
+
+func (w *Worker) send(ctx context.Context, d domain.WebhookDelivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "pr-reviewer-webhooks/1")
+	req.Header.Set(SignatureHeader, Sign(d.Secret, w.now(), d.Payload))
+	req.Header.Set(EventHeader, d.EventType)
+	req.Header.Set(DeliveryHeader, d.ID)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Drain a bounded amount so that the connection can be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// backoff returns the delay after the given number of failed attempts.
+func (w *Worker) backoff(attempts int) time.Duration {
+	return poll.Backoff(w.opts.InitialBackoff, w.opts.MaxBackoff, attempts)
+}
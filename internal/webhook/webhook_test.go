@@ -0,0 +1,127 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"Backend/internal/domain"
+	"Backend/internal/repository/memory"
+)
+
+var start = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestWorker(t *testing.T, url string) (*Worker, *memory.MemoryRepository, *time.Time) {
+	t.Helper()
+	repo := memory.NewMemoryRepository()
+	ctx := context.Background()
+	_, err := repo.CreateWebhookSubscription(ctx, domain.WebhookSubscription{
+		ID: "s1", URL: url, Secret: "topsecret", Events: []string{"*"}, CreatedAt: start,
+	})
+	if err != nil {
+		t.Fatalf("CreateWebhookSubscription failed: %v", err)
+	}
+	err = repo.CreateWebhookDeliveries(ctx, []domain.WebhookDelivery{{
+		ID: "d1", SubscriptionID: "s1", EventID: "evt_1", EventType: domain.EventPRMerged,
+		Payload: json.RawMessage(`{"id":"evt_1"}`), Status: domain.DeliveryPending, NextAttemptAt: start, CreatedAt: start,
+	}})
+	if err != nil {
+		t.Fatalf("CreateWebhookDeliveries failed: %v", err)
+	}
+
+	now := start
+	w := NewWorker(repo, Options{
+		MaxAttempts: 3, InitialBackoff: time.Second, MaxBackoff: time.Minute,
+		PollInterval: time.Second, Timeout: time.Second, BatchSize: 10,
+	})
+	w.now = func() time.Time { return now }
+	return w, repo, &now
+}
+
+func TestWorker_DeliversSignedPayload(t *testing.T) {
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	got := make(chan received, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got <- received{header: r.Header, body: body}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	w, repo, _ := newTestWorker(t, srv.URL)
+	if n, err := w.processBatch(context.Background()); err != nil || n != 1 {
+		t.Fatalf("Expected one delivery to be processed, got %d, %v", n, err)
+	}
+
+	r := <-got
+	if string(r.body) != `{"id":"evt_1"}` {
+		t.Errorf("Expected the stored payload, got %s", r.body)
+	}
+	if sig := r.header.Get(SignatureHeader); sig != Sign("topsecret", start, r.body) {
+		t.Errorf("Expected a valid signature, got %q", sig)
+	}
+	if r.header.Get(EventHeader) != domain.EventPRMerged || r.header.Get(DeliveryHeader) != "d1" {
+		t.Errorf("Expected event and delivery headers, got %v", r.header)
+	}
+
+	pending, _ := repo.ListWebhookDeliveries(context.Background(), domain.DeliveryPending, 10)
+	delivered, _ := repo.ListWebhookDeliveries(context.Background(), domain.DeliveryDelivered, 10)
+	if len(pending) != 0 || len(delivered) != 1 || delivered[0].Attempts != 1 || delivered[0].DeliveredAt == nil {
+		t.Errorf("Expected d1 to be delivered after one attempt, got %+v", delivered)
+	}
+}
+
+func TestWorker_RetriesWithBackoffThenDeadLetters(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Redirect(w, r, "https://elsewhere.example.com", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	w, repo, now := newTestWorker(t, srv.URL)
+	ctx := context.Background()
+
+	for attempt, wait := range []time.Duration{time.Second, 2 * time.Second} {
+		if n, _ := w.processBatch(ctx); n != 1 {
+			t.Fatalf("Attempt %d: expected the delivery to be due", attempt+1)
+		}
+		pending, _ := repo.ListWebhookDeliveries(ctx, domain.DeliveryPending, 10)
+		if len(pending) != 1 || !pending[0].NextAttemptAt.Equal(now.Add(wait)) || pending[0].LastError != "unexpected status 302" {
+			t.Fatalf("Attempt %d: expected a retry in %s, got %+v", attempt+1, wait, pending)
+		}
+		*now = now.Add(wait - time.Millisecond)
+		if n, _ := w.processBatch(ctx); n != 0 {
+			t.Fatalf("Attempt %d: expected no delivery before the backoff elapsed", attempt+1)
+		}
+		*now = now.Add(time.Millisecond)
+	}
+
+	if n, _ := w.processBatch(ctx); n != 1 {
+		t.Fatalf("Expected the last attempt to be made")
+	}
+	dead, _ := repo.ListWebhookDeliveries(ctx, domain.DeliveryDead, 10)
+	if len(dead) != 1 || dead[0].Attempts != 3 {
+		t.Fatalf("Expected d1 to be dead after 3 attempts, got %+v", dead)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("Expected 3 requests without following redirects, got %d", calls.Load())
+	}
+}
+
+func TestWorker_Backoff(t *testing.T) {
+	w := NewWorker(nil, Options{InitialBackoff: time.Second, MaxBackoff: 10 * time.Second})
+	for attempts, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 5: 10 * time.Second, 60: 10 * time.Second} {
+		if got := w.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}